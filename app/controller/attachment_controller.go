@@ -0,0 +1,406 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// coverTypes are the content types accepted as book covers.
+var coverTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// GetAttachment returns the attachment with a signed download URL.
+// @Router /v1/attachments/{id} [get]
+func GetAttachment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	a, err := repo.NewAttachmentRepo(database.GetDB()).Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "attachment was not found"})
+	}
+
+	return c.JSON(fiber.Map{"attachment": withURL(a)})
+}
+
+// GetAttachmentContent streams the attachment. It needs no token, only the
+// expires and signature parameters of a URL handed out by the API.
+// @Router /v1/attachments/{id}/content [get]
+func GetAttachmentContent(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if err := storage.GetSigner().Verify(contentPath(id), c.Query("expires"), c.Query("signature"), time.Now()); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	a, err := repo.NewAttachmentRepo(database.GetDB()).Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "attachment was not found"})
+	}
+
+	// the content never changes, so its hash is a strong validator
+	etag := `"` + a.SHA256 + `"`
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	body, err := storage.GetStore().Get(c.UserContext(), storage.ContentKey(a.SHA256))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	disposition := "attachment"
+	if a.Inline() {
+		disposition = "inline"
+	}
+	c.Set(fiber.HeaderContentType, a.ContentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.SendStream(body, int(a.Size))
+}
+
+// GetTaskAttachments lists the files of a task with signed download URLs.
+// @Router /v1/tasks/{id}/attachments [get]
+func GetTaskAttachments(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if _, err := repo.NewTaskRepo(database.GetDB()).Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	attachments, err := repo.NewAttachmentRepo(database.GetDB()).AllByTask(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	for _, a := range attachments {
+		withURL(a)
+	}
+	if attachments == nil {
+		attachments = []*model.Attachment{}
+	}
+
+	return c.JSON(fiber.Map{"count": len(attachments), "attachments": attachments})
+}
+
+// UploadTaskAttachments attaches the multipart "file" parts to a task.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/attachments [post]
+func UploadTaskAttachments(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+	if _, err := repo.NewTaskRepo(database.GetDB()).Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["file"]) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "expected multipart form data with one or more \"file\" parts"})
+	}
+
+	attachmentRepo := repo.NewAttachmentRepo(database.GetDB())
+	attachments := []*model.Attachment{}
+	for _, fh := range form.File["file"] {
+		a, err := storeUpload(c, fh, config.StorageCfg().AllowedTypes)
+		if err != nil {
+			return UploadFailed(c, err)
+		}
+		a.TaskID = &id
+		a.Kind = model.AttachmentFile
+		a.UploadedBy = &userID
+
+		if err := attachmentRepo.Create(a); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+		}
+		dbAttachment, err := attachmentRepo.Get(a.ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+		}
+		attachments = append(attachments, withURL(dbAttachment))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"count": len(attachments), "attachments": attachments})
+}
+
+// DeleteAttachment removes an attachment; only its uploader or an admin may
+// do so. Deleting a book's cover clears its picture.
+// @Security ApiKeyAuth
+// @Router /v1/attachments/{id} [delete]
+func DeleteAttachment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	attachmentRepo := repo.NewAttachmentRepo(database.GetDB())
+	a, err := attachmentRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "attachment was not found"})
+	}
+	if !IsAdminRequest(c) && (a.UploadedBy == nil || *a.UploadedBy != userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only the uploader can delete an attachment"})
+	}
+
+	if err := attachmentRepo.Delete(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if a.Kind == model.AttachmentCover && a.BookID != nil {
+		if err := setBookPicture(AuditedDB(c), *a.BookID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+		}
+	}
+
+	return c.JSON(fiber.Map{})
+}
+
+// GetBookCover redirects to a signed URL of the book's cover.
+// @Router /v1/books/{id}/cover [get]
+func GetBookCover(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	a, err := repo.NewAttachmentRepo(database.GetDB()).Cover(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book has no cover"})
+	}
+
+	return c.Redirect(withURL(a).URL, fiber.StatusFound)
+}
+
+// UploadBookCover sets the multipart "file" part, an image, as the book's
+// cover. The book's meta.picture then points at GET /books/{id}/cover.
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/cover [put]
+func UploadBookCover(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	bookRepo := repo.NewBookRepo(database.GetDB())
+	if _, err := bookRepo.Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book were not found"})
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "expected multipart form data with a \"file\" part"})
+	}
+	a, err := storeUpload(c, fh, coverTypes)
+	if err != nil {
+		return UploadFailed(c, err)
+	}
+	a.BookID = &id
+	a.Kind = model.AttachmentCover
+	a.UploadedBy = &userID
+
+	attachmentRepo := repo.NewAttachmentRepo(database.GetDB())
+	if err := attachmentRepo.Create(a); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if err := setBookPicture(AuditedDB(c), id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbBook, err := bookRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	dbAttachment, err := attachmentRepo.Get(a.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbBook.Version))
+	return c.JSON(fiber.Map{"book": dbBook, "cover": withURL(dbAttachment)})
+}
+
+// setBookPicture points the book's meta.picture at its cover, or clears it
+// when the book has none left.
+func setBookPicture(db *database.DB, bookID uuid.UUID) error {
+	bookRepo := repo.NewBookRepo(db)
+	before, err := bookRepo.Get(bookID)
+	if err != nil {
+		return err
+	}
+
+	after := *before
+	after.Version = 0
+	after.Meta.Picture = ""
+	if _, err := repo.NewAttachmentRepo(db).Cover(bookID); err == nil {
+		after.Meta.Picture = fmt.Sprintf("/api/v1/books/%s/cover", bookID)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return bookRepo.Patch(bookID, before, &after)
+}
+
+// uploadError is a rejected upload, answered with its status.
+type uploadError struct {
+	status int
+	msg    string
+}
+
+func (e *uploadError) Error() string { return e.msg }
+
+// UploadFailed answers with 413 or 415 for rejected uploads and 500 otherwise.
+func UploadFailed(c *fiber.Ctx, err error) error {
+	var ue *uploadError
+	if errors.As(err, &ue) {
+		return c.Status(ue.status).JSON(fiber.Map{"msg": ue.msg})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+}
+
+// storeUpload checks the size and sniffed content type of an uploaded file
+// and puts its content in the blob store, unless a blob with the same
+// SHA-256 is already there. allowed lists accepted types such as
+// "image/png" or "image/*"; empty allows any.
+func storeUpload(c *fiber.Ctx, fh *multipart.FileHeader, allowed []string) (*model.Attachment, error) {
+	limit := config.StorageCfg().MaxUploadBytes
+	tooLarge := &uploadError{fiber.StatusRequestEntityTooLarge, fmt.Sprintf("files may not be larger than %d bytes", limit)}
+	if fh.Size > limit {
+		return nil, tooLarge
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// the client's Content-Type is not trusted; the bytes decide
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !typeAllowed(contentType, allowed) {
+		return nil, &uploadError{fiber.StatusUnsupportedMediaType, fmt.Sprintf("files of type %s are not accepted", contentType)}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > limit {
+		return nil, tooLarge
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	store := storage.GetStore()
+	key := storage.ContentKey(sum)
+	exists, err := store.Exists(c.UserContext(), key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := store.Put(c.UserContext(), key, f, size, contentType); err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.Attachment{
+		ID:          uuid.New(),
+		Filename:    cleanFilename(fh.Filename),
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      sum,
+	}, nil
+}
+
+func typeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	base, _, _ := mime.ParseMediaType(contentType)
+	for _, t := range allowed {
+		if t == base || (strings.HasSuffix(t, "/*") && strings.HasPrefix(base, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFilename keeps the last path element of a client supplied name,
+// without control characters.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	if len(name) > 255 {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:255-len(ext)], "") + ext
+	}
+	return name
+}
+
+func contentPath(id uuid.UUID) string {
+	return fmt.Sprintf("/api/v1/attachments/%s/content", id)
+}
+
+func withURL(a *model.Attachment) *model.Attachment {
+	a.URL = storage.GetSigner().Sign(contentPath(a.ID), time.Now())
+	return a
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeAllowed(t *testing.T) {
+	assert.True(t, typeAllowed("application/zip", nil))
+	assert.True(t, typeAllowed("image/png", coverTypes))
+	assert.False(t, typeAllowed("text/html; charset=utf-8", coverTypes))
+	assert.True(t, typeAllowed("text/plain; charset=utf-8", []string{"text/plain"}))
+	assert.True(t, typeAllowed("image/webp", []string{"image/*"}))
+	assert.False(t, typeAllowed("imagery/x", []string{"image/*"}))
+}
+
+func TestCleanFilename(t *testing.T) {
+	assert.Equal(t, "report.pdf", cleanFilename("report.pdf"))
+	assert.Equal(t, "passwd", cleanFilename("../../etc/passwd"))
+	assert.Equal(t, "scan.png", cleanFilename(`C:\Users\me\scan.png`))
+	assert.Equal(t, "ab.txt", cleanFilename("a\x00b\n.txt"))
+	assert.Equal(t, "file", cleanFilename(""))
+
+	long := cleanFilename(strings.Repeat("x", 300) + ".tar.gz")
+	assert.Len(t, long, 255)
+	assert.True(t, strings.HasSuffix(long, ".gz"))
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetAuditEvents lists the audit log, newest first. Filters: ?actor_id=<uuid>,
+// ?action=create|update|delete|restore|purge, ?resource_type=book|user|project|task,
+// ?resource_id=<uuid>, ?request_id=<id>, and ?from/?to (RFC 3339 or
+// YYYY-MM-DD) bounding when the change happened.
+// @Security ApiKeyAuth
+// @Router /v1/audit [get]
+func GetAuditEvents(c *fiber.Ctx) error {
+	pageNo, pageSize := GetPagination(c)
+
+	f, err := auditFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	events, err := repo.NewAuditRepo(database.GetDB()).Find(f, pageSize, uint(pageSize*(pageNo-1)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"page":      pageNo,
+		"page_size": pageSize,
+		"count":     len(events),
+		"events":    events,
+	})
+}
+
+// GetAuditEvent @Security ApiKeyAuth
+// @Router /v1/audit/{id} [get]
+func GetAuditEvent(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid id"})
+	}
+
+	e, err := repo.NewAuditRepo(database.GetDB()).Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "audit event were not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"event": e})
+}
+
+func auditFilter(c *fiber.Ctx) (repo.AuditFilter, error) {
+	f := repo.AuditFilter{
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		RequestID:    c.Query("request_id"),
+	}
+	if f.Action != "" && !slices.Contains(model.AuditActions, f.Action) {
+		return f, errors.New("invalid action")
+	}
+	if f.ResourceType != "" && !slices.Contains(model.AuditResourceTypes, f.ResourceType) {
+		return f, errors.New("invalid resource_type")
+	}
+
+	ids := []struct {
+		param string
+		dst   *uuid.UUID
+	}{{"actor_id", &f.ActorID}, {"resource_id", &f.ResourceID}}
+	for _, id := range ids {
+		s := c.Query(id.param)
+		if s == "" {
+			continue
+		}
+		v, err := uuid.Parse(s)
+		if err != nil {
+			return f, errors.New("invalid " + id.param)
+		}
+		*id.dst = v
+	}
+
+	var err error
+	if s := c.Query("from"); s != "" {
+		if f.From, err = parseReportTime(s); err != nil {
+			return f, errors.New("invalid from: " + err.Error())
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if f.To, err = parseReportTime(s); err != nil {
+			return f, errors.New("invalid to: " + err.Error())
+		}
+	}
+	return f, nil
+}
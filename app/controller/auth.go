@@ -39,11 +39,11 @@ func GetNewAccessToken(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "username not found"})
 	}
 
-	if user.PasswordHash == nil || *user.PasswordHash == "" {
+	if user.PasswordHash == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"msg": "user has no password set"})
 	}
 
-	if !IsValidPassword([]byte(*user.PasswordHash), []byte(login.Password)) {
+	if !IsValidPassword([]byte(user.PasswordHash), []byte(login.Password)) {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"msg": "password is wrong"})
 	}
 
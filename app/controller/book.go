@@ -1,6 +1,9 @@
 package controller
 
 import (
+	"errors"
+	"slices"
+
 	"github.com/byeblogs/go-boilerplate/app/model"
 	repo "github.com/byeblogs/go-boilerplate/app/repository"
 	"github.com/byeblogs/go-boilerplate/pkg/validator"
@@ -18,6 +21,13 @@ import (
 // @Produce json
 // @Param page query integer false "Page no"
 // @Param page_size query integer false "records per page"
+// @Param include_deleted query boolean false "include soft-deleted books (admin only)"
+// @Param isbn query string false "ISBN-10 or ISBN-13"
+// @Param author query string false "any author of the book"
+// @Param genre query string false "genre"
+// @Param tag query string false "tag"
+// @Param sort query string false "-created_at (default) or -rating, best rated first"
+// @Param status query string false "comma-separated statuses: draft, active, archived, withdrawn"
 // @Success 200 {object} model.Book "Ok"
 // @Failure 400 {object} model.ErrorResponse "Bad Request"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized"
@@ -28,7 +38,25 @@ func GetBooks(c *fiber.Ctx) error {
 	pageNo, pageSize := GetPagination(c)
 
 	bookRepo := repo.NewBookRepo(database.GetDB())
-	books, err := bookRepo.All(pageSize, uint(pageSize*(pageNo-1)))
+	if IncludeDeleted(c) {
+		bookRepo = bookRepo.WithDeleted()
+	}
+	filter, err := bookFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+	switch c.Query("sort") {
+	case "", "-created_at":
+	case "-rating":
+		filter.ByRating = true
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg": "sort must be -created_at or -rating",
+		})
+	}
+	books, err := bookRepo.Find(filter, pageSize, uint(pageSize*(pageNo-1)))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
@@ -45,6 +73,28 @@ func GetBooks(c *fiber.Ctx) error {
 	})
 }
 
+// bookFilter reads the filters shared by the book listings.
+func bookFilter(c *fiber.Ctx) (repo.BookFilter, error) {
+	filter := repo.BookFilter{
+		Author: c.Query("author"),
+		Genre:  c.Query("genre"),
+		Tag:    c.Query("tag"),
+	}
+	statuses, err := model.ParseBookStatuses(c.Query("status"))
+	if err != nil {
+		return filter, err
+	}
+	filter.Statuses = statuses
+	if v := c.Query("isbn"); v != "" {
+		isbn, ok := validator.NormalizeISBN(v)
+		if !ok {
+			return filter, errors.New("invalid isbn")
+		}
+		filter.ISBN = isbn
+	}
+	return filter, nil
+}
+
 // GetBook func gets a book.
 // @Description a book.
 // @Summary get a book
@@ -66,6 +116,9 @@ func GetBook(c *fiber.Ctx) error {
 		})
 	}
 	bookRepo := repo.NewBookRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		bookRepo = bookRepo.WithDeleted()
+	}
 	book, err := bookRepo.Get(ID)
 
 	if err != nil {
@@ -74,6 +127,46 @@ func GetBook(c *fiber.Ctx) error {
 		})
 	}
 
+	if IfNoneMatch(c, book.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.JSON(fiber.Map{
+		"book": book,
+	})
+}
+
+// GetBookByISBN func gets a book by its ISBN.
+// @Description get the book with an ISBN-10 or ISBN-13, hyphens allowed.
+// @Summary get a book by ISBN
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param isbn path string true "ISBN"
+// @Success 200 {object} model.Book "Ok"
+// @Failure 400 {object} model.ErrorResponse "Bad Request"
+// @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /v1/books/isbn/{isbn} [get]
+func GetBookByISBN(c *fiber.Ctx) error {
+	isbn, ok := validator.NormalizeISBN(c.Params("isbn"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg": "invalid isbn",
+		})
+	}
+	bookRepo := repo.NewBookRepo(database.GetDB())
+	book, err := bookRepo.GetByISBN(isbn)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"msg": "book were not found",
+		})
+	}
+
+	if IfNoneMatch(c, book.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.JSON(fiber.Map{
 		"book": book,
 	})
@@ -89,6 +182,7 @@ func GetBook(c *fiber.Ctx) error {
 // @Failure 400 {object} model.ErrorResponse "Bad Request"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized"
 // @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 409 {object} model.ErrorResponse "Conflict"
 // @Failure 500 {object} model.ErrorResponse "Internal Server Error"
 // @Success 200 {object} model.Book "Ok"
 // @Security ApiKeyAuth
@@ -114,7 +208,10 @@ func CreateBook(c *fiber.Ctx) error {
 
 	book.ID = uuid.New()
 	book.UserID = uuid.MustParse(userID.(string))
-	book.Status = 1 // Active
+	if book.Status == "" {
+		book.Status = model.BookActive
+	}
+	book.NormalizeCatalog()
 
 	// Create a new validator for a Book model.
 	validate := validator.NewValidator()
@@ -125,16 +222,37 @@ func CreateBook(c *fiber.Ctx) error {
 			"errors": validator.ValidatorErrors(err),
 		})
 	}
+	book.ISBN = normalizeISBN(book.ISBN)
 
-	bookRepo := repo.NewBookRepo(database.GetDB())
+	// Return status 400, if the book would skip its lifecycle.
+	if book.Status != model.BookDraft && book.Status != model.BookActive {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg": "a new book is draft or active",
+		})
+	}
+
+	bookRepo := repo.NewBookRepo(AuditedDB(c))
 	if err := bookRepo.Create(book); err != nil {
+		if errors.Is(err, repo.ErrDuplicateISBN) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"msg": err.Error(),
 		})
 	}
 
+	dbBook, err := bookRepo.Get(book.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbBook.Version))
 	return c.JSON(fiber.Map{
-		"book": book,
+		"book": dbBook,
 	})
 }
 
@@ -146,10 +264,13 @@ func CreateBook(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Book ID"
 // @Param updatebook body model.Book true "Update a book"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Success 200 {object} model.Book "Ok"
 // @Failure 400 {object} model.ErrorResponse "Bad Request"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized"
 // @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 409 {object} model.ErrorResponse "Conflict"
+// @Failure 412 {object} model.ErrorResponse "Precondition Failed"
 // @Failure 500 {object} model.ErrorResponse "Internal Server Error"
 // @Security ApiKeyAuth
 // @Router /v1/books/{id} [put]
@@ -160,14 +281,20 @@ func UpdateBook(c *fiber.Ctx) error {
 			"msg": err.Error(),
 		})
 	}
-	bookRepo := repo.NewBookRepo(database.GetDB())
-	_, err = bookRepo.Get(ID)
+	bookRepo := repo.NewBookRepo(AuditedDB(c))
+	current, err := bookRepo.Get(ID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"msg": "book were not found",
 		})
 	}
 
+	// Return status 412, if the client's copy is stale.
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"book": current})
+	}
+
 	book := &model.Book{}
 	if err := c.BodyParser(book); err != nil {
 		// Return status 400 and error message.
@@ -177,6 +304,11 @@ func UpdateBook(c *fiber.Ctx) error {
 	}
 
 	book.ID = ID
+	book.Version = version
+	if book.Status == "" {
+		book.Status = current.Status
+	}
+	book.NormalizeCatalog()
 
 	// Create a new validator for a Book model.
 	validate := validator.NewValidator()
@@ -187,8 +319,123 @@ func UpdateBook(c *fiber.Ctx) error {
 			"errors": validator.ValidatorErrors(err),
 		})
 	}
+	book.ISBN = normalizeISBN(book.ISBN)
+
+	// Return status 409, if the status change skips the lifecycle.
+	if err := current.Status.Transition(book.Status); err != nil {
+		return TransitionFailed(c, err)
+	}
 
 	if err := bookRepo.Update(ID, book); err != nil {
+		if errors.Is(err, repo.ErrDuplicateISBN) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := bookRepo.Get(ID); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"book": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	dbBook, err := bookRepo.Get(ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbBook.Version))
+	return c.JSON(fiber.Map{
+		"book": dbBook,
+	})
+}
+
+// PatchBook func partially updates a book.
+// @Description patch a book with a JSON Merge Patch or a JSON Patch
+// @Summary patch a book
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param patchbook body object true "application/merge-patch+json or application/json-patch+json document"
+// @Param If-Match header string false "ETag of the version being patched"
+// @Success 200 {object} model.Book "Ok"
+// @Failure 400 {object} model.ErrorResponse "Bad Request"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 409 {object} model.ErrorResponse "Conflict"
+// @Failure 412 {object} model.ErrorResponse "Precondition Failed"
+// @Failure 415 {object} model.ErrorResponse "Unsupported Media Type"
+// @Failure 422 {object} model.ErrorResponse "Unprocessable Entity"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /v1/books/{id} [patch]
+func PatchBook(c *fiber.Ctx) error {
+	ID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+	bookRepo := repo.NewBookRepo(AuditedDB(c))
+	current, err := bookRepo.Get(ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"msg": "book were not found",
+		})
+	}
+
+	// Return status 412, if the client's copy is stale.
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"book": current})
+	}
+
+	// Apply the patch to the stored book.
+	book := &model.Book{}
+	if err := ApplyPatch(c, current, book); err != nil {
+		return PatchFailed(c, err)
+	}
+
+	book.ID = ID
+	book.Version = version
+	// A patch of the single author renames the first of the byline.
+	if book.Author != current.Author && slices.Equal(book.Authors, current.Authors) && len(book.Authors) > 0 {
+		book.Authors = append([]string{book.Author}, book.Authors[1:]...)
+	}
+	book.NormalizeCatalog()
+
+	// Re-validate the patched book.
+	validate := validator.NewValidator()
+	if err := validate.Struct(book); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+	book.ISBN = normalizeISBN(book.ISBN)
+
+	// Return status 409, if the status change skips the lifecycle.
+	if err := current.Status.Transition(book.Status); err != nil {
+		return TransitionFailed(c, err)
+	}
+
+	if err := bookRepo.Patch(ID, current, book); err != nil {
+		if errors.Is(err, repo.ErrDuplicateISBN) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := bookRepo.Get(ID); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"book": current})
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"msg": err.Error(),
 		})
@@ -201,6 +448,7 @@ func UpdateBook(c *fiber.Ctx) error {
 		})
 	}
 
+	c.Set(fiber.HeaderETag, ETag(dbBook.Version))
 	return c.JSON(fiber.Map{
 		"book": dbBook,
 	})
@@ -213,10 +461,12 @@ func UpdateBook(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} model.Book "Ok"
 // @Failure 400 {object} model.ErrorResponse "Bad Request"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized"
 // @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 412 {object} model.ErrorResponse "Precondition Failed"
 // @Failure 500 {object} model.ErrorResponse "Internal Server Error"
 // @Security ApiKeyAuth
 // @Router /v1/books/{id} [delete]
@@ -228,16 +478,27 @@ func DeleteBook(c *fiber.Ctx) error {
 		})
 	}
 
-	bookRepo := repo.NewBookRepo(database.GetDB())
-	_, err = bookRepo.Get(ID)
+	bookRepo := repo.NewBookRepo(AuditedDB(c))
+	current, err := bookRepo.Get(ID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"msg": "book were not found",
 		})
 	}
 
-	err = bookRepo.Delete(ID)
+	// Return status 412, if the client's copy is stale.
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"book": current})
+	}
+
+	err = bookRepo.Delete(ID, version)
 	if err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := bookRepo.Get(ID); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"book": current})
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"msg": err.Error(),
 		})
@@ -245,3 +506,192 @@ func DeleteBook(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{})
 }
+
+// PublishBook func makes a draft or archived book active.
+// @Description publish a book: draft or archived becomes active
+// @Summary publish a book
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being published"
+// @Success 200 {object} model.Book "Ok"
+// @Failure 400 {object} model.ErrorResponse "Bad Request"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 409 {object} model.ErrorResponse "Conflict"
+// @Failure 412 {object} model.ErrorResponse "Precondition Failed"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/publish [post]
+func PublishBook(c *fiber.Ctx) error {
+	return setBookStatus(c, model.BookActive)
+}
+
+// ArchiveBook func archives an active book.
+// @Description archive a book: it stays in the catalogue but can't be lent
+// @Summary archive a book
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being archived"
+// @Success 200 {object} model.Book "Ok"
+// @Failure 400 {object} model.ErrorResponse "Bad Request"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 409 {object} model.ErrorResponse "Conflict"
+// @Failure 412 {object} model.ErrorResponse "Precondition Failed"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/archive [post]
+func ArchiveBook(c *fiber.Ctx) error {
+	return setBookStatus(c, model.BookArchived)
+}
+
+// WithdrawBook func withdraws a book from the library.
+// @Description withdraw a book: it leaves the library for good, though it can be archived again
+// @Summary withdraw a book
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being withdrawn"
+// @Success 200 {object} model.Book "Ok"
+// @Failure 400 {object} model.ErrorResponse "Bad Request"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 409 {object} model.ErrorResponse "Conflict"
+// @Failure 412 {object} model.ErrorResponse "Precondition Failed"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/withdraw [post]
+func WithdrawBook(c *fiber.Ctx) error {
+	return setBookStatus(c, model.BookWithdrawn)
+}
+
+func setBookStatus(c *fiber.Ctx, status model.BookStatus) error {
+	ID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	bookRepo := repo.NewBookRepo(AuditedDB(c))
+	current, err := bookRepo.Get(ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"msg": "book were not found",
+		})
+	}
+
+	// Return status 412, if the client's copy is stale.
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"book": current})
+	}
+
+	// Return status 409, if the lifecycle doesn't allow the move.
+	if err := current.Status.Transition(status); err != nil {
+		return TransitionFailed(c, err)
+	}
+
+	book := *current
+	book.Status = status
+	book.Version = version
+	if err := bookRepo.Patch(ID, current, &book); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := bookRepo.Get(ID); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"book": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	dbBook, err := bookRepo.Get(ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbBook.Version))
+	return c.JSON(fiber.Map{
+		"book": dbBook,
+	})
+}
+
+// RestoreBook func restores a soft-deleted book.
+// @Description restore a soft-deleted book
+// @Summary restore a book
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} model.Book "Ok"
+// @Failure 400 {object} model.ErrorResponse "Bad Request"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 404 {object} model.ErrorResponse "Not Found"
+// @Failure 409 {object} model.ErrorResponse "Conflict"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/restore [post]
+func RestoreBook(c *fiber.Ctx) error {
+	ID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	bookRepo := repo.NewBookRepo(AuditedDB(c))
+	book, err := bookRepo.WithDeleted().Get(ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"msg": "book were not found",
+		})
+	}
+	if book.DeletedAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"msg": "book is not deleted",
+		})
+	}
+
+	if err := bookRepo.Restore(ID); err != nil {
+		if errors.Is(err, repo.ErrDuplicateISBN) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	dbBook, err := bookRepo.Get(ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbBook.Version))
+	return c.JSON(fiber.Map{
+		"book": dbBook,
+	})
+}
+
+// normalizeISBN stores a validated ISBN as its ISBN-13 digits; an empty one
+// clears it.
+func normalizeISBN(isbn *string) *string {
+	if isbn == nil {
+		return nil
+	}
+	if n, ok := validator.NormalizeISBN(*isbn); ok {
+		return &n
+	}
+	return nil
+}
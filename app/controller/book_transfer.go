@@ -0,0 +1,357 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// bookImportError reports why a row of an import was skipped. Rows count
+// from 1, not counting the CSV header.
+type bookImportError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+// ImportBooks loads books from CSV or JSON Lines (?format=csv|ndjson, else
+// taken from the Content-Type). ?mapping=field:column,... maps the fields
+// of model.BookImportFields to differently named columns. A row updates
+// the live book with the same ISBN, or without ISBN the same title and
+// first author, and creates a new one otherwise. Invalid rows are skipped
+// and reported; with ?dry_run=true nothing is written. An import of more
+// than model.MaxBookImportRows rows is refused before anything is written;
+// a server error answers 500 with the row it stopped at and the counts of
+// the rows written before it.
+// @Security ApiKeyAuth
+// @Router /v1/books/import [post]
+func ImportBooks(c *fiber.Ctx) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	format := bookImportFormat(c.Query("format"), c.Get(fiber.HeaderContentType))
+	if format == "" {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"msg": "send text/csv or application/x-ndjson, or set ?format=csv|ndjson"})
+	}
+
+	mapping, err := model.ParseBookImportMapping(c.Query("mapping"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	records, err := newBookRecordReader(bytes.NewReader(c.Body()), format, mapping)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	// read every row before writing any, so that an oversized import
+	// writes nothing
+	recs, err := readBookRecords(records, model.MaxBookImportRows)
+	if errors.Is(err, errTooManyRows) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"msg": fmt.Sprintf("imports are limited to %d rows", model.MaxBookImportRows),
+		})
+	}
+
+	im := &bookImport{
+		repo:    repo.NewBookRepo(AuditedDB(c)),
+		userID:  userID,
+		dryRun:  c.QueryBool("dry_run"),
+		pending: map[string]bool{},
+	}
+	failed := []bookImportError{}
+	created, updated := 0, 0
+	for i, r := range recs {
+		if r.err != nil {
+			failed = append(failed, bookImportError{Row: i + 1, Errors: map[string]string{"row": r.err.Error()}})
+			continue
+		}
+
+		isNew, errs, err := im.row(r.rec)
+		if err != nil {
+			// the rows before this one are written; say which
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg":     err.Error(),
+				"row":     i + 1,
+				"created": created,
+				"updated": updated,
+			})
+		}
+		switch {
+		case len(errs) > 0:
+			failed = append(failed, bookImportError{Row: i + 1, Errors: errs})
+		case isNew:
+			created++
+		default:
+			updated++
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"dry_run": im.dryRun,
+		"rows":    len(recs),
+		"created": created,
+		"updated": updated,
+		"failed":  len(failed),
+		"errors":  failed,
+	})
+}
+
+// errTooManyRows stops reading an import with more rows than allowed.
+var errTooManyRows = errors.New("too many rows")
+
+// bookImportRecord is a row of an import, or why it can't be read.
+type bookImportRecord struct {
+	rec model.BookRecord
+	err error
+}
+
+// readBookRecords reads every row of an import, failing with
+// errTooManyRows past limit rows.
+func readBookRecords(records bookRecordReader, limit int) ([]bookImportRecord, error) {
+	var recs []bookImportRecord
+	for {
+		rec, err := records.Next()
+		if errors.Is(err, io.EOF) {
+			return recs, nil
+		}
+		if len(recs) == limit {
+			return nil, errTooManyRows
+		}
+		recs = append(recs, bookImportRecord{rec: rec, err: err})
+	}
+}
+
+// bookImport upserts the rows of one import.
+type bookImport struct {
+	repo   repo.BookRepository
+	userID uuid.UUID
+	dryRun bool
+	// pending holds the keys of books a dry run would have created, so
+	// later rows with the same key count as updates.
+	pending map[string]bool
+}
+
+// row validates one record and, unless dry running, writes it. It reports
+// whether the row creates a book, and what is wrong with an invalid row.
+// The error is for failures other than the row's.
+func (im *bookImport) row(rec model.BookRecord) (bool, map[string]string, error) {
+	probe := &model.Book{}
+	if errs := rec.Apply(probe); len(errs) > 0 {
+		return false, errs, nil
+	}
+	probe.NormalizeCatalog()
+
+	var key string
+	var existing *model.Book
+	var err error
+	switch {
+	case probe.ISBN != nil:
+		isbn, ok := validator.NormalizeISBN(*probe.ISBN)
+		if !ok {
+			return false, map[string]string{"isbn": "invalid isbn"}, nil
+		}
+		key = "isbn:" + isbn
+		existing, err = im.repo.GetByISBN(isbn)
+	case probe.Title != "" && probe.Author != "":
+		key = "title:" + strings.ToLower(probe.Title) + "\x00" + strings.ToLower(probe.Author)
+		existing, err = im.repo.GetByTitleAuthor(probe.Title, probe.Author)
+	default:
+		return false, map[string]string{"row": "needs an isbn, or a title and author"}, nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, nil, err
+	}
+
+	book := &model.Book{ID: uuid.New(), UserID: im.userID, Status: model.BookActive}
+	if existing != nil {
+		copied := *existing
+		book = &copied
+	}
+	if errs := rec.Apply(book); len(errs) > 0 {
+		return false, errs, nil
+	}
+	book.NormalizeCatalog()
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(book); err != nil {
+		return false, validator.ValidatorErrors(err), nil
+	}
+	book.ISBN = normalizeISBN(book.ISBN)
+
+	if existing != nil {
+		if err := existing.Status.Transition(book.Status); err != nil {
+			return false, map[string]string{"status": err.Error()}, nil
+		}
+	} else if book.Status != model.BookDraft && book.Status != model.BookActive {
+		return false, map[string]string{"status": "a new book is draft or active"}, nil
+	}
+
+	isNew := existing == nil && !im.pending[key]
+	if im.dryRun {
+		im.pending[key] = true
+		return isNew, nil, nil
+	}
+
+	if existing == nil {
+		err = im.repo.Create(book)
+	} else {
+		err = im.repo.Update(book.ID, book)
+	}
+	if errors.Is(err, repo.ErrDuplicateISBN) || errors.Is(err, repo.ErrVersionConflict) {
+		return false, map[string]string{"row": err.Error()}, nil
+	}
+	return isNew, nil, err
+}
+
+// bookImportFormat picks the import format from ?format, or else from the
+// Content-Type. It returns "" for anything else.
+func bookImportFormat(format, contentType string) string {
+	if format == "" {
+		format = strings.ToLower(contentType)
+	}
+	switch {
+	case strings.Contains(format, "csv"):
+		return "csv"
+	case strings.Contains(format, "ndjson"), strings.Contains(format, "jsonl"), strings.Contains(format, "json-lines"):
+		return "ndjson"
+	default:
+		return ""
+	}
+}
+
+// bookRecordReader yields the records of an import one at a time. Next
+// returns io.EOF after the last one; other errors concern a single row,
+// and reading can go on after them.
+type bookRecordReader interface {
+	Next() (model.BookRecord, error)
+}
+
+func newBookRecordReader(r io.Reader, format string, mapping map[string]string) (bookRecordReader, error) {
+	if format == "csv" {
+		in := csv.NewReader(r)
+		in.FieldsPerRecord = -1
+		in.TrimLeadingSpace = true
+		header, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the CSV has no header row")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading the CSV header: %w", err)
+		}
+		for i := range header {
+			header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+		}
+		return &csvBookRecords{in: in, header: header, mapping: mapping}, nil
+	}
+
+	in := bufio.NewScanner(r)
+	in.Buffer(make([]byte, 64*1024), 1<<20)
+	return &ndjsonBookRecords{in: in, mapping: mapping}, nil
+}
+
+type csvBookRecords struct {
+	in      *csv.Reader
+	header  []string
+	mapping map[string]string
+}
+
+func (r *csvBookRecords) Next() (model.BookRecord, error) {
+	fields, err := r.in.Read()
+	if err != nil {
+		return nil, err
+	}
+	raw := make(map[string]interface{}, len(fields))
+	for i, v := range fields {
+		if i < len(r.header) {
+			raw[r.header[i]] = v
+		}
+	}
+	return model.NewBookRecord(raw, r.mapping), nil
+}
+
+type ndjsonBookRecords struct {
+	in      *bufio.Scanner
+	mapping map[string]string
+}
+
+func (r *ndjsonBookRecords) Next() (model.BookRecord, error) {
+	for r.in.Scan() {
+		line := bytes.TrimSpace(r.in.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		raw := map[string]interface{}{}
+		if err := json.Unmarshal(line, &raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return model.NewBookRecord(raw, r.mapping), nil
+	}
+	if err := r.in.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ExportBooks streams the books matching the filters of GetBooks as CSV or
+// JSON Lines (?format=csv|ndjson, csv by default), oldest first. Books are
+// read in batches, so exports of any size run in constant memory.
+// @Router /v1/books/export [get]
+func ExportBooks(c *fiber.Ctx) error {
+	format := c.Query("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "format must be csv or ndjson"})
+	}
+
+	filter, err := bookFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	bookRepo := repo.NewBookRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		bookRepo = bookRepo.WithDeleted()
+	}
+
+	return bookExport.stream(c, format, func(fn func([]*model.Book) error) error {
+		return bookRepo.Each(filter, exportBatchSize, fn)
+	})
+}
+
+// bookExport writes the columns of the import fields, so that an export
+// can be imported again, and read-only extras.
+var bookExport = listExport[*model.Book]{
+	name: "books",
+	columns: append(append([]string{"id"}, model.BookImportFields...),
+		"rating_count", "rating_average", "created_at", "updated_at"),
+	row: bookCSVRow,
+}
+
+func bookCSVRow(b *model.Book) []string {
+	average := ""
+	if b.RatingAverage != nil {
+		average = strconv.FormatFloat(*b.RatingAverage, 'f', 2, 64)
+	}
+	return []string{
+		b.ID.String(), b.Title, b.Author, csvList(b.Authors), csvString(b.ISBN), csvString(b.Edition),
+		csvString(b.Publisher), csvInt(b.PublishedYear), csvString(b.Language), csvInt(b.PageCount),
+		csvList(b.Genres), csvList(b.Tags), string(b.Status),
+		b.Meta.Description, b.Meta.Picture, strconv.Itoa(b.Meta.Rating),
+		strconv.Itoa(b.RatingCount), average, csvTime(&b.CreatedAt), csvTime(b.UpdatedAt),
+	}
+}
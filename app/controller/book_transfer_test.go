@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookImportFormat(t *testing.T) {
+	assert.Equal(t, "csv", bookImportFormat("", "text/csv; charset=utf-8"))
+	assert.Equal(t, "ndjson", bookImportFormat("", "application/x-ndjson"))
+	assert.Equal(t, "ndjson", bookImportFormat("jsonl", "text/csv"))
+	assert.Empty(t, bookImportFormat("", "application/json"))
+}
+
+func TestBookRecordReader(t *testing.T) {
+	in := "\ufeffBook Title,isbn\nDune,9780441013593\n\"unterminated,\n"
+	records, err := newBookRecordReader(strings.NewReader(in), "csv", map[string]string{"title": "Book Title"})
+	require.NoError(t, err)
+	rec, err := records.Next()
+	require.NoError(t, err)
+	assert.Equal(t, model.BookRecord{"title": "Dune", "isbn": "9780441013593"}, rec)
+	_, err = records.Next()
+	assert.Error(t, err)
+
+	in = "{\"title\": \"Dune\"}\n\nnot json\n"
+	records, err = newBookRecordReader(strings.NewReader(in), "ndjson", nil)
+	require.NoError(t, err)
+	rec, err = records.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "Dune", rec["title"])
+	_, err = records.Next()
+	assert.Error(t, err)
+	_, err = records.Next()
+	assert.True(t, errors.Is(err, io.EOF))
+
+	_, err = newBookRecordReader(strings.NewReader(""), "csv", nil)
+	assert.Error(t, err, "no header")
+}
+
+func TestReadBookRecords(t *testing.T) {
+	in := "{\"title\": \"Dune\"}\nnot json\n{\"title\": \"Emma\"}\n"
+	records, err := newBookRecordReader(strings.NewReader(in), "ndjson", nil)
+	require.NoError(t, err)
+	recs, err := readBookRecords(records, 3)
+	require.NoError(t, err)
+	require.Len(t, recs, 3)
+	assert.Equal(t, "Dune", recs[0].rec["title"])
+	assert.Error(t, recs[1].err, "kept, to be reported")
+
+	records, err = newBookRecordReader(strings.NewReader(in), "ndjson", nil)
+	require.NoError(t, err)
+	_, err = readBookRecords(records, 2)
+	assert.ErrorIs(t, err, errTooManyRows)
+}
+
+func TestBookCSVRow(t *testing.T) {
+	isbn := "9780441013593"
+	b := &model.Book{
+		ID: uuid.New(), CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Title: "Dune", Author: "Frank Herbert", Authors: []string{"Frank Herbert"},
+		ISBN: &isbn, Genres: []string{"sci-fi", "classic"}, Status: model.BookActive,
+	}
+	row := bookCSVRow(b)
+	require.Len(t, row, len(bookExport.columns))
+	at := func(column string) string {
+		for i, c := range bookExport.columns {
+			if c == column {
+				return row[i]
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, "sci-fi; classic", at("genres"))
+	assert.Equal(t, isbn, at("isbn"))
+	assert.Equal(t, "active", at("status"))
+	assert.Equal(t, "2026-01-02T03:04:05Z", at("created_at"))
+}
@@ -0,0 +1,312 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetTaskComments lists the comments of a task, oldest first.
+// Paginate with ?limit=<n>&cursor=<next_cursor>.
+// @Router /v1/tasks/{id}/comments [get]
+func GetTaskComments(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if _, err := repo.NewTaskRepo(database.GetDB()).Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	return listComments(c, func(r repo.CommentRepository, after *repo.Cursor, limit int) ([]*model.Comment, error) {
+		return r.AllByTask(id, after, limit)
+	})
+}
+
+// GetProjectComments lists the comments of a project, oldest first.
+// Paginate with ?limit=<n>&cursor=<next_cursor>.
+// @Router /v1/projects/{id}/comments [get]
+func GetProjectComments(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if _, err := repo.NewProjectRepo(database.GetDB()).Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	return listComments(c, func(r repo.CommentRepository, after *repo.Cursor, limit int) ([]*model.Comment, error) {
+		return r.AllByProject(id, after, limit)
+	})
+}
+
+// CreateTaskComment posts a comment on a task as the token's user.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/comments [post]
+func CreateTaskComment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if _, err := repo.NewTaskRepo(database.GetDB()).Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	return createComment(c, &model.Comment{TaskID: &id})
+}
+
+// CreateProjectComment posts a comment on a project as the token's user.
+// @Security ApiKeyAuth
+// @Router /v1/projects/{id}/comments [post]
+func CreateProjectComment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if _, err := repo.NewProjectRepo(database.GetDB()).Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	return createComment(c, &model.Comment{ProjectID: &id})
+}
+
+// GetComment @Router /v1/comments/{id} [get]
+func GetComment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	commentRepo := repo.NewCommentRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		commentRepo = commentRepo.WithDeleted()
+	}
+	cm, err := commentRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "comment was not found"})
+	}
+
+	if IfNoneMatch(c, cm.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.JSON(fiber.Map{"comment": cm})
+}
+
+// GetCommentRevisions lists the previous bodies of an edited comment.
+// @Router /v1/comments/{id}/revisions [get]
+func GetCommentRevisions(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	commentRepo := repo.NewCommentRepo(database.GetDB())
+	if _, err := commentRepo.Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "comment was not found"})
+	}
+
+	revisions, err := commentRepo.Revisions(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"count": len(revisions), "revisions": revisions})
+}
+
+// UpdateComment edits a comment; only its author or an admin may do so.
+// @Security ApiKeyAuth
+// @Router /v1/comments/{id} [put]
+func UpdateComment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	commentRepo := repo.NewCommentRepo(database.GetDB())
+	current, err := commentRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "comment was not found"})
+	}
+	if !canModerate(c, current, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only the author can edit a comment"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"comment": current})
+	}
+
+	w := &model.WriteComment{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	cm := &model.Comment{Body: w.Body, Mentions: resolveMentions(w.Body), Version: version}
+	if err := commentRepo.Update(id, cm, userID); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := commentRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"comment": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbComment, err := commentRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbComment.Version))
+	return c.JSON(fiber.Map{"comment": dbComment})
+}
+
+// DeleteComment soft-deletes a comment; only its author or an admin may do so.
+// @Security ApiKeyAuth
+// @Router /v1/comments/{id} [delete]
+func DeleteComment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	commentRepo := repo.NewCommentRepo(database.GetDB())
+	current, err := commentRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "comment was not found"})
+	}
+	if !canModerate(c, current, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only the author can delete a comment"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"comment": current})
+	}
+
+	if err := commentRepo.Delete(id, version); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := commentRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"comment": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{})
+}
+
+func createComment(c *fiber.Ctx, cm *model.Comment) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	w := &model.WriteComment{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	cm.ID = uuid.New()
+	cm.AuthorID = &userID
+	cm.Body = w.Body
+	cm.Mentions = resolveMentions(w.Body)
+
+	commentRepo := repo.NewCommentRepo(database.GetDB())
+	if err := commentRepo.Create(cm); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbComment, err := commentRepo.Get(cm.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbComment.Version))
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"comment": dbComment})
+}
+
+func listComments(c *fiber.Ctx, list func(repo.CommentRepository, *repo.Cursor, int) ([]*model.Comment, error)) error {
+	after, err := repo.ParseCursor(c.Query("cursor"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "limit must be between 1 and 100"})
+	}
+
+	commentRepo := repo.NewCommentRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		commentRepo = commentRepo.WithDeleted()
+	}
+	// one extra row tells whether there is a next page
+	comments, err := list(commentRepo, after, limit+1)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	next := ""
+	if len(comments) > limit {
+		comments = comments[:limit]
+		last := comments[limit-1]
+		next = repo.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+	if comments == nil {
+		comments = []*model.Comment{}
+	}
+
+	return c.JSON(fiber.Map{
+		"count":       len(comments),
+		"comments":    comments,
+		"next_cursor": next,
+	})
+}
+
+// resolveMentions returns the ids of the live users @mentioned in body.
+// Unknown usernames are left as plain text.
+func resolveMentions(body string) []uuid.UUID {
+	userRepo := repo.NewUserRepo(database.GetDB())
+	ids := []uuid.UUID{}
+	for _, name := range model.Mentions(body) {
+		if u, err := userRepo.GetByUsername(name); err == nil {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids
+}
+
+func canModerate(c *fiber.Ctx, cm *model.Comment, userID uuid.UUID) bool {
+	return IsAdminRequest(c) || (cm.AuthorID != nil && *cm.AuthorID == userID)
+}
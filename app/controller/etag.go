@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ETag returns the strong entity tag of a resource version.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// IfMatch evaluates the If-Match header against the stored version. It
+// returns the version the write must be conditioned on (0 when the request
+// has no If-Match) and false when the precondition fails. Weak tags never
+// match, as required for If-Match.
+func IfMatch(c *fiber.Ctx, current int64) (int64, bool) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return 0, true
+	}
+	if header == "*" {
+		return current, true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == ETag(current) {
+			return current, true
+		}
+	}
+	return 0, false
+}
+
+// IfNoneMatch sets the ETag header of a read and reports whether the client
+// copy is still current, in which case the handler answers 304.
+func IfNoneMatch(c *fiber.Ctx, current int64) bool {
+	c.Set(fiber.HeaderETag, ETag(current))
+
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfNoneMatch))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == ETag(current) {
+			return true
+		}
+	}
+	return false
+}
+
+// PreconditionFailed answers 412 with the current representation of the
+// resource, so the client can merge its change and retry.
+func PreconditionFailed(c *fiber.Ctx, version int64, current fiber.Map) error {
+	c.Set(fiber.HeaderETag, ETag(version))
+	current["msg"] = "resource has changed, precondition failed"
+	return c.Status(fiber.StatusPreconditionFailed).JSON(current)
+}
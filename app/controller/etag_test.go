@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreconditions(t *testing.T) {
+	tests := []struct {
+		description string
+		header      string
+		value       string
+		wantStatus  int
+	}{
+		{"no If-Match is unconditional", fiber.HeaderIfMatch, "", fiber.StatusOK},
+		{"matching If-Match", fiber.HeaderIfMatch, `"3"`, fiber.StatusOK},
+		{"If-Match in a list", fiber.HeaderIfMatch, `"1", "3"`, fiber.StatusOK},
+		{"If-Match wildcard", fiber.HeaderIfMatch, "*", fiber.StatusOK},
+		{"stale If-Match", fiber.HeaderIfMatch, `"2"`, fiber.StatusPreconditionFailed},
+		{"weak If-Match never matches", fiber.HeaderIfMatch, `W/"3"`, fiber.StatusPreconditionFailed},
+		{"current If-None-Match", fiber.HeaderIfNoneMatch, `W/"3"`, fiber.StatusNotModified},
+		{"stale If-None-Match", fiber.HeaderIfNoneMatch, `"2"`, fiber.StatusOK},
+	}
+
+	app := fiber.New()
+	app.Put("/", func(c *fiber.Ctx) error {
+		if _, ok := IfMatch(c, 3); !ok {
+			return PreconditionFailed(c, 3, fiber.Map{})
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/", func(c *fiber.Ctx) error {
+		if IfNoneMatch(c, 3) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for _, test := range tests {
+		method := fiber.MethodPut
+		if test.header == fiber.HeaderIfNoneMatch {
+			method = fiber.MethodGet
+		}
+		req := httptest.NewRequest(method, "/", nil)
+		if test.value != "" {
+			req.Header.Set(test.header, test.value)
+		}
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err, test.description)
+		assert.Equal(t, test.wantStatus, resp.StatusCode, test.description)
+		if resp.StatusCode != fiber.StatusOK || method == fiber.MethodGet {
+			assert.Equal(t, `"3"`, resp.Header.Get(fiber.HeaderETag), test.description)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/events"
+	"github.com/byeblogs/go-boilerplate/platform/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// eventBatch is how many events a stream reads at a time.
+const eventBatch = 500
+
+// eventLookback is how far back, in event ids, a stream looks for events
+// committed after events with higher ids.
+const eventLookback = 100
+
+// errStreamClosed stops a stream whose client is gone.
+var errStreamClosed = errors.New("event stream closed")
+
+// GetEvents streams the task and project events of the projects the user
+// can see (every project for admins) as they are committed, as
+// Server-Sent Events or, when the request asks for it, over a WebSocket.
+// Filters: ?types=task.*,project.deleted (see CreateWebhook) and
+// ?project_id=<uuid>. Each event carries a cursor, the SSE id; a stream
+// opened with it as Last-Event-ID (or ?last_event_id) resumes after it.
+// Browsers can pass the token as ?access_token.
+// @Security ApiKeyAuth
+// @Router /v1/events [get]
+func GetEvents(c *fiber.Ctx) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	patterns := model.EventPatterns{"*"}
+	if s := c.Query("types"); s != "" {
+		patterns = strings.Split(s, ",")
+		if err := patterns.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+		}
+	}
+
+	f := repo.EventFilter{UserID: userID, All: IsAdminRequest(c)}
+	if s := c.Query("project_id"); s != "" {
+		projectID, err := uuid.Parse(s)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid project_id"})
+		}
+		f.ProjectID = projectID
+	}
+
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	var after int64
+	if lastEventID != "" {
+		var err error
+		if after, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || after < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid Last-Event-ID"})
+		}
+	}
+
+	// subscribe before reading where to start, so that no event falls in between
+	wake, unsubscribe := events.GetBroker().Subscribe()
+	eventRepo := repo.NewEventRepo(database.GetDB())
+	if lastEventID == "" {
+		var err error
+		if after, err = eventRepo.LastID(); err != nil {
+			unsubscribe()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+		}
+	}
+
+	s := &eventStream{repo: eventRepo, filter: f, patterns: patterns, cursor: newEventCursor(after)}
+	heartbeat := config.EventsCfg().Heartbeat
+
+	if websocket.IsUpgrade(c) {
+		return websocket.Upgrade(c, func(conn *websocket.Conn) {
+			defer unsubscribe()
+			if err := s.run(wake, conn.Done(), heartbeat, wsSink{conn}); err != nil && !errors.Is(err, errStreamClosed) {
+				logr.Errorf("event stream of %s: %v", userID, err)
+			}
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // or nginx holds the events back
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		if err := s.run(wake, nil, heartbeat, sseSink{w}); err != nil && !errors.Is(err, errStreamClosed) {
+			logr.Errorf("event stream of %s: %v", userID, err)
+		}
+	})
+	return nil
+}
+
+// liveEvent is an event as streamed, with the cursor to resume after it.
+type liveEvent struct {
+	Cursor int64 `json:"cursor"`
+	*model.WebhookEvent
+}
+
+// eventSink is where a stream sends its events: an SSE response or a
+// WebSocket. Errors mean the client is gone.
+type eventSink interface {
+	send(e liveEvent) error
+	ping() error
+}
+
+type sseSink struct{ w *bufio.Writer }
+
+func (s sseSink) send(e liveEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", e.Cursor, e.Type, data)
+	return s.w.Flush()
+}
+
+func (s sseSink) ping() error {
+	s.w.WriteString(": ping\n\n")
+	return s.w.Flush()
+}
+
+type wsSink struct{ conn *websocket.Conn }
+
+func (s wsSink) send(e liveEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.conn.WriteText(data)
+}
+
+func (s wsSink) ping() error { return s.conn.Ping() }
+
+// eventStream sends the events a client can see as they are committed.
+type eventStream struct {
+	repo     repo.EventRepository
+	filter   repo.EventFilter
+	patterns model.EventPatterns
+	cursor   *eventCursor
+}
+
+// run pings, so that the client sees the stream open, sends the events
+// committed since the cursor, then those committed whenever wake fires,
+// until wake is closed (the server is shutting down), done is closed or
+// the client is gone. Idle, it pings every heartbeat.
+func (s *eventStream) run(wake <-chan struct{}, done <-chan struct{}, heartbeat time.Duration, sink eventSink) error {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	if err := sink.ping(); err != nil {
+		return errStreamClosed
+	}
+	if err := s.flush(sink); err != nil {
+		return err
+	}
+	for {
+		select {
+		case _, ok := <-wake:
+			if !ok {
+				return nil
+			}
+			if err := s.flush(sink); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := sink.ping(); err != nil {
+				return errStreamClosed
+			}
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// flush sends the events committed since the last flush.
+func (s *eventStream) flush(sink eventSink) error {
+	for {
+		batch, err := s.repo.After(s.filter, s.cursor.floor, eventBatch)
+		if err != nil {
+			return err
+		}
+		for _, e := range batch {
+			if !s.cursor.see(e.ID) || !s.patterns.Match(e.Type) {
+				continue
+			}
+			if err := sink.send(liveEvent{Cursor: s.cursor.last, WebhookEvent: e}); err != nil {
+				return errStreamClosed
+			}
+		}
+		if len(batch) < eventBatch {
+			return nil
+		}
+	}
+}
+
+// eventCursor tracks the events a stream went through. Event ids are
+// handed out as events are written but become visible as their
+// transactions commit, so an event can show up after events with higher
+// ids. The cursor keeps looking eventLookback ids back for those, and
+// remembers the ids it saw there.
+type eventCursor struct {
+	floor int64 // every event up to floor was seen
+	last  int64 // highest id seen
+	seen  map[int64]bool
+}
+
+func newEventCursor(after int64) *eventCursor {
+	return &eventCursor{floor: after, last: after, seen: map[int64]bool{}}
+}
+
+// see records the event id, and reports whether it is new.
+func (cur *eventCursor) see(id int64) bool {
+	if id <= cur.floor || cur.seen[id] {
+		return false
+	}
+	cur.seen[id] = true
+	if id > cur.last {
+		cur.last = id
+	}
+	if floor := cur.last - eventLookback; floor > cur.floor {
+		cur.floor = floor
+		for id := range cur.seen {
+			if id <= floor {
+				delete(cur.seen, id)
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventCursor(t *testing.T) {
+	cur := newEventCursor(10)
+	assert.False(t, cur.see(10), "resumed after it")
+	assert.True(t, cur.see(12))
+	assert.False(t, cur.see(12), "seen")
+	assert.True(t, cur.see(11), "committed late")
+	assert.Equal(t, int64(12), cur.last)
+	assert.Equal(t, int64(10), cur.floor)
+
+	assert.True(t, cur.see(12+eventLookback+5))
+	assert.Equal(t, int64(17), cur.floor, "looks eventLookback back")
+	assert.NotContains(t, cur.seen, int64(12), "forgotten below the floor")
+	assert.False(t, cur.see(15))
+	assert.True(t, cur.see(20))
+}
+
+// memEvents serves events from memory, as EventRepo.After does.
+type memEvents struct {
+	events []*model.WebhookEvent
+}
+
+func (m *memEvents) LastID() (int64, error) { return 0, nil }
+
+func (m *memEvents) After(f repo.EventFilter, after int64, limit int) ([]*model.WebhookEvent, error) {
+	var out []*model.WebhookEvent
+	for _, e := range m.events {
+		if e.ID > after && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestEventStream(t *testing.T) {
+	events := &memEvents{events: []*model.WebhookEvent{
+		{ID: 1, Type: "task.created"},
+		{ID: 3, Type: "project.updated"},
+	}}
+	s := &eventStream{repo: events, patterns: model.EventPatterns{"task.*"}, cursor: newEventCursor(0)}
+
+	var buf bytes.Buffer
+	sink := sseSink{bufio.NewWriter(&buf)}
+	require.NoError(t, s.flush(sink))
+
+	// a task event committed late, and one after it
+	events.events = []*model.WebhookEvent{
+		{ID: 1, Type: "task.created"},
+		{ID: 2, Type: "task.updated"},
+		{ID: 3, Type: "project.updated"},
+		{ID: 4, Type: "task.deleted"},
+	}
+	wake := make(chan struct{})
+	close(wake) // the server shuts down
+	require.NoError(t, s.run(wake, nil, time.Hour, sink))
+
+	out := buf.String()
+	assert.Contains(t, out, ": ping\n\n")
+	assert.Contains(t, out, "id: 1\nevent: task.created\ndata: {\"cursor\":1,\"id\":1,")
+	assert.Contains(t, out, "id: 3\nevent: task.updated\ndata: {\"cursor\":3,\"id\":2,", "resumes after the highest id")
+	assert.Contains(t, out, "id: 4\nevent: task.deleted\n")
+	assert.NotContains(t, out, "project.updated", "filtered out by type")
+	assert.Equal(t, 3, bytes.Count(buf.Bytes(), []byte("\nevent: ")), "each event once")
+}
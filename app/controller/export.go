@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// exportBatchSize is how many rows an export reads from the database at a
+// time.
+const exportBatchSize = 500
+
+const (
+	mimeCSV    = "text/csv"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+// listFormat picks the format a list is returned in: "csv", "ndjson", or
+// "json" for the usual paginated response. ?format wins over the Accept
+// header, so that exports can be linked to.
+func listFormat(c *fiber.Ctx) (string, bool) {
+	switch c.Query("format") {
+	case "":
+	case "json", "csv", "ndjson":
+		return c.Query("format"), true
+	default:
+		return "", false
+	}
+	switch c.Accepts(fiber.MIMEApplicationJSON, mimeCSV, mimeNDJSON) {
+	case mimeCSV:
+		return "csv", true
+	case mimeNDJSON:
+		return "ndjson", true
+	default:
+		return "json", true
+	}
+}
+
+// listExport describes how the rows of a list are written out.
+type listExport[T any] struct {
+	name    string   // of the downloaded file, without extension
+	columns []string // CSV header
+	row     func(T) []string
+	// item is what a JSON line holds; the row itself when nil.
+	item func(T) interface{}
+}
+
+// stream sends the rows each yields as CSV or JSON Lines. The rows are
+// written batch by batch while each reads them, so the response never
+// holds more than one batch. The status is sent before the first row is
+// read: an error halfway can only cut the stream short, and is logged.
+func (ex listExport[T]) stream(c *fiber.Ctx, format string, each func(fn func([]T) error) error) error {
+	if format == "csv" {
+		c.Set(fiber.HeaderContentType, mimeCSV+"; charset=utf-8")
+	} else {
+		c.Set(fiber.HeaderContentType, mimeNDJSON)
+	}
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+ex.name+`.`+format+`"`)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		write := ex.ndjson(w)
+		if format == "csv" {
+			write = ex.csv(w)
+		}
+		err := each(func(rows []T) error {
+			if err := write(rows); err != nil {
+				return err
+			}
+			return w.Flush()
+		})
+		if err != nil {
+			logr.Errorf("%s export failed: %v", ex.name, err)
+		}
+	})
+	return nil
+}
+
+// csv returns a writer of CSV rows that starts with the header, even when
+// there are no rows at all.
+func (ex listExport[T]) csv(w *bufio.Writer) func([]T) error {
+	out := csv.NewWriter(w)
+	_ = out.Write(ex.columns)
+	return func(rows []T) error {
+		for _, v := range rows {
+			_ = out.Write(ex.row(v))
+		}
+		out.Flush()
+		return out.Error()
+	}
+}
+
+func (ex listExport[T]) ndjson(w *bufio.Writer) func([]T) error {
+	enc := json.NewEncoder(w)
+	return func(rows []T) error {
+		for _, v := range rows {
+			var item interface{} = v
+			if ex.item != nil {
+				item = ex.item(v)
+			}
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// CSV cells of optional values are empty when unset.
+
+func csvString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func csvInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func csvList(items []string) string {
+	return strings.Join(items, "; ")
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFormat(t *testing.T) {
+	tests := []struct {
+		description string
+		target      string
+		accept      string
+		want        string
+	}{
+		{"JSON by default", "/", "", "json"},
+		{"browsers get JSON", "/", "text/html,*/*;q=0.8", "json"},
+		{"CSV", "/", "text/csv", "csv"},
+		{"JSON Lines", "/", "application/x-ndjson", "ndjson"},
+		{"preferred type", "/", "text/csv;q=0.5, application/x-ndjson", "ndjson"},
+		{"query wins", "/?format=csv", "application/json", "csv"},
+		{"unknown query", "/?format=xml", "", "invalid"},
+	}
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		format, ok := listFormat(c)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).SendString("invalid")
+		}
+		return c.SendString(format)
+	})
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.target, nil)
+		if test.accept != "" {
+			req.Header.Set(fiber.HeaderAccept, test.accept)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err, test.description)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, test.want, string(body), test.description)
+	}
+}
+
+func TestListExportStream(t *testing.T) {
+	type row struct{ name, note string }
+	ex := listExport[row]{
+		name:    "rows",
+		columns: []string{"name", "note"},
+		row:     func(r row) []string { return []string{r.name, r.note} },
+		item:    func(r row) interface{} { return map[string]string{"name": r.name} },
+	}
+	batches := [][]row{{{"a", "x,y"}, {"b", ""}}, {{"c", `"q"`}}}
+
+	app := fiber.New()
+	app.Get("/:format", func(c *fiber.Ctx) error {
+		return ex.stream(c, c.Params("format"), func(fn func([]row) error) error {
+			for _, b := range batches {
+				if err := fn(b); err != nil {
+					return err
+				}
+			}
+			return errors.New("cut short")
+		})
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/csv", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+	assert.Contains(t, resp.Header.Get(fiber.HeaderContentDisposition), `filename="rows.csv"`)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "name,note\na,\"x,y\"\nb,\nc,\"\"\"q\"\"\"\n", string(body), "rows before the error are sent")
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/ndjson", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get(fiber.HeaderContentType))
+	body, _ = io.ReadAll(resp.Body)
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	assert.Equal(t, []string{`{"name":"a"}`, `{"name":"b"}`, `{"name":"c"}`}, lines)
+}
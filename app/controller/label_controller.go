@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetLabels lists the labels defined for a project.
+// @Router /v1/projects/{id}/labels [get]
+func GetLabels(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if _, err := repo.NewProjectRepo(database.GetDB()).Get(projectID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	labels, err := repo.NewLabelRepo(database.GetDB()).AllByProject(projectID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"count": len(labels), "labels": labels})
+}
+
+// CreateLabel defines a label for a project. Defining an existing name
+// updates its color.
+// @Security ApiKeyAuth
+// @Router /v1/projects/{id}/labels [post]
+func CreateLabel(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if _, err := repo.NewProjectRepo(database.GetDB()).Get(projectID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	l := &model.Label{}
+	if err := c.BodyParser(l); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	l.ID = uuid.New()
+	l.ProjectID = projectID
+	l.Name = strings.ToLower(strings.TrimSpace(l.Name))
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(l); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	labelRepo := repo.NewLabelRepo(database.GetDB())
+	if err := labelRepo.Create(l); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbLabel, err := labelRepo.Get(l.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"label": dbLabel})
+}
+
+// DeleteLabel removes a label from a project and all of its tasks.
+// @Security ApiKeyAuth
+// @Router /v1/projects/{id}/labels/{label_id} [delete]
+func DeleteLabel(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	labelID, err := uuid.Parse(c.Params("label_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	labelRepo := repo.NewLabelRepo(database.GetDB())
+	l, err := labelRepo.Get(labelID)
+	if err != nil || l.ProjectID != projectID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "label was not found"})
+	}
+
+	if err := labelRepo.Delete(labelID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{})
+}
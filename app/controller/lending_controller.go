@@ -0,0 +1,428 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func newLendingRepo() repo.LendingRepository {
+	return repo.NewLendingRepo(database.GetDB(), config.LendingCfg().ReservationHold)
+}
+
+// GetBookCopies lists the copies of a book and how many are on the shelf.
+// @Router /v1/books/{id}/copies [get]
+func GetBookCopies(c *fiber.Ctx) error {
+	bookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if _, err := repo.NewBookRepo(database.GetDB()).Get(bookID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book were not found"})
+	}
+
+	copies, err := newLendingRepo().Copies(bookID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if copies == nil {
+		copies = []*model.BookCopy{}
+	}
+
+	available := 0
+	for _, cp := range copies {
+		if cp.Available() {
+			available++
+		}
+	}
+	return c.JSON(fiber.Map{"copies": copies, "available": available})
+}
+
+// CreateBookCopy adds a copy to a book; admins only.
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/copies [post]
+func CreateBookCopy(c *fiber.Ctx) error {
+	bookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if _, err := repo.NewBookRepo(database.GetDB()).Get(bookID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book were not found"})
+	}
+
+	nc := &model.NewBookCopy{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(nc); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+		}
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(nc); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	lendingRepo := newLendingRepo()
+	cp := &model.BookCopy{ID: uuid.New(), BookID: bookID, Barcode: nc.Barcode, Note: nc.Note}
+	if err := lendingRepo.AddCopy(cp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbCopy, err := lendingRepo.GetCopy(cp.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"copy": dbCopy})
+}
+
+// WithdrawBookCopy takes a copy out of circulation; admins only. A copy on
+// loan has to be returned first.
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/copies/{copy_id} [delete]
+func WithdrawBookCopy(c *fiber.Ctx) error {
+	bookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	copyID, err := uuid.Parse(c.Params("copy_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	lendingRepo := newLendingRepo()
+	cp, err := lendingRepo.GetCopy(copyID)
+	if err != nil || cp.BookID != bookID || cp.WithdrawnAt != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "copy was not found"})
+	}
+
+	if err := lendingRepo.WithdrawCopy(cp, time.Now().UTC()); err != nil {
+		if errors.Is(err, repo.ErrCopyOnLoan) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{})
+}
+
+// CheckoutBook lends a copy of the book to the current user. Copies go to
+// the waitlist first: without a ready reservation this answers 409 when
+// every free copy is held for someone else.
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/checkout [post]
+func CheckoutBook(c *fiber.Ctx) error {
+	bookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	book, err := repo.NewBookRepo(database.GetDB()).Get(bookID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book were not found"})
+	}
+	if !book.Status.Lendable() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "only active books can be lent"})
+	}
+
+	ch := &model.Checkout{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(ch); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+		}
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(ch); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	now := time.Now().UTC()
+	cfg := config.LendingCfg()
+	due, err := ch.DueAt(now, cfg.LoanDays, cfg.MaxLoanDays)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	loan := &model.Loan{ID: uuid.New(), BookID: bookID, UserID: userID, CheckedOutAt: now, DueAt: due}
+	if ch.CopyID != nil {
+		loan.CopyID = *ch.CopyID
+	}
+
+	lendingRepo := newLendingRepo()
+	if err := lendingRepo.Checkout(loan); err != nil {
+		if errors.Is(err, repo.ErrNoCopyAvailable) || errors.Is(err, repo.ErrAlreadyBorrowed) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbLoan, err := lendingRepo.GetLoan(loan.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"loan": dbLoan})
+}
+
+// GetLoans lists loans, latest first: the current user's, or for admins
+// everyone's or those of ?user_id=<uuid>|me. Filters: ?book_id and
+// ?status=open|overdue.
+// @Security ApiKeyAuth
+// @Router /v1/loans [get]
+func GetLoans(c *fiber.Ctx) error {
+	pageNo, pageSize := GetPagination(c)
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	f := repo.LoanFilter{UserID: userID}
+	if IsAdminRequest(c) {
+		switch s := c.Query("user_id"); s {
+		case "":
+			f.UserID = uuid.Nil
+		case "me":
+		default:
+			id, err := uuid.Parse(s)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid user_id"})
+			}
+			f.UserID = id
+		}
+	}
+	if s := c.Query("book_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid book_id"})
+		}
+		f.BookID = id
+	}
+	switch c.Query("status") {
+	case "":
+	case "open":
+		f.Open = true
+	case "overdue":
+		f.Overdue = true
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "status must be open or overdue"})
+	}
+
+	loans, err := newLendingRepo().FindLoans(f, pageSize, uint(pageSize*(pageNo-1)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if loans == nil {
+		loans = []*model.Loan{}
+	}
+
+	return c.JSON(fiber.Map{
+		"page":      pageNo,
+		"page_size": pageSize,
+		"count":     len(loans),
+		"loans":     loans,
+	})
+}
+
+// GetLoan returns a loan to its borrower or an admin.
+// @Security ApiKeyAuth
+// @Router /v1/loans/{id} [get]
+func GetLoan(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	loan, err := newLendingRepo().GetLoan(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "loan was not found"})
+	}
+	if loan.UserID != userID && !IsAdminRequest(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only its borrower can see a loan"})
+	}
+
+	return c.JSON(fiber.Map{"loan": loan})
+}
+
+// ReturnLoan brings a copy back; its borrower or an admin may do so. The
+// copy is then held for the next reservation, if any.
+// @Security ApiKeyAuth
+// @Router /v1/loans/{id}/return [post]
+func ReturnLoan(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	lendingRepo := newLendingRepo()
+	loan, err := lendingRepo.GetLoan(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "loan was not found"})
+	}
+	if loan.UserID != userID && !IsAdminRequest(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only its borrower can return a loan"})
+	}
+
+	if err := lendingRepo.Return(id, time.Now().UTC()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "loan was returned already"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbLoan, err := lendingRepo.GetLoan(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"loan": dbLoan})
+}
+
+// ReserveBook puts the current user on the waitlist of the book. When a
+// copy is free the reservation is ready to check out right away.
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/reservations [post]
+func ReserveBook(c *fiber.Ctx) error {
+	bookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	book, err := repo.NewBookRepo(database.GetDB()).Get(bookID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book were not found"})
+	}
+	if !book.Status.Lendable() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "only active books can be reserved"})
+	}
+
+	lendingRepo := newLendingRepo()
+	r := &model.Reservation{ID: uuid.New(), BookID: bookID, UserID: userID, CreatedAt: time.Now().UTC()}
+	if err := lendingRepo.Reserve(r); err != nil {
+		if errors.Is(err, repo.ErrAlreadyReserved) || errors.Is(err, repo.ErrAlreadyBorrowed) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbReservation, err := lendingRepo.GetReservation(r.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"reservation": dbReservation})
+}
+
+// GetBookReservations lists the waitlist of a book in queue order.
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/reservations [get]
+func GetBookReservations(c *fiber.Ctx) error {
+	bookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if _, err := repo.NewBookRepo(database.GetDB()).Get(bookID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book were not found"})
+	}
+
+	reservations, err := newLendingRepo().FindReservations(repo.ReservationFilter{BookID: bookID, Open: true})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if reservations == nil {
+		reservations = []*model.Reservation{}
+	}
+	return c.JSON(fiber.Map{"reservations": reservations})
+}
+
+// GetReservations lists the current user's reservations, oldest first;
+// ?open=true leaves out the closed ones.
+// @Security ApiKeyAuth
+// @Router /v1/reservations [get]
+func GetReservations(c *fiber.Ctx) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	f := repo.ReservationFilter{UserID: userID, Open: c.QueryBool("open")}
+	reservations, err := newLendingRepo().FindReservations(f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if reservations == nil {
+		reservations = []*model.Reservation{}
+	}
+	return c.JSON(fiber.Map{"reservations": reservations})
+}
+
+// CancelReservation leaves the waitlist; its user or an admin may do so.
+// @Security ApiKeyAuth
+// @Router /v1/reservations/{id} [delete]
+func CancelReservation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	lendingRepo := newLendingRepo()
+	r, err := lendingRepo.GetReservation(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "reservation was not found"})
+	}
+	if r.UserID != userID && !IsAdminRequest(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only its user can cancel a reservation"})
+	}
+
+	if err := lendingRepo.CancelReservation(id, time.Now().UTC()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "reservation is closed already"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbReservation, err := lendingRepo.GetReservation(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"reservation": dbReservation})
+}
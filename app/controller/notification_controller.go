@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/notify"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetNotificationPreferences returns how the token's user is reminded of
+// their tasks, with the server defaults for unset fields.
+// @Security ApiKeyAuth
+// @Router /v1/notifications/preferences [get]
+func GetNotificationPreferences(c *fiber.Ctx) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	p, err := repo.NewReminderRepo(database.GetDB()).Preferences(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"preferences": p, "defaults": notificationDefaults()})
+}
+
+// UpdateNotificationPreferences replaces the token's user's preferences.
+// @Security ApiKeyAuth
+// @Router /v1/notifications/preferences [put]
+func UpdateNotificationPreferences(c *fiber.Ctx) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	p := model.DefaultNotificationPreferences(userID)
+	if err := c.BodyParser(p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	p.UserID = userID
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	cfg := config.NotifyCfg()
+	if p.Channel != nil && *p.Channel != notify.ChannelNone && !slices.Contains(cfg.Channels, *p.Channel) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg": fmt.Sprintf("channel %s is not enabled on this server", *p.Channel),
+		})
+	}
+	if p.Channel != nil && *p.Channel == notify.ChannelWebhook && p.WebhookURL == nil && cfg.WebhookURL == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "the webhook channel needs a webhook_url"})
+	}
+
+	reminderRepo := repo.NewReminderRepo(database.GetDB())
+	if err := reminderRepo.SetPreferences(p); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbPreferences, err := reminderRepo.Preferences(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"preferences": dbPreferences, "defaults": notificationDefaults()})
+}
+
+func notificationDefaults() fiber.Map {
+	cfg := config.NotifyCfg()
+	return fiber.Map{
+		"channel":               cfg.DefaultChannel,
+		"channels":              cfg.Channels,
+		"remind_before_minutes": int(cfg.ReminderLead.Minutes()),
+	}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	MIMEMergePatch = "application/merge-patch+json"
+	MIMEJSONPatch  = "application/json-patch+json"
+)
+
+var errUnsupportedPatch = errors.New("unsupported patch format, use " + MIMEMergePatch + " or " + MIMEJSONPatch)
+
+// ApplyPatch applies the request body to the JSON form of current and decodes
+// the result into patched, which should be a zero value of the same type.
+// The body is a JSON Merge Patch (RFC 7396) or, with the json-patch media
+// type, a JSON Patch (RFC 6902). Plain application/json is read as a merge
+// patch.
+func ApplyPatch(c *fiber.Ctx, current, patched interface{}) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	switch mediaType {
+	case MIMEMergePatch, fiber.MIMEApplicationJSON:
+		doc, err = jsonpatch.MergePatch(doc, c.Body())
+	case MIMEJSONPatch:
+		var patch jsonpatch.Patch
+		patch, err = jsonpatch.DecodePatch(c.Body())
+		if err == nil {
+			doc, err = patch.Apply(doc)
+		}
+	default:
+		return errUnsupportedPatch
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(doc, patched)
+}
+
+// PatchFailed answers a patch that could not be applied: 415 for an unknown
+// media type, 422 for a malformed patch or a failed operation.
+func PatchFailed(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errUnsupportedPatch) {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"msg": err.Error()})
+}
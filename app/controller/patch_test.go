@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	desc := "keep me"
+	stored := &model.Project{Name: "Stored", Description: &desc, Version: 2}
+
+	tests := []struct {
+		description     string
+		contentType     string
+		body            string
+		expectedCode    int
+		expectedName    string
+		wantDescription bool
+	}{
+		{"merge patch changes one field", MIMEMergePatch, `{"name": "Merged"}`, 200, "Merged", true},
+		{"merge patch null removes a field", MIMEMergePatch, `{"description": null}`, 200, "Stored", false},
+		{"plain JSON is a merge patch", fiber.MIMEApplicationJSON, `{"name": "Plain"}`, 200, "Plain", true},
+		{"json patch replace", MIMEJSONPatch, `[{"op": "replace", "path": "/name", "value": "Replaced"}]`, 200, "Replaced", true},
+		{"json patch remove", MIMEJSONPatch, `[{"op": "remove", "path": "/description"}]`, 200, "Stored", false},
+		{"json patch failed test", MIMEJSONPatch, `[{"op": "test", "path": "/name", "value": "Other"}]`, 422, "", false},
+		{"malformed json patch", MIMEJSONPatch, `{"op": "replace"}`, 422, "", false},
+		{"unsupported media type", fiber.MIMETextPlain, `name=x`, 415, "", false},
+	}
+
+	for _, test := range tests {
+		var patched *model.Project
+
+		app := fiber.New()
+		app.Patch("/", func(c *fiber.Ctx) error {
+			patched = &model.Project{}
+			if err := ApplyPatch(c, stored, patched); err != nil {
+				return PatchFailed(c, err)
+			}
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		req := httptest.NewRequest(fiber.MethodPatch, "/", strings.NewReader(test.body))
+		req.Header.Set(fiber.HeaderContentType, test.contentType)
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err, test.description)
+		assert.Equal(t, test.expectedCode, resp.StatusCode, test.description)
+		if test.expectedCode != fiber.StatusOK {
+			continue
+		}
+
+		assert.Equal(t, test.expectedName, patched.Name, test.description)
+		assert.Equal(t, test.wantDescription, patched.Description != nil, test.description)
+		assert.Equal(t, int64(2), patched.Version, test.description)
+	}
+
+	assert.Equal(t, "Stored", stored.Name, "the stored model is left untouched")
+}
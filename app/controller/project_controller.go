@@ -1,6 +1,9 @@
 package controller
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/byeblogs/go-boilerplate/app/model"
 	repo "github.com/byeblogs/go-boilerplate/app/repository"
 	"github.com/byeblogs/go-boilerplate/pkg/validator"
@@ -9,33 +12,48 @@ import (
 	"github.com/google/uuid"
 )
 
-// GetProjects supports optional filter: ?owner_user_id=<uuid>
+// GetProjects lists live projects. Filters: ?owner_user_id=<uuid>,
+// ?archived=true (include archived) or only, ?template=true (templates).
+// Asked for text/csv or application/x-ndjson (or ?format=csv|ndjson), it
+// streams every matching project instead of a page.
 // @Router /v1/projects [get]
 func GetProjects(c *fiber.Ctx) error {
 	pageNo, pageSize := GetPagination(c)
+	format, ok := listFormat(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "format must be json, csv or ndjson"})
+	}
 
 	projectRepo := repo.NewProjectRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		projectRepo = projectRepo.WithDeleted()
+	}
 
+	f := repo.ProjectFilter{Templates: c.QueryBool("template")}
 	if s := c.Query("owner_user_id"); s != "" {
 		ownerID, err := uuid.Parse(s)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid owner_user_id"})
 		}
+		f.OwnerID = ownerID
+	}
+	switch c.Query("archived") {
+	case "", "false":
+	case "true":
+		f.Archived = repo.IncludeArchived
+	case "only":
+		f.Archived = repo.OnlyArchived
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "archived must be true, false or only"})
+	}
 
-		projects, err := projectRepo.AllByOwner(ownerID, pageSize, uint(pageSize*(pageNo-1)))
-		if err != nil {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "projects were not found"})
-		}
-
-		return c.JSON(fiber.Map{
-			"page":      pageNo,
-			"page_size": pageSize,
-			"count":     len(projects),
-			"projects":  projects,
+	if format != "json" {
+		return projectExport.stream(c, format, func(fn func([]*model.Project) error) error {
+			return projectRepo.Each(f, exportBatchSize, fn)
 		})
 	}
 
-	projects, err := projectRepo.All(pageSize, uint(pageSize*(pageNo-1)))
+	projects, err := projectRepo.Find(f, pageSize, uint(pageSize*(pageNo-1)))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "projects were not found"})
 	}
@@ -48,6 +66,17 @@ func GetProjects(c *fiber.Ctx) error {
 	})
 }
 
+var projectExport = listExport[*model.Project]{
+	name:    "projects",
+	columns: []string{"id", "owner_user_id", "name", "description", "is_template", "archived_at", "created_at", "updated_at"},
+	row: func(p *model.Project) []string {
+		return []string{
+			p.ID.String(), p.OwnerUserID.String(), p.Name, csvString(p.Description), strconv.FormatBool(p.IsTemplate),
+			csvTime(p.ArchivedAt), csvTime(&p.CreatedAt), csvTime(&p.UpdatedAt),
+		}
+	},
+}
+
 // GetProject @Router /v1/projects/{id} [get]
 func GetProject(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
@@ -56,11 +85,18 @@ func GetProject(c *fiber.Ctx) error {
 	}
 
 	projectRepo := repo.NewProjectRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		projectRepo = projectRepo.WithDeleted()
+	}
 	p, err := projectRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
 	}
 
+	if IfNoneMatch(c, p.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.JSON(fiber.Map{"project": p})
 }
 
@@ -82,12 +118,18 @@ func CreateProject(c *fiber.Ctx) error {
 		})
 	}
 
-	projectRepo := repo.NewProjectRepo(database.GetDB())
+	projectRepo := repo.NewProjectRepo(AuditedDB(c))
 	if err := projectRepo.Create(p); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"project": p})
+	dbProject, err := projectRepo.Get(p.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbProject.Version))
+	return c.JSON(fiber.Map{"project": dbProject})
 }
 
 // UpdateProject @Security ApiKeyAuth
@@ -98,17 +140,23 @@ func UpdateProject(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	projectRepo := repo.NewProjectRepo(database.GetDB())
-	_, err = projectRepo.Get(id)
+	projectRepo := repo.NewProjectRepo(AuditedDB(c))
+	current, err := projectRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
 	}
 
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"project": current})
+	}
+
 	p := &model.Project{}
 	if err := c.BodyParser(p); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 	p.ID = id
+	p.Version = version
 
 	// On update, we typically don’t force owner_user_id revalidation.
 	// But if you want strict: keep validate.Struct(p) and require owner_user_id in body.
@@ -119,6 +167,11 @@ func UpdateProject(c *fiber.Ctx) error {
 	}
 
 	if err := projectRepo.Update(id, p); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := projectRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"project": current})
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
@@ -127,6 +180,59 @@ func UpdateProject(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
+	c.Set(fiber.HeaderETag, ETag(dbProject.Version))
+	return c.JSON(fiber.Map{"project": dbProject})
+}
+
+// PatchProject @Security ApiKeyAuth
+// @Router /v1/projects/{id} [patch]
+func PatchProject(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	projectRepo := repo.NewProjectRepo(AuditedDB(c))
+	current, err := projectRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"project": current})
+	}
+
+	p := &model.Project{}
+	if err := ApplyPatch(c, current, p); err != nil {
+		return PatchFailed(c, err)
+	}
+	p.ID = id
+	p.Version = version
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	if err := projectRepo.Patch(id, current, p); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := projectRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"project": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbProject, err := projectRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbProject.Version))
 	return c.JSON(fiber.Map{"project": dbProject})
 }
 
@@ -138,15 +244,192 @@ func DeleteProject(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	projectRepo := repo.NewProjectRepo(database.GetDB())
-	_, err = projectRepo.Get(id)
+	projectRepo := repo.NewProjectRepo(AuditedDB(c))
+	current, err := projectRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
 	}
 
-	if err := projectRepo.Delete(id); err != nil {
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"project": current})
+	}
+
+	if err := projectRepo.Delete(id, version); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := projectRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"project": current})
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{})
 }
+
+// RestoreProject restores a soft-deleted project.
+// @Security ApiKeyAuth
+// @Router /v1/projects/{id}/restore [post]
+func RestoreProject(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	projectRepo := repo.NewProjectRepo(AuditedDB(c))
+	p, err := projectRepo.WithDeleted().Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+	if p.DeletedAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "project is not deleted"})
+	}
+
+	if err := projectRepo.Restore(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	p, err = projectRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(p.Version))
+	return c.JSON(fiber.Map{"project": p})
+}
+
+// ArchiveProject hides a project from listings; its tasks are kept.
+// @Security ApiKeyAuth
+// @Router /v1/projects/{id}/archive [post]
+func ArchiveProject(c *fiber.Ctx) error {
+	return setProjectArchived(c, true)
+}
+
+// UnarchiveProject lists an archived project again.
+// @Security ApiKeyAuth
+// @Router /v1/projects/{id}/unarchive [post]
+func UnarchiveProject(c *fiber.Ctx) error {
+	return setProjectArchived(c, false)
+}
+
+func setProjectArchived(c *fiber.Ctx, archived bool) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	projectRepo := repo.NewProjectRepo(AuditedDB(c))
+	current, err := projectRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"project": current})
+	}
+
+	if archived {
+		err = projectRepo.Archive(id, version)
+	} else {
+		err = projectRepo.Unarchive(id, version)
+	}
+	if err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := projectRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"project": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbProject, err := projectRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbProject.Version))
+	return c.JSON(fiber.Map{"project": dbProject})
+}
+
+// CloneProject deep-copies a project, typically a template, into a new one
+// owned by the token's user. With start_at, due dates shift so the earliest
+// falls on it.
+// @Security ApiKeyAuth
+// @Router /v1/projects/{id}/clone [post]
+func CloneProject(c *fiber.Ctx) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	projectRepo := repo.NewProjectRepo(AuditedDB(c))
+	src, err := projectRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	opts := &model.CloneProject{}
+	if err := c.BodyParser(opts); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if opts.Description == nil {
+		opts.Description = src.Description
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(opts); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	dst := &model.Project{
+		ID:          uuid.New(),
+		OwnerUserID: userID,
+		Name:        opts.Name,
+		Description: opts.Description,
+		IsTemplate:  opts.IsTemplate,
+	}
+
+	copied, err := projectRepo.Clone(src.ID, dst, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbProject, err := projectRepo.Get(dst.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbProject.Version))
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"project": dbProject, "tasks_copied": copied})
+}
+
+// GetProjectBoard returns the project's live tasks grouped into one column
+// per status, in workflow order, each column in board order.
+// @Router /v1/projects/{id}/board [get]
+func GetProjectBoard(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	project, err := repo.NewProjectRepo(database.GetDB()).Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	tasks, err := repo.NewTaskRepo(database.GetDB()).Find(repo.TaskFilter{ProjectID: id, ByPosition: true}, 0, 0)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"project": project, "columns": model.NewBoard(tasks)})
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// upcomingOccurrences is how many future starts the recurrence endpoints show.
+const upcomingOccurrences = 5
+
+// GetTaskRecurrence returns the series a task belongs to and its next starts.
+// @Router /v1/tasks/{id}/recurrence [get]
+func GetTaskRecurrence(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	t, err := repo.NewTaskRepo(database.GetDB()).Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+	if t.RecurrenceID == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task does not recur"})
+	}
+
+	r, err := repo.NewRecurrenceRepo(database.GetDB()).Get(*t.RecurrenceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"recurrence": r, "upcoming": upcoming(r)})
+}
+
+// SetTaskRecurrence makes a task recur, or changes the schedule of its
+// series. The rule is an RFC 5545 RRULE such as "FREQ=WEEKLY;BYDAY=MO",
+// evaluated in the given IANA time zone (UTC by default). The task's due_at
+// keeps its distance from starts_at in every occurrence.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/recurrence [put]
+func SetTaskRecurrence(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	t, err := repo.NewTaskRepo(AuditedDB(c)).Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	w := &model.WriteTaskRecurrence{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	validate := validator.NewValidator()
+	if err := validate.Struct(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+	if w.Timezone == "" {
+		w.Timezone = "UTC"
+	}
+
+	recurrenceRepo := repo.NewRecurrenceRepo(AuditedDB(c))
+	r := &model.TaskRecurrence{ID: uuid.New(), TaskID: t.ID}
+	if t.RecurrenceID != nil {
+		if r, err = recurrenceRepo.Get(*t.RecurrenceID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+		}
+	}
+
+	r.Rule, r.Timezone = w.Rule, w.Timezone
+	rule, _, err := r.Schedule()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	r.Rule = rule.String()
+
+	// a new series starts at the task, an existing one keeps its start
+	// unless asked otherwise
+	switch {
+	case w.StartsAt != nil:
+		r.StartsAt = w.StartsAt.UTC()
+	case t.RecurrenceID != nil:
+	case t.DueAt != nil:
+		r.StartsAt = t.DueAt.UTC()
+	default:
+		r.StartsAt = t.CreatedAt.UTC()
+	}
+	if t.DueAt != nil && (w.StartsAt != nil || t.RecurrenceID == nil) {
+		offset := int64(t.DueAt.Sub(r.StartsAt) / time.Second)
+		r.DueOffset = &offset
+	}
+
+	status := fiber.StatusOK
+	if t.RecurrenceID == nil {
+		err = recurrenceRepo.Create(r)
+		status = fiber.StatusCreated
+	} else {
+		err = recurrenceRepo.Update(r)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	created, err := recurrenceRepo.Advance(r.ID, time.Now().UTC().Add(config.WorkerCfg().RecurrenceWindow), true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbRecurrence, err := recurrenceRepo.Get(r.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"recurrence": dbRecurrence,
+		"upcoming":   upcoming(dbRecurrence),
+		"created":    created,
+	})
+}
+
+// DeleteTaskRecurrence ends the series of a task. Its occurrences are kept.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/recurrence [delete]
+func DeleteTaskRecurrence(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	t, err := repo.NewTaskRepo(AuditedDB(c)).Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+	if t.RecurrenceID == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task does not recur"})
+	}
+
+	if err := repo.NewRecurrenceRepo(AuditedDB(c)).Delete(*t.RecurrenceID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{})
+}
+
+// advanceRecurrence creates the next occurrence of a recurring task that was
+// just closed. Failing to do so doesn't fail the request; the scheduler
+// catches up on its next run.
+func advanceRecurrence(before, after *model.Task) {
+	if after.RecurrenceID == nil || before.Status.Closed() || !after.Status.Closed() {
+		return
+	}
+	upTo := time.Now().UTC().Add(config.WorkerCfg().RecurrenceWindow)
+	if _, err := repo.NewRecurrenceRepo(database.GetDB()).Advance(*after.RecurrenceID, upTo, true); err != nil {
+		logr.Errorf("recurrence %s: %v", *after.RecurrenceID, err)
+	}
+}
+
+// upcoming lists the next starts of the series, in its time zone.
+func upcoming(r *model.TaskRecurrence) []time.Time {
+	rule, loc, err := r.Schedule()
+	if err != nil {
+		return []time.Time{}
+	}
+	out := rule.Occurrences(r.StartsAt.In(loc), r.LastOccurrenceAt, upcomingOccurrences)
+	if out == nil {
+		out = []time.Time{}
+	}
+	return out
+}
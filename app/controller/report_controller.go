@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// defaultReportSpan is how far back a report reaches without ?from.
+const defaultReportSpan = 12 * 7 * 24 * time.Hour
+
+// GetProjectStats counts the project's live tasks by status, with the
+// overdue ones and the completion rate.
+// @Router /v1/projects/{id}/stats [get]
+func GetProjectStats(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if _, err := repo.NewProjectRepo(database.GetDB()).Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "project was not found"})
+	}
+
+	stats, err := repo.NewReportRepo(database.GetDB()).ProjectStats(id, time.Now().UTC())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"stats": stats})
+}
+
+// GetThroughput counts the tasks done per period with their average cycle
+// time (start, or creation, to done). ?from and ?to take RFC 3339 times or
+// dates and default to the last 12 weeks; ?group_by=day|week|month (week);
+// ?project_id narrows it to one project. With REPORTS_MATERIALIZED the
+// counts come from a periodically refreshed view unless ?live=true.
+// @Router /v1/reports/throughput [get]
+func GetThroughput(c *fiber.Ctx) error {
+	now := time.Now().UTC()
+	f := repo.ThroughputFilter{To: now, GroupBy: model.ReportPeriod(c.Query("group_by", string(model.PeriodWeek)))}
+	if !f.GroupBy.Valid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "group_by must be day, week or month"})
+	}
+
+	var err error
+	if s := c.Query("to"); s != "" {
+		if f.To, err = parseReportTime(s); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid to: " + err.Error()})
+		}
+	}
+	f.From = f.To.Add(-defaultReportSpan)
+	if s := c.Query("from"); s != "" {
+		if f.From, err = parseReportTime(s); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid from: " + err.Error()})
+		}
+	}
+	if !f.From.Before(f.To) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "from must be before to"})
+	}
+	if s := c.Query("project_id"); s != "" {
+		if f.ProjectID, err = uuid.Parse(s); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid project_id"})
+		}
+	}
+
+	// check the span before querying, FillThroughput would refuse it anyway
+	if _, err := model.FillThroughput(nil, f.From, f.To, f.GroupBy); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	reportRepo := repo.NewReportRepo(database.GetDB())
+	source, read := "live", reportRepo.Throughput
+	if config.ReportsCfg().Materialized && !c.QueryBool("live") {
+		source, read = "materialized", reportRepo.MaterializedThroughput
+	}
+	rows, err := read(f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	periods, err := model.FillThroughput(rows, f.From, f.To, f.GroupBy)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	completed, cycle := model.ThroughputTotals(periods)
+
+	return c.JSON(fiber.Map{
+		"from":                 f.From,
+		"to":                   f.To,
+		"group_by":             f.GroupBy,
+		"source":               source,
+		"completed":            completed,
+		"avg_cycle_time_hours": cycle,
+		"periods":              periods,
+	})
+}
+
+// parseReportTime reads an RFC 3339 time or a date, taken as UTC midnight.
+func parseReportTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a date", s)
+}
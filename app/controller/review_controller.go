@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetBookReviews lists the reviews of a book, newest first, with the
+// book's rating.
+// @Router /v1/books/{id}/reviews [get]
+func GetBookReviews(c *fiber.Ctx) error {
+	pageNo, pageSize := GetPagination(c)
+
+	bookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	book, err := repo.NewBookRepo(database.GetDB()).Get(bookID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book were not found"})
+	}
+
+	reviews, err := repo.NewReviewRepo(database.GetDB()).Find(bookID, pageSize, uint(pageSize*(pageNo-1)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if reviews == nil {
+		reviews = []*model.BookReview{}
+	}
+
+	return c.JSON(fiber.Map{
+		"page":           pageNo,
+		"page_size":      pageSize,
+		"count":          len(reviews),
+		"rating_count":   book.RatingCount,
+		"rating_average": book.RatingAverage,
+		"reviews":        reviews,
+	})
+}
+
+// CreateBookReview reviews the book as the current user; a user reviews a
+// book once and edits that review afterwards.
+// @Security ApiKeyAuth
+// @Router /v1/books/{id}/reviews [post]
+func CreateBookReview(c *fiber.Ctx) error {
+	bookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	if _, err := repo.NewBookRepo(AuditedDB(c)).Get(bookID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "book were not found"})
+	}
+
+	w := &model.WriteBookReview{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	reviewRepo := repo.NewReviewRepo(AuditedDB(c))
+	r := &model.BookReview{ID: uuid.New(), BookID: bookID, UserID: userID, Rating: w.Rating, Body: w.Body}
+	if err := reviewRepo.Create(r); err != nil {
+		if errors.Is(err, repo.ErrAlreadyReviewed) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbReview, err := reviewRepo.Get(r.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbReview.Version))
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"review": dbReview})
+}
+
+// GetReview @Router /v1/reviews/{id} [get]
+func GetReview(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	r, err := repo.NewReviewRepo(database.GetDB()).Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "review was not found"})
+	}
+
+	if IfNoneMatch(c, r.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderETag, ETag(r.Version))
+	return c.JSON(fiber.Map{"review": r})
+}
+
+// UpdateReview rewrites a review; only its author may do so.
+// @Security ApiKeyAuth
+// @Router /v1/reviews/{id} [put]
+func UpdateReview(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	reviewRepo := repo.NewReviewRepo(AuditedDB(c))
+	current, err := reviewRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "review was not found"})
+	}
+	if current.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only its author can edit a review"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"review": current})
+	}
+
+	w := &model.WriteBookReview{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	r := &model.BookReview{ID: id, Rating: w.Rating, Body: w.Body, Version: version}
+	if err := reviewRepo.Update(r); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := reviewRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"review": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbReview, err := reviewRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbReview.Version))
+	return c.JSON(fiber.Map{"review": dbReview})
+}
+
+// DeleteReview removes a review; its author or an admin may do so.
+// @Security ApiKeyAuth
+// @Router /v1/reviews/{id} [delete]
+func DeleteReview(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	reviewRepo := repo.NewReviewRepo(AuditedDB(c))
+	current, err := reviewRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "review was not found"})
+	}
+	if current.UserID != userID && !IsAdminRequest(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only its author can delete a review"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"review": current})
+	}
+
+	if err := reviewRepo.Delete(id, version); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := reviewRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"review": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{})
+}
@@ -3,8 +3,11 @@ package controller
 import (
 	"strconv"
 
+	"github.com/byeblogs/go-boilerplate/platform/database"
 	"github.com/byeblogs/go-boilerplate/platform/logger"
+	"github.com/form3tech-oss/jwt-go"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 var logr = logger.GetLogger()
@@ -43,3 +46,54 @@ func GetPagination(c *fiber.Ctx) (pageNo, pageSize int) {
 
 	return pageNo, pageSize
 }
+
+// GetClaims returns the JWT claims of the request, if it carried a valid token.
+func GetClaims(c *fiber.Ctx) (jwt.MapClaims, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	return claims, ok
+}
+
+// CurrentUserID returns the user_id claim of the request's token.
+func CurrentUserID(c *fiber.Ctx) (uuid.UUID, bool) {
+	claims, ok := GetClaims(c)
+	if !ok {
+		return uuid.Nil, false
+	}
+	s, _ := claims["user_id"].(string)
+	id, err := uuid.Parse(s)
+	return id, err == nil
+}
+
+// AuditedDB returns the database to make the request's writes through, so
+// that the audit log records who made them, from where and in which
+// request.
+func AuditedDB(c *fiber.Ctx) *database.DB {
+	a := database.Audit{IP: c.IP()}
+	if id, ok := CurrentUserID(c); ok {
+		a.ActorID = id.String()
+	}
+	if id, ok := c.Locals("requestid").(string); ok {
+		a.RequestID = id
+	}
+	return database.GetDB().WithAudit(a)
+}
+
+// IsAdminRequest reports whether the request was made with an admin token.
+func IsAdminRequest(c *fiber.Ctx) bool {
+	claims, ok := GetClaims(c)
+	if !ok {
+		return false
+	}
+	isAdmin, _ := claims["admin"].(bool)
+	return isAdmin
+}
+
+// IncludeDeleted reports whether soft-deleted rows should be part of the
+// response: admins may ask for them with ?include_deleted=true.
+func IncludeDeleted(c *fiber.Ctx) bool {
+	return c.QueryBool("include_deleted") && IsAdminRequest(c)
+}
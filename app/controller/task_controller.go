@@ -1,7 +1,9 @@
 package controller
 
 import (
-	"strings"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/byeblogs/go-boilerplate/app/model"
 	repo "github.com/byeblogs/go-boilerplate/app/repository"
@@ -11,33 +13,68 @@ import (
 	"github.com/google/uuid"
 )
 
-// GetTasks supports optional filter: ?project_id=<uuid>
+// GetTasks supports optional filters: ?project_id=<uuid>, ?assignee=<uuid>|me,
+// ?label=<name> and ?priority=low|medium|high|urgent. Tasks come newest
+// first, or in board order with ?sort=position. Asked for text/csv or
+// application/x-ndjson (or ?format=csv|ndjson), it streams every matching
+// task instead of a page.
 // @Router /v1/tasks [get]
 func GetTasks(c *fiber.Ctx) error {
 	pageNo, pageSize := GetPagination(c)
+	format, ok := listFormat(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "format must be json, csv or ndjson"})
+	}
 
 	taskRepo := repo.NewTaskRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		taskRepo = taskRepo.WithDeleted()
+	}
 
+	f := repo.TaskFilter{
+		Label:    c.Query("label"),
+		Priority: model.TaskPriority(c.Query("priority")),
+	}
+	if f.Priority != "" && !f.Priority.Valid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid priority"})
+	}
+	switch c.Query("sort") {
+	case "", "-created_at":
+	case "position":
+		f.ByPosition = true
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "sort must be position or -created_at"})
+	}
 	if s := c.Query("project_id"); s != "" {
 		projectID, err := uuid.Parse(s)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid project_id"})
 		}
-
-		tasks, err := taskRepo.AllByProject(projectID, pageSize, uint(pageSize*(pageNo-1)))
+		f.ProjectID = projectID
+	}
+	switch s := c.Query("assignee"); s {
+	case "":
+	case "me":
+		userID, ok := CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"msg": "assignee=me needs a token"})
+		}
+		f.AssigneeID = userID
+	default:
+		userID, err := uuid.Parse(s)
 		if err != nil {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "tasks were not found"})
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid assignee"})
 		}
+		f.AssigneeID = userID
+	}
 
-		return c.JSON(fiber.Map{
-			"page":      pageNo,
-			"page_size": pageSize,
-			"count":     len(tasks),
-			"tasks":     tasks,
+	if format != "json" {
+		return taskExport.stream(c, format, func(fn func([]*model.Task) error) error {
+			return taskRepo.Each(f, exportBatchSize, fn)
 		})
 	}
 
-	tasks, err := taskRepo.All(pageSize, uint(pageSize*(pageNo-1)))
+	tasks, err := taskRepo.Find(f, pageSize, uint(pageSize*(pageNo-1)))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "tasks were not found"})
 	}
@@ -50,6 +87,28 @@ func GetTasks(c *fiber.Ctx) error {
 	})
 }
 
+var taskExport = listExport[*model.Task]{
+	name: "tasks",
+	columns: []string{"id", "project_id", "parent_task_id", "title", "description", "status", "priority",
+		"assignees", "labels", "due_at", "estimate_minutes", "started_at", "completed_at", "position",
+		"created_at", "updated_at"},
+	row: func(t *model.Task) []string {
+		assignees := make([]string, len(t.Assignees))
+		for i, id := range t.Assignees {
+			assignees[i] = id.String()
+		}
+		parent := ""
+		if t.ParentTaskID != nil {
+			parent = t.ParentTaskID.String()
+		}
+		return []string{
+			t.ID.String(), t.ProjectID.String(), parent, t.Title, csvString(t.Description), string(t.Status), string(t.Priority),
+			csvList(assignees), csvList(t.Labels), csvTime(t.DueAt), csvInt(t.EstimateMinutes), csvTime(t.StartedAt),
+			csvTime(t.CompletedAt), t.Position, csvTime(&t.CreatedAt), csvTime(&t.UpdatedAt),
+		}
+	},
+}
+
 // GetTask @Router /v1/tasks/{id} [get]
 func GetTask(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
@@ -58,11 +117,18 @@ func GetTask(c *fiber.Ctx) error {
 	}
 
 	taskRepo := repo.NewTaskRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		taskRepo = taskRepo.WithDeleted()
+	}
 	t, err := taskRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
 	}
 
+	if IfNoneMatch(c, t.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.JSON(fiber.Map{"task": t})
 }
 
@@ -75,9 +141,14 @@ func CreateTask(c *fiber.Ctx) error {
 	}
 
 	t.ID = uuid.New()
-	if strings.TrimSpace(t.Status) == "" {
-		t.Status = "todo"
+	if t.Status == "" {
+		t.Status = model.TaskTodo
+	}
+	if t.Priority == "" {
+		t.Priority = model.TaskPriorityMedium
 	}
+	t.Labels = model.NormalizeLabels(t.Labels)
+	t.StartedAt, t.CompletedAt, t.Position = nil, nil, ""
 
 	validate := validator.NewValidator()
 	if err := validate.Struct(t); err != nil {
@@ -87,12 +158,27 @@ func CreateTask(c *fiber.Ctx) error {
 		})
 	}
 
-	taskRepo := repo.NewTaskRepo(database.GetDB())
+	if err := checkAssignees(t.Assignees); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if err := checkParentTask(t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	TaskWorkflow().Stamp(t, time.Now().UTC())
+
+	taskRepo := repo.NewTaskRepo(AuditedDB(c))
 	if err := taskRepo.Create(t); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"task": t})
+	dbTask, err := taskRepo.Get(t.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbTask.Version))
+	return c.JSON(fiber.Map{"task": dbTask})
 }
 
 // UpdateTask @Security ApiKeyAuth
@@ -103,21 +189,32 @@ func UpdateTask(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	taskRepo := repo.NewTaskRepo(database.GetDB())
-	_, err = taskRepo.Get(id)
+	taskRepo := repo.NewTaskRepo(AuditedDB(c))
+	current, err := taskRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
 	}
 
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+	}
+
 	t := &model.Task{}
 	if err := c.BodyParser(t); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 	t.ID = id
+	t.ProjectID = current.ProjectID // tasks don't move between projects
+	t.Version = version
 
-	if strings.TrimSpace(t.Status) == "" {
-		t.Status = "todo"
+	if t.Status == "" {
+		t.Status = current.Status
+	}
+	if t.Priority == "" {
+		t.Priority = current.Priority
 	}
+	t.Labels = model.NormalizeLabels(t.Labels)
 
 	validate := validator.NewValidator()
 	if err := validate.Struct(t); err != nil {
@@ -127,7 +224,108 @@ func UpdateTask(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := checkAssignees(t.Assignees); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if err := checkParentTask(t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if err := moveTask(current, t); err != nil {
+		return TransitionFailed(c, err)
+	}
+
 	if err := taskRepo.Update(id, t); err != nil {
+		if errors.As(err, new(*repo.BlockedError)) {
+			return TransitionFailed(c, err)
+		}
+		if errors.Is(err, repo.ErrCycle) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "parent_task_id: " + err.Error()})
+		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := taskRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbTask, err := taskRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	advanceRecurrence(current, dbTask)
+
+	c.Set(fiber.HeaderETag, ETag(dbTask.Version))
+	return c.JSON(fiber.Map{"task": dbTask})
+}
+
+// PatchTask @Security ApiKeyAuth
+// @Router /v1/tasks/{id} [patch]
+func PatchTask(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	taskRepo := repo.NewTaskRepo(AuditedDB(c))
+	current, err := taskRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+	}
+
+	t := &model.Task{}
+	if err := ApplyPatch(c, current, t); err != nil {
+		return PatchFailed(c, err)
+	}
+	t.ID = id
+	t.ProjectID = current.ProjectID // tasks don't move between projects
+	t.Version = version
+
+	if t.Status == "" {
+		t.Status = current.Status
+	}
+	if t.Priority == "" {
+		t.Priority = current.Priority
+	}
+	t.Labels = model.NormalizeLabels(t.Labels)
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	if err := checkAssignees(t.Assignees); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if err := checkParentTask(t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	if err := moveTask(current, t); err != nil {
+		return TransitionFailed(c, err)
+	}
+
+	if err := taskRepo.Patch(id, current, t); err != nil {
+		if errors.As(err, new(*repo.BlockedError)) {
+			return TransitionFailed(c, err)
+		}
+		if errors.Is(err, repo.ErrCycle) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "parent_task_id: " + err.Error()})
+		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := taskRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
@@ -135,10 +333,142 @@ func UpdateTask(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
+	advanceRecurrence(current, dbTask)
 
+	c.Set(fiber.HeaderETag, ETag(dbTask.Version))
 	return c.JSON(fiber.Map{"task": dbTask})
 }
 
+// TransitionTask moves a task to another status, as the workflow allows.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/transition [post]
+func TransitionTask(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	taskRepo := repo.NewTaskRepo(AuditedDB(c))
+	current, err := taskRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+	}
+
+	tr := &model.TaskTransition{}
+	if err := c.BodyParser(tr); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(tr); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	t := *current
+	t.Status, t.Version = tr.Status, version
+	if err := moveTask(current, &t); err != nil {
+		return TransitionFailed(c, err)
+	}
+
+	if err := taskRepo.Patch(id, current, &t); err != nil {
+		if errors.As(err, new(*repo.BlockedError)) {
+			return TransitionFailed(c, err)
+		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := taskRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbTask, err := taskRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	advanceRecurrence(current, dbTask)
+
+	c.Set(fiber.HeaderETag, ETag(dbTask.Version))
+	return c.JSON(fiber.Map{"task": dbTask, "next": TaskWorkflow().Next(dbTask.Status)})
+}
+
+// MoveTask moves a task on the board: to another status, which has to be
+// allowed by the workflow, and/or next to other tasks of that column. Both
+// change in one write.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/move [post]
+func MoveTask(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	taskRepo := repo.NewTaskRepo(AuditedDB(c))
+	current, err := taskRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+	}
+
+	mv := &model.TaskMove{}
+	if err := c.BodyParser(mv); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(mv); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	t := *current
+	t.Version = version
+	if mv.Status != "" && mv.Status != current.Status {
+		t.Status = mv.Status
+		if err := moveTask(current, &t); err != nil {
+			return TransitionFailed(c, err)
+		}
+	}
+
+	if err := taskRepo.Move(id, &t, mv.AfterID, mv.BeforeID); err != nil {
+		if errors.As(err, new(*repo.BlockedError)) {
+			return TransitionFailed(c, err)
+		}
+		if errors.Is(err, repo.ErrBadNeighbour) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+		}
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := taskRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbTask, err := taskRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	advanceRecurrence(current, dbTask)
+
+	c.Set(fiber.HeaderETag, ETag(dbTask.Version))
+	return c.JSON(fiber.Map{"task": dbTask, "next": TaskWorkflow().Next(dbTask.Status)})
+}
+
 // DeleteTask @Security ApiKeyAuth
 // @Router /v1/tasks/{id} [delete]
 func DeleteTask(c *fiber.Ctx) error {
@@ -147,15 +477,85 @@ func DeleteTask(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	taskRepo := repo.NewTaskRepo(database.GetDB())
-	_, err = taskRepo.Get(id)
+	taskRepo := repo.NewTaskRepo(AuditedDB(c))
+	current, err := taskRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
 	}
 
-	if err := taskRepo.Delete(id); err != nil {
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+	}
+
+	if err := taskRepo.Delete(id, version); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := taskRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"task": current})
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{})
 }
+
+// RestoreTask restores a soft-deleted task.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/restore [post]
+func RestoreTask(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	taskRepo := repo.NewTaskRepo(AuditedDB(c))
+	t, err := taskRepo.WithDeleted().Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+	if t.DeletedAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "task is not deleted"})
+	}
+
+	if err := taskRepo.Restore(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	t, err = taskRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(t.Version))
+	return c.JSON(fiber.Map{"task": t})
+}
+
+// checkAssignees makes sure every assignee is a live user.
+func checkAssignees(ids []uuid.UUID) error {
+	userRepo := repo.NewUserRepo(database.GetDB())
+	for _, id := range ids {
+		if _, err := userRepo.Get(id); err != nil {
+			return fmt.Errorf("assignee %s was not found", id)
+		}
+	}
+	return nil
+}
+
+// checkParentTask makes sure the parent of t is a live task of the same project.
+func checkParentTask(t *model.Task) error {
+	if t.ParentTaskID == nil {
+		return nil
+	}
+	if *t.ParentTaskID == t.ID {
+		return errors.New("a task can't be its own parent")
+	}
+	parent, err := repo.NewTaskRepo(database.GetDB()).Get(*t.ParentTaskID)
+	if err != nil {
+		return fmt.Errorf("parent task %s was not found", *t.ParentTaskID)
+	}
+	if parent.ProjectID != t.ProjectID {
+		return errors.New("a subtask must belong to the project of its parent")
+	}
+	return nil
+}
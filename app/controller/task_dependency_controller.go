@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetTaskTree returns a task with its subtasks, nested.
+// @Router /v1/tasks/{id}/tree [get]
+func GetTaskTree(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	taskRepo := repo.NewTaskRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		taskRepo = taskRepo.WithDeleted()
+	}
+	tasks, err := taskRepo.Tree(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	if len(tasks) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	return c.JSON(fiber.Map{"task": model.NewTaskTree(tasks)})
+}
+
+// GetTaskDependencies returns the tasks blocking the task and the tasks it blocks.
+// @Router /v1/tasks/{id}/dependencies [get]
+func GetTaskDependencies(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	taskRepo := repo.NewTaskRepo(database.GetDB())
+	if _, err := taskRepo.Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	return taskDependencies(c, taskRepo, id)
+}
+
+// AddTaskDependency blocks a task on another one.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/dependencies [post]
+func AddTaskDependency(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dep := &model.TaskDependency{}
+	if err := c.BodyParser(dep); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(dep); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+	if dep.BlockedByID == id {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "a task can't block itself"})
+	}
+
+	taskRepo := repo.NewTaskRepo(database.GetDB())
+	if _, err := taskRepo.Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+	if _, err := taskRepo.Get(dep.BlockedByID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "blocking task was not found"})
+	}
+
+	if err := taskRepo.AddDependency(id, dep.BlockedByID); err != nil {
+		if errors.Is(err, repo.ErrCycle) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return taskDependencies(c, taskRepo, id)
+}
+
+// RemoveTaskDependency unblocks a task from another one.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/dependencies/{blocker_id} [delete]
+func RemoveTaskDependency(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	blockerID, err := uuid.Parse(c.Params("blocker_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	taskRepo := repo.NewTaskRepo(database.GetDB())
+	if _, err := taskRepo.Get(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	if err := taskRepo.RemoveDependency(id, blockerID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return taskDependencies(c, taskRepo, id)
+}
+
+func taskDependencies(c *fiber.Ctx, taskRepo repo.TaskRepository, id uuid.UUID) error {
+	blockedBy, err := taskRepo.Blockers(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	blocking, err := taskRepo.Blocking(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"blocked_by": nonNil(blockedBy),
+		"blocking":   nonNil(blocking),
+	})
+}
+
+func nonNil(tasks []*model.Task) []*model.Task {
+	if tasks == nil {
+		return []*model.Task{}
+	}
+	return tasks
+}
@@ -0,0 +1,423 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetTimeEntries lists time entries, latest first. Filters: ?task_id,
+// ?project_id, ?user_id=<uuid>|me, and ?from / ?to on the start time.
+// @Security ApiKeyAuth
+// @Router /v1/time-entries [get]
+func GetTimeEntries(c *fiber.Ctx) error {
+	pageNo, pageSize := GetPagination(c)
+
+	f, err := timeEntryFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	entries, err := repo.NewTimeEntryRepo(database.GetDB()).Find(f, pageSize, uint(pageSize*(pageNo-1)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"page":         pageNo,
+		"page_size":    pageSize,
+		"count":        len(entries),
+		"time_entries": entries,
+	})
+}
+
+// ExportTimeEntries returns every entry matching the filters of
+// GetTimeEntries as CSV, for billing.
+// @Security ApiKeyAuth
+// @Router /v1/time-entries/export [get]
+func ExportTimeEntries(c *fiber.Ctx) error {
+	f, err := timeEntryFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	entries, err := repo.NewTimeEntryRepo(database.GetDB()).Find(f, 0, 0)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="time-entries.csv"`)
+	return writeTimeEntriesCSV(c, entries)
+}
+
+func writeTimeEntriesCSV(w io.Writer, entries []*model.TimeEntry) error {
+	out := csv.NewWriter(w)
+	_ = out.Write([]string{"id", "project_id", "task_id", "task_title", "user_id", "started_at", "ended_at", "duration_minutes", "note"})
+	for _, e := range entries {
+		ended, note := "", ""
+		if e.EndedAt != nil {
+			ended = e.EndedAt.UTC().Format(time.RFC3339)
+		}
+		if e.Note != nil {
+			note = *e.Note
+		}
+		_ = out.Write([]string{
+			e.ID.String(), e.ProjectID.String(), e.TaskID.String(), e.TaskTitle, e.UserID.String(),
+			e.StartedAt.UTC().Format(time.RFC3339), ended,
+			strconv.FormatFloat(float64(e.DurationSeconds)/60, 'f', 2, 64), note,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// GetTimeSummary totals the tracked time per ?group_by=project|task|user
+// (project), with the filters of GetTimeEntries.
+// @Security ApiKeyAuth
+// @Router /v1/time-entries/summary [get]
+func GetTimeSummary(c *fiber.Ctx) error {
+	group := model.TimeGroup(c.Query("group_by", string(model.TimeByProject)))
+	if !group.Valid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "group_by must be project, task or user"})
+	}
+
+	f, err := timeEntryFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	summary, err := repo.NewTimeEntryRepo(database.GetDB()).Summary(group, f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	var total int64
+	for _, s := range summary {
+		total += s.Seconds
+	}
+	return c.JSON(fiber.Map{"group_by": group, "total_seconds": total, "summary": summary})
+}
+
+// timeEntryFilter reads the filters shared by the time entry listings.
+func timeEntryFilter(c *fiber.Ctx) (repo.TimeEntryFilter, error) {
+	f := repo.TimeEntryFilter{}
+	ids := []struct {
+		param string
+		dst   *uuid.UUID
+	}{{"task_id", &f.TaskID}, {"project_id", &f.ProjectID}, {"user_id", &f.UserID}}
+	for _, id := range ids {
+		s := c.Query(id.param)
+		if s == "" {
+			continue
+		}
+		if s == "me" && id.param == "user_id" {
+			userID, ok := CurrentUserID(c)
+			if !ok {
+				return f, errors.New("can't extract user info from request")
+			}
+			*id.dst = userID
+			continue
+		}
+		v, err := uuid.Parse(s)
+		if err != nil {
+			return f, errors.New("invalid " + id.param)
+		}
+		*id.dst = v
+	}
+
+	var err error
+	if s := c.Query("from"); s != "" {
+		if f.From, err = parseReportTime(s); err != nil {
+			return f, errors.New("invalid from: " + err.Error())
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if f.To, err = parseReportTime(s); err != nil {
+			return f, errors.New("invalid to: " + err.Error())
+		}
+	}
+	return f, nil
+}
+
+// GetTimeEntry @Security ApiKeyAuth
+// @Router /v1/time-entries/{id} [get]
+func GetTimeEntry(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	e, err := repo.NewTimeEntryRepo(database.GetDB()).Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "time entry was not found"})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(e.Version))
+	return c.JSON(fiber.Map{"time_entry": e})
+}
+
+// CreateTaskTimeEntry records time the current user spent on the task.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/time-entries [post]
+func CreateTaskTimeEntry(c *fiber.Ctx) error {
+	taskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	if _, err := repo.NewTaskRepo(database.GetDB()).Get(taskID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	w := &model.WriteTimeEntry{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	end, err := w.End(time.Now().UTC())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	e := &model.TimeEntry{ID: uuid.New(), TaskID: taskID, UserID: userID, StartedAt: w.StartedAt, EndedAt: &end, Note: w.Note}
+
+	timeEntryRepo := repo.NewTimeEntryRepo(database.GetDB())
+	if err := timeEntryRepo.Create(e); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbEntry, err := timeEntryRepo.Get(e.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbEntry.Version))
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"time_entry": dbEntry})
+}
+
+// UpdateTimeEntry rewrites an entry; only its user or an admin may do so.
+// Rewriting a running timer stops it.
+// @Security ApiKeyAuth
+// @Router /v1/time-entries/{id} [put]
+func UpdateTimeEntry(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	timeEntryRepo := repo.NewTimeEntryRepo(database.GetDB())
+	current, err := timeEntryRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "time entry was not found"})
+	}
+	if current.UserID != userID && !IsAdminRequest(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only its user can edit a time entry"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"time_entry": current})
+	}
+
+	w := &model.WriteTimeEntry{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(w); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	end, err := w.End(time.Now().UTC())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	e := &model.TimeEntry{ID: id, StartedAt: w.StartedAt, EndedAt: &end, Note: w.Note, Version: version}
+
+	if err := timeEntryRepo.Update(e); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := timeEntryRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"time_entry": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbEntry, err := timeEntryRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbEntry.Version))
+	return c.JSON(fiber.Map{"time_entry": dbEntry})
+}
+
+// DeleteTimeEntry removes an entry; only its user or an admin may do so.
+// @Security ApiKeyAuth
+// @Router /v1/time-entries/{id} [delete]
+func DeleteTimeEntry(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	timeEntryRepo := repo.NewTimeEntryRepo(database.GetDB())
+	current, err := timeEntryRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "time entry was not found"})
+	}
+	if current.UserID != userID && !IsAdminRequest(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"msg": "only its user can delete a time entry"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"time_entry": current})
+	}
+
+	if err := timeEntryRepo.Delete(id, version); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := timeEntryRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"time_entry": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{})
+}
+
+// GetTimer returns the current user's running timer, or null.
+// @Security ApiKeyAuth
+// @Router /v1/timer [get]
+func GetTimer(c *fiber.Ctx) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	e, err := repo.NewTimeEntryRepo(database.GetDB()).Running(userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"time_entry": e})
+}
+
+// StartTaskTimer starts the current user's timer on the task. A user runs
+// one timer at a time: while another one runs this answers 409 with it.
+// @Security ApiKeyAuth
+// @Router /v1/tasks/{id}/timer/start [post]
+func StartTaskTimer(c *fiber.Ctx) error {
+	taskID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	if _, err := repo.NewTaskRepo(database.GetDB()).Get(taskID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "task was not found"})
+	}
+
+	st := &model.StartTimer{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(st); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+		}
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(st); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+
+	timeEntryRepo := repo.NewTimeEntryRepo(database.GetDB())
+	e := &model.TimeEntry{ID: uuid.New(), TaskID: taskID, UserID: userID, StartedAt: time.Now().UTC(), Note: st.Note}
+	if err := timeEntryRepo.Start(e); err != nil {
+		if errors.Is(err, repo.ErrTimerRunning) {
+			running, _ := timeEntryRepo.Running(userID)
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": err.Error(), "time_entry": running})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbEntry, err := timeEntryRepo.Get(e.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbEntry.Version))
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"time_entry": dbEntry})
+}
+
+// StopTimer stops the current user's running timer.
+// @Security ApiKeyAuth
+// @Router /v1/timer/stop [post]
+func StopTimer(c *fiber.Ctx) error {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "can't extract user info from request"})
+	}
+
+	timeEntryRepo := repo.NewTimeEntryRepo(database.GetDB())
+	id, err := timeEntryRepo.Stop(userID, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "no timer is running"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbEntry, err := timeEntryRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbEntry.Version))
+	return c.JSON(fiber.Map{"time_entry": dbEntry})
+}
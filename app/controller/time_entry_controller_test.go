@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTimeEntriesCSV(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	end := start.Add(95 * time.Minute)
+	note := `call with "ACME", billed`
+	e := &model.TimeEntry{
+		ID: uuid.New(), ProjectID: uuid.New(), TaskID: uuid.New(), TaskTitle: "Kickoff", UserID: uuid.New(),
+		StartedAt: start, EndedAt: &end, Note: &note, DurationSeconds: 95 * 60,
+	}
+	running := &model.TimeEntry{ID: uuid.New(), StartedAt: end, Running: true, DurationSeconds: 30}
+
+	var b strings.Builder
+	require.NoError(t, writeTimeEntriesCSV(&b, []*model.TimeEntry{e, running}))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "id,project_id,task_id,task_title,user_id,started_at,ended_at,duration_minutes,note", lines[0])
+	assert.Equal(t, strings.Join([]string{
+		e.ID.String(), e.ProjectID.String(), e.TaskID.String(), "Kickoff", e.UserID.String(),
+		"2026-10-19T09:00:00Z", "2026-10-19T10:35:00Z", "95.00", `"call with ""ACME"", billed"`,
+	}, ","), lines[1])
+	assert.Contains(t, lines[2], ",2026-10-19T10:35:00Z,,0.50,")
+}
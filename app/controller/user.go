@@ -1,6 +1,10 @@
 package controller
 
 import (
+	"errors"
+	"strconv"
+
+	"github.com/byeblogs/go-boilerplate/app/dto"
 	"github.com/byeblogs/go-boilerplate/app/model"
 	repo "github.com/byeblogs/go-boilerplate/app/repository"
 	"github.com/byeblogs/go-boilerplate/pkg/validator"
@@ -9,11 +13,26 @@ import (
 	"github.com/google/uuid"
 )
 
-// GetUsers @Router /v1/users [get]
+// GetUsers lists users, newest first. Asked for text/csv or
+// application/x-ndjson (or ?format=csv|ndjson), it streams every user
+// instead of a page.
+// @Router /v1/users [get]
 func GetUsers(c *fiber.Ctx) error {
 	pageNo, pageSize := GetPagination(c)
+	format, ok := listFormat(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "format must be json, csv or ndjson"})
+	}
 
 	userRepo := repo.NewUserRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		userRepo = userRepo.WithDeleted()
+	}
+	if format != "json" {
+		return userExport.stream(c, format, func(fn func([]*model.User) error) error {
+			return userRepo.Each(exportBatchSize, fn)
+		})
+	}
 	users, err := userRepo.All(pageSize, uint(pageSize*(pageNo-1)))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "users were not found"})
@@ -23,10 +42,23 @@ func GetUsers(c *fiber.Ctx) error {
 		"page":      pageNo,
 		"page_size": pageSize,
 		"count":     len(users),
-		"users":     users,
+		"users":     dto.ToUsers(users),
 	})
 }
 
+// userExport writes what dto.User shows, never the password hash.
+var userExport = listExport[*model.User]{
+	name:    "users",
+	columns: []string{"id", "username", "email", "first_name", "last_name", "is_active", "is_admin", "created_at", "updated_at"},
+	row: func(u *model.User) []string {
+		return []string{
+			u.ID.String(), u.UserName, u.Email, u.FirstName, u.LastName, strconv.FormatBool(u.IsActive),
+			strconv.FormatBool(u.IsAdmin), csvTime(&u.CreatedAt), csvTime(&u.UpdatedAt),
+		}
+	},
+	item: func(u *model.User) interface{} { return dto.ToUser(u) },
+}
+
 // GetUser @Router /v1/users/{id} [get]
 func GetUser(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
@@ -35,38 +67,65 @@ func GetUser(c *fiber.Ctx) error {
 	}
 
 	userRepo := repo.NewUserRepo(database.GetDB())
+	if IncludeDeleted(c) {
+		userRepo = userRepo.WithDeleted()
+	}
 	u, err := userRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "user was not found"})
 	}
 
-	return c.JSON(fiber.Map{"user": u})
+	if IfNoneMatch(c, u.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.JSON(fiber.Map{"user": dto.ToUser(u)})
 }
 
 // CreateUser @Security ApiKeyAuth
 // @Router /v1/users [post]
 func CreateUser(c *fiber.Ctx) error {
-	u := &model.User{}
-	if err := c.BodyParser(u); err != nil {
+	cu := &model.CreateUser{}
+	if err := c.BodyParser(cu); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	u.ID = uuid.New()
-
 	validate := validator.NewValidator()
-	if err := validate.Struct(u); err != nil {
+	if err := validate.Struct(cu); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"msg":    "invalid input found",
 			"errors": validator.ValidatorErrors(err),
 		})
 	}
 
-	userRepo := repo.NewUserRepo(database.GetDB())
+	hash, err := GeneratePasswordHash([]byte(cu.Password))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	u := &model.User{
+		ID:           uuid.New(),
+		IsActive:     cu.IsActive,
+		IsAdmin:      cu.IsAdmin,
+		UserName:     cu.UserName,
+		Email:        cu.Email,
+		PasswordHash: hash,
+		FirstName:    cu.FirstName,
+		LastName:     cu.LastName,
+	}
+
+	userRepo := repo.NewUserRepo(AuditedDB(c))
 	if err := userRepo.Create(u); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"user": u})
+	dbUser, err := userRepo.Get(u.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbUser.Version))
+	return c.JSON(fiber.Map{"user": dto.ToUser(dbUser)})
 }
 
 // UpdateUser @Security ApiKeyAuth
@@ -77,17 +136,75 @@ func UpdateUser(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	userRepo := repo.NewUserRepo(database.GetDB())
-	_, err = userRepo.Get(id)
+	userRepo := repo.NewUserRepo(AuditedDB(c))
+	u, err := userRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "user was not found"})
 	}
 
-	u := &model.User{}
-	if err := c.BodyParser(u); err != nil {
+	version, ok := IfMatch(c, u.Version)
+	if !ok {
+		return PreconditionFailed(c, u.Version, fiber.Map{"user": dto.ToUser(u)})
+	}
+
+	uu := &model.UpdateUser{}
+	if err := c.BodyParser(uu); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	validate := validator.NewValidator()
+	if err := validate.Struct(uu); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		})
+	}
+	uu.Apply(u)
+	u.Version = version
+
+	if err := userRepo.Update(id, u); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := userRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"user": dto.ToUser(current)})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbUser, err := userRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbUser.Version))
+	return c.JSON(fiber.Map{"user": dto.ToUser(dbUser)})
+}
+
+// PatchUser @Security ApiKeyAuth
+// @Router /v1/users/{id} [patch]
+func PatchUser(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
+
+	userRepo := repo.NewUserRepo(AuditedDB(c))
+	current, err := userRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "user was not found"})
+	}
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"user": dto.ToUser(current)})
+	}
+
+	u := &model.User{}
+	if err := ApplyPatch(c, dto.ToUser(current), u); err != nil {
+		return PatchFailed(c, err)
+	}
 	u.ID = id
+	u.Version = version
 
 	validate := validator.NewValidator()
 	if err := validate.Struct(u); err != nil {
@@ -97,7 +214,12 @@ func UpdateUser(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := userRepo.Update(id, u); err != nil {
+	if err := userRepo.Patch(id, current, u); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := userRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"user": dto.ToUser(current)})
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
@@ -106,7 +228,8 @@ func UpdateUser(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"user": dbUser})
+	c.Set(fiber.HeaderETag, ETag(dbUser.Version))
+	return c.JSON(fiber.Map{"user": dto.ToUser(dbUser)})
 }
 
 // DeleteUser @Security ApiKeyAuth
@@ -117,15 +240,56 @@ func DeleteUser(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
 	}
 
-	userRepo := repo.NewUserRepo(database.GetDB())
-	_, err = userRepo.Get(id)
+	userRepo := repo.NewUserRepo(AuditedDB(c))
+	current, err := userRepo.Get(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "user was not found"})
 	}
 
-	if err := userRepo.Delete(id); err != nil {
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"user": dto.ToUser(current)})
+	}
+
+	if err := userRepo.Delete(id, version); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := userRepo.Get(id); err == nil {
+				return PreconditionFailed(c, current.Version, fiber.Map{"user": dto.ToUser(current)})
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{})
 }
+
+// RestoreUser restores a soft-deleted user.
+// @Security ApiKeyAuth
+// @Router /v1/users/{id}/restore [post]
+func RestoreUser(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	userRepo := repo.NewUserRepo(AuditedDB(c))
+	u, err := userRepo.WithDeleted().Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "user was not found"})
+	}
+	if u.DeletedAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"msg": "user is not deleted"})
+	}
+
+	if err := userRepo.Restore(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	u, err = userRepo.Get(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(u.Version))
+	return c.JSON(fiber.Map{"user": dto.ToUser(u)})
+}
@@ -0,0 +1,316 @@
+package controller
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetWebhooks lists the webhook subscriptions, oldest first.
+// @Security ApiKeyAuth
+// @Router /v1/webhooks [get]
+func GetWebhooks(c *fiber.Ctx) error {
+	pageNo, pageSize := GetPagination(c)
+
+	subs, err := repo.NewWebhookRepo(database.GetDB()).Subscriptions(pageSize, uint(pageSize*(pageNo-1)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	for _, s := range subs {
+		s.Secret = ""
+	}
+
+	return c.JSON(fiber.Map{
+		"page":      pageNo,
+		"page_size": pageSize,
+		"count":     len(subs),
+		"webhooks":  subs,
+	})
+}
+
+// CreateWebhook subscribes an endpoint to events: "events" lists event types
+// such as "task.status_changed", "book.*" for every event of a resource, or
+// "*" (the default). Without a "secret" one is generated. The secret signs
+// every request, and is only shown in this response.
+// @Security ApiKeyAuth
+// @Router /v1/webhooks [post]
+func CreateWebhook(c *fiber.Ctx) error {
+	s := &model.WebhookSubscription{Active: true, Events: model.EventPatterns{"*"}}
+	if err := c.BodyParser(s); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	s.ID = uuid.New()
+	s.CreatedByUserID = nil
+	if userID, ok := CurrentUserID(c); ok {
+		s.CreatedByUserID = &userID
+	}
+	if s.Secret == "" {
+		s.Secret = newWebhookSecret()
+	}
+
+	if err := validateWebhook(s); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+
+	webhookRepo := repo.NewWebhookRepo(database.GetDB())
+	if err := webhookRepo.CreateSubscription(s); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbSub, err := webhookRepo.GetSubscription(s.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderETag, ETag(dbSub.Version))
+	return c.JSON(fiber.Map{"webhook": dbSub})
+}
+
+// GetWebhook @Security ApiKeyAuth
+// @Router /v1/webhooks/{id} [get]
+func GetWebhook(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	s, err := repo.NewWebhookRepo(database.GetDB()).GetSubscription(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "webhook was not found"})
+	}
+	s.Secret = ""
+
+	c.Set(fiber.HeaderETag, ETag(s.Version))
+	return c.JSON(fiber.Map{"webhook": s})
+}
+
+// UpdateWebhook overwrites a subscription. The secret is kept unless a new
+// one is given; "active": false pauses deliveries, which are sent once the
+// subscription is active again.
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id} [put]
+func UpdateWebhook(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	webhookRepo := repo.NewWebhookRepo(database.GetDB())
+	current, err := webhookRepo.GetSubscription(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "webhook was not found"})
+	}
+	secret := current.Secret
+	current.Secret = ""
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"webhook": current})
+	}
+
+	s := &model.WebhookSubscription{Active: true, Events: model.EventPatterns{"*"}}
+	if err := c.BodyParser(s); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	s.ID = id
+	s.Version = version
+	s.CreatedByUserID = current.CreatedByUserID
+	if s.Secret == "" {
+		s.Secret = secret
+	}
+
+	if err := validateWebhook(s); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+
+	if err := webhookRepo.UpdateSubscription(s); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := webhookRepo.GetSubscription(id); err == nil {
+				current.Secret = ""
+				return PreconditionFailed(c, current.Version, fiber.Map{"webhook": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	dbSub, err := webhookRepo.GetSubscription(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	dbSub.Secret = ""
+
+	c.Set(fiber.HeaderETag, ETag(dbSub.Version))
+	return c.JSON(fiber.Map{"webhook": dbSub})
+}
+
+// DeleteWebhook removes a subscription along with its deliveries.
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id} [delete]
+func DeleteWebhook(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	webhookRepo := repo.NewWebhookRepo(database.GetDB())
+	current, err := webhookRepo.GetSubscription(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "webhook was not found"})
+	}
+	current.Secret = ""
+
+	version, ok := IfMatch(c, current.Version)
+	if !ok {
+		return PreconditionFailed(c, current.Version, fiber.Map{"webhook": current})
+	}
+
+	if err := webhookRepo.DeleteSubscription(id, version); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			if current, err := webhookRepo.GetSubscription(id); err == nil {
+				current.Secret = ""
+				return PreconditionFailed(c, current.Version, fiber.Map{"webhook": current})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{})
+}
+
+// GetWebhookDeliveries lists the deliveries of a subscription, newest first.
+// Filters: ?status=pending|delivered|dead and ?event_id=<id>.
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(c *fiber.Ctx) error {
+	pageNo, pageSize := GetPagination(c)
+
+	f, err := deliveryFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	webhookRepo := repo.NewWebhookRepo(database.GetDB())
+	if _, err := webhookRepo.GetSubscription(f.SubscriptionID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "webhook was not found"})
+	}
+
+	deliveries, err := webhookRepo.Deliveries(f, pageSize, uint(pageSize*(pageNo-1)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"page":       pageNo,
+		"page_size":  pageSize,
+		"count":      len(deliveries),
+		"deliveries": deliveries,
+	})
+}
+
+// ReplayWebhookDeliveries sends the deliveries of a subscription again, with
+// a fresh set of attempts: the dead ones by default, or those of ?status,
+// optionally only those created ?from on (RFC 3339 or YYYY-MM-DD) and of
+// one ?event_id.
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/{id}/replay [post]
+func ReplayWebhookDeliveries(c *fiber.Ctx) error {
+	f, err := deliveryFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	var from time.Time
+	if s := c.Query("from"); s != "" {
+		if from, err = parseReportTime(s); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid from: " + err.Error()})
+		}
+	}
+
+	webhookRepo := repo.NewWebhookRepo(database.GetDB())
+	if _, err := webhookRepo.GetSubscription(f.SubscriptionID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "webhook was not found"})
+	}
+
+	n, err := webhookRepo.ReplayAll(f, from)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"replayed": n})
+}
+
+// ReplayWebhookDelivery sends one delivery again, whatever became of it.
+// @Security ApiKeyAuth
+// @Router /v1/webhooks/deliveries/{id}/replay [post]
+func ReplayWebhookDelivery(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	webhookRepo := repo.NewWebhookRepo(database.GetDB())
+	if err := webhookRepo.Replay(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"msg": "delivery was not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+
+	d, err := webhookRepo.GetDelivery(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"delivery": d})
+}
+
+func deliveryFilter(c *fiber.Ctx) (repo.DeliveryFilter, error) {
+	f := repo.DeliveryFilter{Status: model.WebhookDeliveryStatus(c.Query("status"))}
+	var err error
+	if f.SubscriptionID, err = uuid.Parse(c.Params("id")); err != nil {
+		return f, errors.New("invalid id")
+	}
+	switch f.Status {
+	case "", model.DeliveryPending, model.DeliveryDelivered, model.DeliveryDead:
+	default:
+		return f, errors.New("status must be pending, delivered or dead")
+	}
+	if s := c.Query("event_id"); s != "" {
+		if f.EventID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return f, errors.New("invalid event_id")
+		}
+	}
+	return f, nil
+}
+
+// validateWebhook returns the response to an invalid subscription, or nil.
+func validateWebhook(s *model.WebhookSubscription) fiber.Map {
+	validate := validator.NewValidator()
+	if err := validate.Struct(s); err != nil {
+		return fiber.Map{
+			"msg":    "invalid input found",
+			"errors": validator.ValidatorErrors(err),
+		}
+	}
+	if err := s.Events.Validate(); err != nil {
+		return fiber.Map{
+			"msg":    "invalid input found",
+			"errors": map[string]string{"events": err.Error()},
+		}
+	}
+	return nil
+}
+
+// newWebhookSecret returns 32 random bytes, hex encoded.
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	workflowOnce sync.Once
+	workflow     *model.TaskWorkflow
+)
+
+// TaskWorkflow returns the task state machine configured by TASK_TRANSITIONS.
+// An invalid configuration is logged and replaced by the default workflow.
+func TaskWorkflow() *model.TaskWorkflow {
+	workflowOnce.Do(func() {
+		w, err := model.ParseTaskWorkflow(config.WorkflowCfg().TaskTransitions)
+		if err != nil {
+			logr.Errorf("invalid TASK_TRANSITIONS, using the default workflow: %v", err)
+			w = model.DefaultTaskWorkflow()
+		}
+		workflow = w
+	})
+	return workflow
+}
+
+// moveTask takes the lifecycle of t from the stored task and moves it to the
+// status t asks for, so clients can't skip the workflow or set the
+// timestamps themselves. Whether open blockers keep the task from being
+// done is checked by the write, see repo.BlockedError.
+func moveTask(current, t *model.Task) error {
+	next := t.Status
+	t.Status, t.StartedAt, t.CompletedAt = current.Status, current.StartedAt, current.CompletedAt
+	return TaskWorkflow().Transition(t, next, time.Now().UTC())
+}
+
+// TransitionFailed answers a status change the workflow refused with 409
+// and the statuses the task (or book) may move to instead, or the blockers
+// that keep it from being done; an unknown status with 400. Anything else
+// is a server error.
+func TransitionFailed(c *fiber.Ctx, err error) error {
+	var te *model.TransitionError
+	if errors.As(err, &te) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"msg":     err.Error(),
+			"allowed": te.Allowed,
+		})
+	}
+	var bte *model.BookTransitionError
+	if errors.As(err, &bte) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"msg":     err.Error(),
+			"allowed": bte.Allowed,
+		})
+	}
+	var be *repo.BlockedError
+	if errors.As(err, &be) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"msg":      err.Error(),
+			"blockers": be.Blockers,
+		})
+	}
+	if errors.Is(err, model.ErrUnknownTaskStatus) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"msg": err.Error()})
+}
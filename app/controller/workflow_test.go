@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitionFailed(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{&model.TransitionError{From: model.TaskDone, To: model.TaskTodo}, 409},
+		{&model.BookTransitionError{From: model.BookWithdrawn, To: model.BookActive}, 409},
+		{fmt.Errorf("move: %w", &repo.BlockedError{}), 409},
+		{fmt.Errorf("%w %q", model.ErrUnknownTaskStatus, "later"), 400},
+		{errors.New("connection reset by peer"), 500},
+	}
+
+	for _, test := range tests {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return TransitionFailed(c, test.err) })
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil), -1)
+		require.NoError(t, err)
+		assert.Equalf(t, test.code, resp.StatusCode, "%v", test.err)
+	}
+}
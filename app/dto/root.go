@@ -16,6 +16,8 @@ type User struct {
 
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Version   int64      `json:"version"`
 	IsActive  bool       `json:"is_active"`
 	IsAdmin   bool       `json:"is_admin"`
 	UserName  string     `json:"username"`
@@ -29,6 +31,8 @@ func ToUser(u *model.User) *User {
 		ID:        u.ID,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: &u.UpdatedAt,
+		DeletedAt: u.DeletedAt,
+		Version:   u.Version,
 		IsActive:  u.IsActive,
 		IsAdmin:   u.IsAdmin,
 		UserName:  u.UserName,
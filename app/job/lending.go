@@ -0,0 +1,23 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/worker"
+)
+
+// ExpireHolds ends reservations whose hold on a copy ran out, so the copy
+// goes to the next user on the waitlist.
+func ExpireHolds(hold, interval time.Duration) worker.Job {
+	return worker.Job{
+		Name:     "expire-holds",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := repo.NewLendingRepo(database.GetDB(), hold).ExpireHolds(time.Now().UTC())
+			return err
+		},
+	}
+}
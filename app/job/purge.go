@@ -0,0 +1,63 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/logger"
+	"github.com/byeblogs/go-boilerplate/platform/storage"
+	"github.com/byeblogs/go-boilerplate/platform/worker"
+)
+
+// PurgeDeleted permanently removes rows that were soft-deleted longer than retention ago.
+func PurgeDeleted(retention, interval time.Duration) worker.Job {
+	return worker.Job{
+		Name:     "purge-deleted",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			db := database.GetDB()
+			before := time.Now().UTC().Add(-retention)
+
+			// children first, so the counts reflect what each purge removed itself
+			purgers := []struct {
+				table string
+				purge func(time.Time) (int64, error)
+			}{
+				{"comments", repo.NewCommentRepo(db).Purge},
+				{"attachments", repo.NewAttachmentRepo(db).Purge},
+				{"tasks", repo.NewTaskRepo(db).Purge},
+				{"projects", repo.NewProjectRepo(db).Purge},
+				{"book", repo.NewBookRepo(db).Purge},
+				{"users", repo.NewUserRepo(db).Purge},
+			}
+
+			for _, p := range purgers {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				n, err := p.purge(before)
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					logger.GetLogger().Infof("purged %d soft-deleted rows from %s", n, p.table)
+				}
+			}
+
+			// content no attachment refers to any more, once it is as old as the retention
+			store := storage.GetStore()
+			n, err := repo.NewAttachmentRepo(db).PurgeBlobs(before, func(sum string) error {
+				return store.Delete(ctx, storage.ContentKey(sum))
+			})
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				logger.GetLogger().Infof("purged %d unreferenced blobs", n)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/logger"
+	"github.com/byeblogs/go-boilerplate/platform/worker"
+)
+
+// MaterializeRecurrences creates the occurrences of recurring tasks that
+// start within window from now.
+func MaterializeRecurrences(window, interval time.Duration) worker.Job {
+	return worker.Job{
+		Name:     "materialize-recurrences",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			recurrenceRepo := repo.NewRecurrenceRepo(database.GetDB())
+			ids, err := recurrenceRepo.IDs()
+			if err != nil {
+				return err
+			}
+
+			upTo := time.Now().UTC().Add(window)
+			for _, id := range ids {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// one broken series must not hold up the others
+				created, err := recurrenceRepo.Advance(id, upTo, false)
+				if err != nil {
+					logger.GetLogger().Errorf("recurrence %s: %v", id, err)
+					continue
+				}
+				if len(created) > 0 {
+					logger.GetLogger().Infof("recurrence %s: created %d occurrences", id, len(created))
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/logger"
+	"github.com/byeblogs/go-boilerplate/platform/notify"
+	"github.com/byeblogs/go-boilerplate/platform/worker"
+)
+
+// reminderBatch is how many reminders one run sends at most; the rest wait
+// for the next tick.
+const reminderBatch = 500
+
+// SendReminders notifies the assignees of open tasks that are due within
+// lead, or overdue, over the channel each of them prefers. Every reminder is
+// claimed before it is sent, so it goes out once even with several servers;
+// a failed delivery is released and retried on the next run. Shutting down
+// stops the run between two deliveries.
+func SendReminders(notifiers notify.Router, lead time.Duration, defaultChannel string, interval time.Duration) worker.Job {
+	return worker.Job{
+		Name:     "send-reminders",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			reminderRepo := repo.NewReminderRepo(database.GetDB())
+			now := time.Now().UTC()
+			reminders, err := reminderRepo.Due(now, lead, defaultChannel, reminderBatch)
+			if err != nil {
+				return err
+			}
+
+			sent := 0
+			for _, r := range reminders {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				claimed, err := reminderRepo.Claim(r)
+				if err != nil {
+					return err
+				}
+				if !claimed {
+					continue
+				}
+
+				if err := notifiers.Notify(ctx, r.Channel, reminderMessage(r, now)); err != nil {
+					logger.GetLogger().Errorf("reminder for task %s to %s over %s: %v", r.TaskID, r.Email, r.Channel, err)
+					if err := reminderRepo.Release(r); err != nil {
+						return err
+					}
+					continue
+				}
+				sent++
+			}
+			if sent > 0 {
+				logger.GetLogger().Infof("sent %d task reminders", sent)
+			}
+			return nil
+		},
+	}
+}
+
+func reminderMessage(r *model.Reminder, now time.Time) notify.Message {
+	due := r.DueAt.UTC()
+	m := notify.Message{
+		Kind: string(r.Kind),
+		To: notify.Recipient{
+			UserID: r.UserID,
+			Name:   (&model.User{FirstName: r.FirstName, LastName: r.LastName}).FullName(),
+			Email:  r.Email,
+		},
+		TaskID: r.TaskID,
+		DueAt:  &due,
+		SentAt: now,
+	}
+	if r.WebhookURL != nil {
+		m.To.WebhookURL = *r.WebhookURL
+	}
+
+	if r.Kind == model.ReminderOverdue {
+		m.Subject = fmt.Sprintf("%q is overdue", r.Title)
+		m.Text = fmt.Sprintf("The task %q was due %s ago, at %s.", r.Title, roughly(now.Sub(due)), due.Format(time.RFC1123))
+	} else {
+		m.Subject = fmt.Sprintf("%q is due in %s", r.Title, roughly(due.Sub(now)))
+		m.Text = fmt.Sprintf("The task %q is due at %s.", r.Title, due.Format(time.RFC1123))
+	}
+	m.Text += fmt.Sprintf("\n\n/api/v1/tasks/%s", r.TaskID)
+	return m
+}
+
+// roughly renders d in its largest whole unit, e.g. "3 hours".
+func roughly(d time.Duration) string {
+	unit := func(n int, name string) string {
+		if n == 1 {
+			return "1 " + name
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
+	switch {
+	case d >= 48*time.Hour:
+		return unit(int(d/(24*time.Hour)), "day")
+	case d >= 2*time.Hour:
+		return unit(int(d/time.Hour), "hour")
+	default:
+		return unit(int(d/time.Minute), "minute")
+	}
+}
@@ -0,0 +1,37 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderMessage(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	hook := "https://hooks.example.com/me"
+	r := &model.Reminder{
+		TaskID: uuid.New(), Title: "Water plants", DueAt: now.Add(3*time.Hour + 20*time.Minute),
+		Kind: model.ReminderDueSoon, UserID: uuid.New(), Email: "ann@example.com",
+		FirstName: "Ann", LastName: "Lee", WebhookURL: &hook,
+	}
+
+	m := reminderMessage(r, now)
+	assert.Equal(t, `"Water plants" is due in 3 hours`, m.Subject)
+	assert.Equal(t, "Ann Lee", m.To.Name)
+	assert.Equal(t, hook, m.To.WebhookURL)
+	assert.Contains(t, m.Text, "/api/v1/tasks/"+r.TaskID.String())
+
+	r.Kind, r.DueAt = model.ReminderOverdue, now.Add(-50*time.Hour)
+	assert.Equal(t, `"Water plants" is overdue`, reminderMessage(r, now).Subject)
+	assert.Contains(t, reminderMessage(r, now).Text, "was due 2 days ago")
+}
+
+func TestRoughly(t *testing.T) {
+	assert.Equal(t, "1 minute", roughly(time.Minute+10*time.Second))
+	assert.Equal(t, "90 minutes", roughly(90*time.Minute))
+	assert.Equal(t, "47 hours", roughly(47*time.Hour+59*time.Minute))
+	assert.Equal(t, "3 days", roughly(80*time.Hour))
+}
@@ -0,0 +1,21 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/worker"
+)
+
+// RefreshReports recomputes the materialized views behind the reports.
+func RefreshReports(interval time.Duration) worker.Job {
+	return worker.Job{
+		Name:     "refresh-reports",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			return repo.NewReportRepo(database.GetDB()).RefreshThroughput()
+		},
+	}
+}
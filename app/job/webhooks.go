@@ -0,0 +1,139 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/logger"
+	"github.com/byeblogs/go-boilerplate/platform/webhook"
+	"github.com/byeblogs/go-boilerplate/platform/worker"
+)
+
+// webhookBatch is how many events one run fans out, and how many deliveries
+// it sends, at most; the rest wait for the next tick. Due deliveries are
+// claimed webhookClaim at a time.
+const (
+	webhookBatch = 500
+	webhookClaim = 50
+)
+
+// DispatchWebhooks turns the events of the outbox into deliveries to the
+// subscriptions that want them, and sends the deliveries that are due.
+// Failed deliveries are retried with exponential backoff until they are
+// dead. Deliveries are claimed before they are sent, so each attempt is
+// made once even with several servers. Shutting down stops the run between
+// two deliveries.
+func DispatchWebhooks(client *webhook.Client, retry model.WebhookRetry, timeout, interval time.Duration) worker.Job {
+	return worker.Job{
+		Name:     "dispatch-webhooks",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			webhookRepo := repo.NewWebhookRepo(database.GetDB())
+			if _, err := webhookRepo.Fanout(webhookBatch); err != nil {
+				return err
+			}
+
+			d := &dispatcher{repo: webhookRepo, client: client, retry: retry,
+				subs: map[string]*model.WebhookSubscription{}, events: map[int64][]byte{}}
+			delivered, failed := 0, 0
+			for claimed := 0; claimed < webhookBatch; claimed += webhookClaim {
+				// a claim outlives every attempt of the batch, should this server die
+				deliveries, err := webhookRepo.Due(time.Now().UTC(), webhookClaim*timeout, webhookClaim)
+				if err != nil {
+					return err
+				}
+				for _, delivery := range deliveries {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					ok, err := d.deliver(ctx, delivery)
+					if err != nil {
+						return err
+					}
+					if ok {
+						delivered++
+					} else {
+						failed++
+					}
+				}
+				if len(deliveries) < webhookClaim {
+					break
+				}
+			}
+			if delivered+failed > 0 {
+				logger.GetLogger().Infof("webhooks: %d delivered, %d failed", delivered, failed)
+			}
+			return nil
+		},
+	}
+}
+
+// dispatcher sends the deliveries of one run, reading each subscription
+// and event once.
+type dispatcher struct {
+	repo   repo.WebhookRepository
+	client *webhook.Client
+	retry  model.WebhookRetry
+	subs   map[string]*model.WebhookSubscription
+	events map[int64][]byte
+}
+
+// deliver makes one attempt and records its outcome. It reports whether
+// the endpoint accepted the delivery; the error is for failures to record
+// it.
+func (d *dispatcher) deliver(ctx context.Context, delivery *model.WebhookDelivery) (bool, error) {
+	sub, body, err := d.load(delivery)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the subscription was deleted since, and its deliveries with it
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	status, err := d.client.Post(ctx, webhook.Request{
+		URL:        sub.URL,
+		Secret:     sub.Secret,
+		Event:      delivery.EventType,
+		DeliveryID: delivery.ID.String(),
+		Body:       body,
+	})
+	now := time.Now().UTC()
+	if err != nil {
+		logger.GetLogger().Errorf("webhook %s to %s: %v", delivery.EventType, sub.URL, err)
+		delivery.Failed(d.retry, now, status, err)
+	} else {
+		delivery.Delivered(now, status)
+	}
+	return err == nil, d.repo.RecordAttempt(delivery)
+}
+
+func (d *dispatcher) load(delivery *model.WebhookDelivery) (*model.WebhookSubscription, []byte, error) {
+	sub, ok := d.subs[delivery.SubscriptionID.String()]
+	if !ok {
+		var err error
+		if sub, err = d.repo.GetSubscription(delivery.SubscriptionID); err != nil {
+			return nil, nil, err
+		}
+		d.subs[delivery.SubscriptionID.String()] = sub
+	}
+
+	body, ok := d.events[delivery.EventID]
+	if !ok {
+		e, err := d.repo.GetEvent(delivery.EventID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if body, err = json.Marshal(e); err != nil {
+			return nil, nil, err
+		}
+		d.events[delivery.EventID] = body
+	}
+	return sub, body, nil
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AttachmentKind tells what an attachment is used for.
+type AttachmentKind string
+
+const (
+	// AttachmentFile is a file attached to a task.
+	AttachmentFile AttachmentKind = "file"
+	// AttachmentCover is the cover picture of a book.
+	AttachmentCover AttachmentKind = "cover"
+)
+
+// Attachment is an uploaded file owned by a task or a book. Its content is a
+// blob addressed by SHA-256, shared by every attachment with the same bytes.
+type Attachment struct {
+	ID          uuid.UUID      `db:"id" json:"id"`
+	TaskID      *uuid.UUID     `db:"task_id" json:"task_id,omitempty"`
+	BookID      *uuid.UUID     `db:"book_id" json:"book_id,omitempty"`
+	Kind        AttachmentKind `db:"kind" json:"kind"`
+	Filename    string         `db:"filename" json:"filename"`
+	ContentType string         `db:"content_type" json:"content_type"`
+	Size        int64          `db:"size" json:"size"`
+	SHA256      string         `db:"sha256" json:"sha256"`
+	UploadedBy  *uuid.UUID     `db:"uploaded_by_user_id" json:"uploaded_by"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+	DeletedAt   *time.Time     `db:"deleted_at" json:"deleted_at,omitempty"`
+	// URL is a signed, time-limited download link.
+	URL string `db:"-" json:"url,omitempty"`
+}
+
+// Inline reports whether browsers may display the attachment in place.
+// Anything else is served as a download.
+func (a *Attachment) Inline() bool {
+	switch a.ContentType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf":
+		return true
+	}
+	return strings.HasPrefix(a.ContentType, "text/plain")
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// AuditActions are the kinds of change the audit log records. Soft deletes
+// are "delete", hard deletes "purge".
+var AuditActions = []string{"create", "update", "delete", "restore", "purge"}
+
+// AuditResourceTypes are the kinds of rows whose changes are audited.
+var AuditResourceTypes = []string{"book", "user", "project", "task"}
+
+// AuditEvent records one change of an audited row. Before and After hold
+// the columns that changed, with their old and new values; secrets such as
+// password hashes show as "[redacted]". ActorUserID is nil for changes made
+// by the system.
+type AuditEvent struct {
+	ID           int64           `db:"id" json:"id"`
+	OccurredAt   time.Time       `db:"occurred_at" json:"occurred_at"`
+	ActorUserID  *uuid.UUID      `db:"actor_user_id" json:"actor_user_id"`
+	Action       string          `db:"action" json:"action"`
+	ResourceType string          `db:"resource_type" json:"resource_type"`
+	ResourceID   uuid.UUID       `db:"resource_id" json:"resource_id"`
+	Before       *types.JSONText `db:"before" json:"before"`
+	After        *types.JSONText `db:"after" json:"after"`
+	RequestID    *string         `db:"request_id" json:"request_id"`
+	IP           *string         `db:"ip" json:"ip"`
+}
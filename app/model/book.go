@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,12 +15,61 @@ type Book struct {
 	ID        uuid.UUID  `db:"id" json:"id" validate:"uuid"`
 	CreatedAt time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt *time.Time `db:"updated_at" json:"updated_at"`
-	IsDeleted bool       `db:"is_deleted" json:"is_deleted"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Version   int64      `db:"version" json:"version"`
 	UserID    uuid.UUID  `db:"user_id" json:"user_id" validate:"required"`
 	Title     string     `db:"title" json:"title" validate:"required,lte=255"`
-	Author    string     `db:"author" json:"author" validate:"required,lte=255"`
-	Status    int        `db:"status" json:"status" validate:"required,len=1"`
-	Meta      Meta       `db:"meta" json:"meta" validate:"required,dive"`
+	// Author is the first of Authors, kept for clients that know one only.
+	Author  string   `db:"author" json:"author" validate:"required_without=Authors,lte=255"`
+	Authors []string `db:"-" json:"authors" validate:"dive,required,lte=255"`
+	// ISBN is stored as the 13 digits of the ISBN-13, see validator.NormalizeISBN.
+	ISBN          *string    `db:"isbn" json:"isbn" validate:"omitempty,isbn"`
+	Edition       *string    `db:"edition" json:"edition" validate:"omitempty,lte=100"`
+	Publisher     *string    `db:"publisher" json:"publisher" validate:"omitempty,lte=255"`
+	PublishedYear *int       `db:"published_year" json:"published_year" validate:"omitempty,min=1,max=9999"`
+	Language      *string    `db:"language" json:"language" validate:"omitempty,alpha,min=2,max=3"` // ISO 639 code
+	PageCount     *int       `db:"page_count" json:"page_count" validate:"omitempty,min=1,max=100000"`
+	Genres        []string   `db:"-" json:"genres" validate:"dive,required,lte=50"`
+	Tags          []string   `db:"-" json:"tags" validate:"dive,required,lte=50"`
+	Status        BookStatus `db:"status" json:"status" validate:"required,oneof=draft active archived withdrawn"`
+	Meta          Meta       `db:"meta" json:"meta" validate:"required,dive"`
+	// Review aggregates, kept up to date by review writes; read-only here.
+	RatingCount   int      `db:"rating_count" json:"rating_count"`
+	RatingSum     int64    `db:"rating_sum" json:"-"`
+	RatingAverage *float64 `db:"rating_avg" json:"rating_average"`
+}
+
+// NormalizeCatalog tidies the catalog fields of b before it is stored:
+// Author and Authors agree, genres and tags are normalized like labels and
+// the language code is lower case. The ISBN is normalized by the caller.
+func (b *Book) NormalizeCatalog() {
+	authors := make([]string, 0, len(b.Authors)+1)
+	seen := map[string]bool{}
+	for _, name := range b.Authors {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		authors = append(authors, name)
+	}
+	if author := strings.TrimSpace(b.Author); len(authors) == 0 && author != "" {
+		authors = append(authors, author)
+	}
+	b.Authors = authors
+	if len(authors) > 0 {
+		b.Author = authors[0]
+	} else {
+		// leave Authors unset so that validation asks for an author
+		b.Authors = nil
+	}
+
+	b.Genres = NormalizeLabels(b.Genres)
+	b.Tags = NormalizeLabels(b.Tags)
+	if b.Language != nil {
+		lang := strings.ToLower(strings.TrimSpace(*b.Language))
+		b.Language = &lang
+	}
 }
 
 // Meta struct to describe book attributes.
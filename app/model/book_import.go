@@ -0,0 +1,199 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BookImportFields are the fields a book import reads, and the columns of a
+// book export. Lists (authors, genres, tags) are arrays in JSON Lines and
+// separated by ";" in CSV.
+var BookImportFields = []string{
+	"title", "author", "authors", "isbn", "edition", "publisher", "published_year",
+	"language", "page_count", "genres", "tags", "status", "description", "picture", "rating",
+}
+
+// MaxBookImportRows bounds the rows of one import.
+const MaxBookImportRows = 50000
+
+// ParseBookImportMapping reads a column mapping written as comma-separated
+// field:column pairs, e.g. "title:Book Title,isbn:ISBN 13". Fields without a
+// pair are read from the column of the same name.
+func ParseBookImportMapping(spec string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, ":")
+		field, column = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("mapping %q: want field:column", pair)
+		}
+		if !isBookImportField(field) {
+			return nil, fmt.Errorf("mapping %q: unknown field %q", pair, field)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+func isBookImportField(name string) bool {
+	for _, f := range BookImportFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// BookRecord is one row of an import, keyed by field. Values are strings
+// from CSV, or any JSON value from JSON Lines.
+type BookRecord map[string]interface{}
+
+// NewBookRecord picks the import fields out of a raw row using the mapping.
+// The nested meta object of exported JSON Lines is read as well.
+func NewBookRecord(raw map[string]interface{}, mapping map[string]string) BookRecord {
+	if meta, ok := raw["meta"].(map[string]interface{}); ok {
+		for _, key := range []string{"description", "picture", "rating"} {
+			if _, set := raw[key]; !set {
+				raw[key] = meta[key]
+			}
+		}
+	}
+
+	r := BookRecord{}
+	for _, field := range BookImportFields {
+		column := field
+		if c, ok := mapping[field]; ok {
+			column = c
+		}
+		if v, ok := raw[column]; ok && v != nil && v != "" {
+			r[field] = v
+		}
+	}
+	return r
+}
+
+// Apply writes the fields of the record into b, leaving the others as they
+// are. It returns the fields that don't hold a value of the right type.
+func (r BookRecord) Apply(b *Book) map[string]string {
+	errs := map[string]string{}
+	str := func(field string) (string, bool) {
+		switch v := r[field].(type) {
+		case nil:
+			return "", false
+		case string:
+			return strings.TrimSpace(v), true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		default:
+			errs[field] = "want a string"
+			return "", false
+		}
+	}
+	num := func(field string) (int, bool) {
+		switch v := r[field].(type) {
+		case nil:
+			return 0, false
+		case float64:
+			if v != float64(int(v)) {
+				errs[field] = "want a whole number"
+				return 0, false
+			}
+			return int(v), true
+		case string:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				errs[field] = "want a whole number"
+				return 0, false
+			}
+			return n, true
+		default:
+			errs[field] = "want a whole number"
+			return 0, false
+		}
+	}
+	list := func(field string) ([]string, bool) {
+		switch v := r[field].(type) {
+		case nil:
+			return nil, false
+		case string:
+			return strings.Split(v, ";"), true
+		case []interface{}:
+			out := make([]string, 0, len(v))
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					errs[field] = "want a list of strings"
+					return nil, false
+				}
+				out = append(out, s)
+			}
+			return out, true
+		default:
+			errs[field] = "want a list of strings"
+			return nil, false
+		}
+	}
+	optional := func(field string, dst **string) {
+		if s, ok := str(field); ok {
+			*dst = &s
+		}
+	}
+
+	if s, ok := str("title"); ok {
+		b.Title = s
+	}
+	if authors, ok := list("authors"); ok {
+		b.Authors = authors
+		b.Author = ""
+	} else if s, ok := str("author"); ok {
+		b.Author, b.Authors = s, nil
+	}
+	optional("isbn", &b.ISBN)
+	optional("edition", &b.Edition)
+	optional("publisher", &b.Publisher)
+	optional("language", &b.Language)
+	if n, ok := num("published_year"); ok {
+		b.PublishedYear = &n
+	}
+	if n, ok := num("page_count"); ok {
+		b.PageCount = &n
+	}
+	if genres, ok := list("genres"); ok {
+		b.Genres = genres
+	}
+	if tags, ok := list("tags"); ok {
+		b.Tags = tags
+	}
+	switch v := r["status"].(type) {
+	case nil:
+	case float64:
+		if code := int(v); float64(code) == v && code >= 0 && code < len(BookStatuses) {
+			b.Status = BookStatuses[code]
+		} else {
+			errs["status"] = "unknown status code"
+		}
+	default:
+		if s, ok := str("status"); ok {
+			statuses, err := ParseBookStatuses(s)
+			if err != nil || len(statuses) != 1 {
+				errs["status"] = "want one of draft, active, archived, withdrawn"
+			} else {
+				b.Status = statuses[0]
+			}
+		}
+	}
+	if s, ok := str("description"); ok {
+		b.Meta.Description = s
+	}
+	if s, ok := str("picture"); ok {
+		b.Meta.Picture = s
+	}
+	if n, ok := num("rating"); ok {
+		b.Meta.Rating = n
+	}
+	return errs
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBookImportMapping(t *testing.T) {
+	mapping, err := ParseBookImportMapping("Title:Book Title, isbn:ISBN 13,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"title": "Book Title", "isbn": "ISBN 13"}, mapping)
+
+	_, err = ParseBookImportMapping("title")
+	assert.Error(t, err, "no column")
+	_, err = ParseBookImportMapping("shelf:Shelf")
+	assert.Error(t, err, "unknown field")
+}
+
+func TestBookRecordApply(t *testing.T) {
+	raw := map[string]interface{}{
+		"Name":           "Dune",
+		"authors":        "Frank Herbert; Brian Herbert",
+		"published_year": "1965",
+		"genres":         []interface{}{"sci-fi"},
+		"status":         float64(2),
+		"isbn":           "",
+		"meta":           map[string]interface{}{"description": "Spice", "rating": float64(9)},
+	}
+	rec := NewBookRecord(raw, map[string]string{"title": "Name"})
+	assert.NotContains(t, rec, "isbn", "empty values are skipped")
+
+	b := Book{Author: "someone", Tags: []string{"kept"}}
+	require.Empty(t, rec.Apply(&b))
+	assert.Equal(t, "Dune", b.Title)
+	assert.Equal(t, []string{"Frank Herbert", " Brian Herbert"}, b.Authors)
+	assert.Empty(t, b.Author)
+	assert.Equal(t, 1965, *b.PublishedYear)
+	assert.Equal(t, []string{"sci-fi"}, b.Genres)
+	assert.Equal(t, []string{"kept"}, b.Tags)
+	assert.Equal(t, BookArchived, b.Status)
+	assert.Equal(t, "Spice", b.Meta.Description)
+	assert.Equal(t, 9, b.Meta.Rating)
+	assert.Nil(t, b.ISBN)
+
+	errs := BookRecord{"page_count": "many", "status": "lost", "tags": float64(1)}.Apply(&b)
+	assert.Len(t, errs, 3)
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BookStatus is the lifecycle state of a book:
+//
+//   - draft: being catalogued, not lendable yet
+//   - active: in the catalogue and lendable
+//   - archived: kept for the record, not lendable
+//   - withdrawn: taken out of the library for good
+//
+// Statuses are strings in JSON. Clients from before the enum may still send
+// the numeric codes 0 (draft), 1 (active), 2 (archived) and 3 (withdrawn).
+type BookStatus string
+
+const (
+	BookDraft     BookStatus = "draft"
+	BookActive    BookStatus = "active"
+	BookArchived  BookStatus = "archived"
+	BookWithdrawn BookStatus = "withdrawn"
+)
+
+// BookStatuses lists every status, in lifecycle order; the index of a status
+// is its numeric code. It must match the CHECK constraint on book.status.
+var BookStatuses = []BookStatus{BookDraft, BookActive, BookArchived, BookWithdrawn}
+
+// bookTransitions is the lifecycle of books: drafts are published, active
+// books archived and back, and anything but a withdrawn book can be
+// withdrawn. A withdrawn book can only be brought back into the archive.
+var bookTransitions = map[BookStatus][]BookStatus{
+	BookDraft:     {BookActive, BookWithdrawn},
+	BookActive:    {BookArchived, BookWithdrawn},
+	BookArchived:  {BookActive, BookWithdrawn},
+	BookWithdrawn: {BookArchived},
+}
+
+// Valid reports whether s is a known status.
+func (s BookStatus) Valid() bool {
+	for _, known := range BookStatuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Lendable reports whether copies of a book in status s may be checked out
+// or reserved.
+func (s BookStatus) Lendable() bool {
+	return s == BookActive
+}
+
+// Next returns the statuses a book in status s may move to.
+func (s BookStatus) Next() []BookStatus {
+	return append([]BookStatus(nil), bookTransitions[s]...)
+}
+
+// CanTransition reports whether a book may move from s to status to.
+// Staying in the same status is always allowed.
+func (s BookStatus) CanTransition(to BookStatus) bool {
+	if s == to {
+		return true
+	}
+	for _, next := range bookTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition checks that a book may move from s to status to.
+func (s BookStatus) Transition(to BookStatus) error {
+	if !s.CanTransition(to) {
+		return &BookTransitionError{From: s, To: to, Allowed: s.Next()}
+	}
+	return nil
+}
+
+// UnmarshalJSON reads a status name, or one of the legacy numeric codes.
+func (s *BookStatus) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' && !bytes.Equal(data, []byte("null")) {
+		var code int
+		if err := json.Unmarshal(data, &code); err != nil {
+			return fmt.Errorf("book status must be a name or a numeric code: %w", err)
+		}
+		if code < 0 || code >= len(BookStatuses) {
+			return fmt.Errorf("unknown book status code %d", code)
+		}
+		*s = BookStatuses[code]
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	*s = BookStatus(strings.ToLower(strings.TrimSpace(name)))
+	return nil
+}
+
+// ParseBookStatuses reads a comma-separated list of statuses, names or
+// numeric codes, as used by query filters.
+func ParseBookStatuses(list string) ([]BookStatus, error) {
+	var out []BookStatus
+	for _, name := range strings.Split(list, ",") {
+		s := BookStatus(strings.ToLower(strings.TrimSpace(name)))
+		if s == "" {
+			continue
+		}
+		if code, err := strconv.Atoi(string(s)); err == nil && code >= 0 && code < len(BookStatuses) {
+			s = BookStatuses[code]
+		}
+		if !s.Valid() {
+			return nil, fmt.Errorf("unknown book status %q", s)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// BookTransitionError is returned for a status change the lifecycle does
+// not allow.
+type BookTransitionError struct {
+	From, To BookStatus
+	Allowed  []BookStatus
+}
+
+func (e *BookTransitionError) Error() string {
+	return fmt.Sprintf("book can't move from %s to %s", e.From, e.To)
+}
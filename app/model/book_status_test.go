@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookStatusJSON(t *testing.T) {
+	var b Book
+	require.NoError(t, json.Unmarshal([]byte(`{"status": 1}`), &b))
+	assert.Equal(t, BookActive, b.Status, "legacy numeric code")
+	require.NoError(t, json.Unmarshal([]byte(`{"status": "Archived"}`), &b))
+	assert.Equal(t, BookArchived, b.Status)
+	require.NoError(t, json.Unmarshal([]byte(`{"status": 0}`), &b))
+	assert.Equal(t, BookDraft, b.Status)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"status": 7}`), &b), "unknown code")
+	assert.Error(t, json.Unmarshal([]byte(`{"status": 1.5}`), &b), "not a code")
+
+	out, err := json.Marshal(Book{Status: BookWithdrawn})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"status":"withdrawn"`)
+}
+
+func TestBookStatusTransitions(t *testing.T) {
+	assert.True(t, BookDraft.CanTransition(BookActive))
+	assert.True(t, BookActive.CanTransition(BookArchived))
+	assert.True(t, BookArchived.CanTransition(BookActive))
+	assert.True(t, BookWithdrawn.CanTransition(BookWithdrawn), "staying put")
+	assert.False(t, BookDraft.CanTransition(BookArchived))
+	assert.False(t, BookWithdrawn.CanTransition(BookActive))
+
+	var te *BookTransitionError
+	require.ErrorAs(t, BookWithdrawn.Transition(BookActive), &te)
+	assert.Equal(t, []BookStatus{BookArchived}, te.Allowed)
+	assert.NoError(t, BookActive.Transition(BookWithdrawn))
+
+	assert.True(t, BookActive.Lendable())
+	assert.False(t, BookArchived.Lendable())
+}
+
+func TestParseBookStatuses(t *testing.T) {
+	statuses, err := ParseBookStatuses("active, 0,")
+	require.NoError(t, err)
+	assert.Equal(t, []BookStatus{BookActive, BookDraft}, statuses)
+
+	statuses, err = ParseBookStatuses("")
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+
+	_, err = ParseBookStatuses("lost")
+	assert.Error(t, err)
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookNormalizeCatalog(t *testing.T) {
+	lang := " EN "
+	b := &Book{
+		Author:   "ignored",
+		Authors:  []string{" Terry Pratchett ", "", "Neil Gaiman", "terry pratchett"},
+		Genres:   []string{"Fantasy", " fantasy", "Humor"},
+		Tags:     nil,
+		Language: &lang,
+	}
+	b.NormalizeCatalog()
+	assert.Equal(t, []string{"Terry Pratchett", "Neil Gaiman"}, b.Authors)
+	assert.Equal(t, "Terry Pratchett", b.Author, "author follows the byline")
+	assert.Equal(t, []string{"fantasy", "humor"}, b.Genres)
+	assert.Equal(t, []string{}, b.Tags)
+	assert.Equal(t, "en", *b.Language)
+
+	b = &Book{Author: " Ursula K. Le Guin "}
+	b.NormalizeCatalog()
+	assert.Equal(t, []string{"Ursula K. Le Guin"}, b.Authors, "a single author becomes the byline")
+	assert.Equal(t, "Ursula K. Le Guin", b.Author)
+
+	b = &Book{Authors: []string{" "}}
+	b.NormalizeCatalog()
+	assert.Nil(t, b.Authors, "no author left to validate")
+}
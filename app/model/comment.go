@@ -0,0 +1,66 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Comment is a Markdown message on a task or a project.
+type Comment struct {
+	ID        uuid.UUID   `db:"id" json:"id"`
+	TaskID    *uuid.UUID  `db:"task_id" json:"task_id,omitempty"`
+	ProjectID *uuid.UUID  `db:"project_id" json:"project_id,omitempty"`
+	AuthorID  *uuid.UUID  `db:"author_user_id" json:"author_id"` // nil once the author is purged
+	Body      string      `db:"body" json:"body" validate:"required,lte=20000"`
+	Mentions  []uuid.UUID `db:"-" json:"mentions"`
+	CreatedAt time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time   `db:"updated_at" json:"updated_at"`
+	EditedAt  *time.Time  `db:"edited_at" json:"edited_at"`
+	DeletedAt *time.Time  `db:"deleted_at" json:"deleted_at,omitempty"`
+	Version   int64       `db:"version" json:"version"`
+}
+
+// CommentRevision is a previous body of an edited comment.
+type CommentRevision struct {
+	ID        int64      `db:"id" json:"id"`
+	CommentID uuid.UUID  `db:"comment_id" json:"comment_id"`
+	Body      string     `db:"body" json:"body"`
+	EditedBy  *uuid.UUID `db:"edited_by_user_id" json:"edited_by"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// WriteComment is the payload to post or edit a comment.
+type WriteComment struct {
+	Body string `json:"body" validate:"required,lte=20000"`
+}
+
+// mention matches @username outside of words and e-mail addresses.
+var mention = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9_][A-Za-z0-9_.-]*[A-Za-z0-9_]|[A-Za-z0-9_])`)
+
+// Mentions returns the distinct usernames @mentioned in a Markdown body,
+// in order of appearance. Code spans and blocks are skipped.
+func Mentions(body string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, m := range mention.FindAllStringSubmatch(stripCode(body), -1) {
+		name := m[1]
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+var (
+	codeBlock = regexp.MustCompile("(?s)```.*?```")
+	codeSpan  = regexp.MustCompile("`[^`\n]*`")
+)
+
+func stripCode(body string) string {
+	body = codeBlock.ReplaceAllString(body, " ")
+	return codeSpan.ReplaceAllString(strings.TrimSpace(body), " ")
+}
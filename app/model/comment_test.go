@@ -0,0 +1,25 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"@nami can you review?", []string{"nami"}},
+		{"thanks @zoro, @luna and @zoro again.", []string{"zoro", "luna"}},
+		{"mail me at sanji@example.com", []string{}},
+		{"run `@nami` or\n```\n@luna\n```\nthen ping @yaahtze", []string{"yaahtze"}},
+		{"(@first.last)", []string{"first.last"}},
+		{"no mentions here", []string{}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, Mentions(test.body), test.body)
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Label is a tag defined for the tasks of one project.
+type Label struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	ProjectID uuid.UUID `db:"project_id" json:"project_id"`
+	Name      string    `db:"name" json:"name" validate:"required,lte=50"`
+	Color     *string   `db:"color" json:"color" validate:"omitempty,hexcolor"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookCopy is one physical copy of a book that can be lent out.
+type BookCopy struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	BookID      uuid.UUID  `db:"book_id" json:"book_id"`
+	Barcode     *string    `db:"barcode" json:"barcode"`
+	Note        *string    `db:"note" json:"note"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	WithdrawnAt *time.Time `db:"withdrawn_at" json:"withdrawn_at,omitempty"`
+	// LoanID is the open loan of the copy, computed when it is read.
+	LoanID *uuid.UUID `db:"loan_id" json:"loan_id"`
+}
+
+// Available reports whether the copy is on the shelf.
+func (c *BookCopy) Available() bool {
+	return c.WithdrawnAt == nil && c.LoanID == nil
+}
+
+// NewBookCopy adds a copy to a book.
+type NewBookCopy struct {
+	Barcode *string `json:"barcode" validate:"omitempty,lte=64"`
+	Note    *string `json:"note" validate:"omitempty,lte=2000"`
+}
+
+// Loan is a copy lent to a user. It is open until ReturnedAt is set.
+type Loan struct {
+	ID           uuid.UUID  `db:"id" json:"id"`
+	CopyID       uuid.UUID  `db:"copy_id" json:"copy_id"`
+	BookID       uuid.UUID  `db:"book_id" json:"book_id"`
+	BookTitle    string     `db:"book_title" json:"book_title"`
+	UserID       uuid.UUID  `db:"user_id" json:"user_id"`
+	CheckedOutAt time.Time  `db:"checked_out_at" json:"checked_out_at"`
+	DueAt        time.Time  `db:"due_at" json:"due_at"`
+	ReturnedAt   *time.Time `db:"returned_at" json:"returned_at"`
+	// Overdue is computed when the loan is read: open and past DueAt.
+	Overdue   bool      `db:"overdue" json:"overdue"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Checkout asks for a copy of a book, a specific one or any available, for
+// a number of days.
+type Checkout struct {
+	CopyID *uuid.UUID `json:"copy_id"`
+	Days   *int       `json:"days" validate:"omitempty,min=1"`
+}
+
+// DueAt returns when a loan starting at now is due: after Days, or after
+// defaultDays when unset. Loans can't run longer than maxDays.
+func (ch *Checkout) DueAt(now time.Time, defaultDays, maxDays int) (time.Time, error) {
+	days := defaultDays
+	if ch.Days != nil {
+		days = *ch.Days
+	}
+	if days < 1 {
+		return now, errors.New("days must be at least 1")
+	}
+	if maxDays > 0 && days > maxDays {
+		return now, errors.New("loans can't run longer than the lending period")
+	}
+	return now.AddDate(0, 0, days), nil
+}
+
+// ReservationStatus is where a reservation is in the waitlist of its book.
+type ReservationStatus string
+
+const (
+	// ReservationWaiting is in the queue for the next copy.
+	ReservationWaiting ReservationStatus = "waiting"
+	// ReservationReady holds a copy for its user until it expires.
+	ReservationReady     ReservationStatus = "ready"
+	ReservationFulfilled ReservationStatus = "fulfilled"
+	ReservationCancelled ReservationStatus = "cancelled"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Open reports whether the reservation is still in the queue.
+func (s ReservationStatus) Open() bool {
+	return s == ReservationWaiting || s == ReservationReady
+}
+
+// Reservation is a user's place in the waitlist of a book.
+type Reservation struct {
+	ID        uuid.UUID         `db:"id" json:"id"`
+	BookID    uuid.UUID         `db:"book_id" json:"book_id"`
+	UserID    uuid.UUID         `db:"user_id" json:"user_id"`
+	Status    ReservationStatus `db:"status" json:"status"`
+	ReadyAt   *time.Time        `db:"ready_at" json:"ready_at"`
+	ExpiresAt *time.Time        `db:"expires_at" json:"expires_at"`
+	ClosedAt  *time.Time        `db:"closed_at" json:"closed_at"`
+	// Position is the 1-based place in the queue of an open reservation,
+	// computed when it is read.
+	Position  *int      `db:"position" json:"position"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
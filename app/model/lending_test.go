@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckoutDueAt(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	due, err := (&Checkout{}).DueAt(now, 14, 60)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 14), due, "default period")
+
+	seven := 7
+	due, err = (&Checkout{Days: &seven}).DueAt(now, 14, 60)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 7), due)
+
+	long, zero := 61, 0
+	_, err = (&Checkout{Days: &long}).DueAt(now, 14, 60)
+	assert.Error(t, err, "longer than the lending period")
+	_, err = (&Checkout{Days: &zero}).DueAt(now, 14, 60)
+	assert.Error(t, err, "empty loan")
+}
+
+func TestBookCopyAvailable(t *testing.T) {
+	now, loan := time.Now(), uuid.New()
+	assert.True(t, (&BookCopy{}).Available())
+	assert.False(t, (&BookCopy{LoanID: &loan}).Available(), "on loan")
+	assert.False(t, (&BookCopy{WithdrawnAt: &now}).Available(), "withdrawn")
+}
+
+func TestReservationStatusOpen(t *testing.T) {
+	assert.True(t, ReservationWaiting.Open())
+	assert.True(t, ReservationReady.Open())
+	for _, s := range []ReservationStatus{ReservationFulfilled, ReservationCancelled, ReservationExpired} {
+		assert.False(t, s.Open(), s)
+	}
+}
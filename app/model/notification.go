@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationPreferences are how a user wants to hear about their tasks.
+// Unset fields fall back to the server defaults.
+type NotificationPreferences struct {
+	UserID  uuid.UUID `db:"user_id" json:"user_id"`
+	Channel *string   `db:"channel" json:"channel" validate:"omitempty,oneof=log webhook email none"`
+	// WebhookURL receives the user's notifications on the webhook channel.
+	WebhookURL          *string   `db:"webhook_url" json:"webhook_url" validate:"omitempty,url,lte=2048"`
+	RemindBeforeMinutes *int      `db:"remind_before_minutes" json:"remind_before_minutes" validate:"omitempty,min=0,max=43200"`
+	DueSoon             bool      `db:"due_soon" json:"due_soon"`
+	Overdue             bool      `db:"overdue" json:"overdue"`
+	UpdatedAt           time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// DefaultNotificationPreferences are the preferences of a user who never set any.
+func DefaultNotificationPreferences(userID uuid.UUID) *NotificationPreferences {
+	return &NotificationPreferences{UserID: userID, DueSoon: true, Overdue: true}
+}
+
+// ReminderKind tells why a reminder is sent.
+type ReminderKind string
+
+const (
+	ReminderDueSoon ReminderKind = "due_soon"
+	ReminderOverdue ReminderKind = "overdue"
+)
+
+// Reminder is a notification owed to an assignee of an open task with a
+// due date. Each kind is sent once per user and due date.
+type Reminder struct {
+	TaskID     uuid.UUID    `db:"task_id"`
+	ProjectID  uuid.UUID    `db:"project_id"`
+	Title      string       `db:"title"`
+	DueAt      time.Time    `db:"due_at"`
+	Kind       ReminderKind `db:"kind"`
+	UserID     uuid.UUID    `db:"user_id"`
+	Email      string       `db:"email"`
+	FirstName  string       `db:"first_name"`
+	LastName   string       `db:"last_name"`
+	Channel    string       `db:"channel"`
+	WebhookURL *string      `db:"webhook_url"`
+}
@@ -7,10 +7,37 @@ import (
 )
 
 type Project struct {
-	ID          uuid.UUID `db:"id" json:"id"`
-	OwnerUserID uuid.UUID `db:"owner_user_id" json:"owner_user_id" validate:"required"`
-	Name        string    `db:"name" json:"name" validate:"required"`
-	Description *string   `db:"description" json:"description"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID          uuid.UUID  `db:"id" json:"id"`
+	OwnerUserID uuid.UUID  `db:"owner_user_id" json:"owner_user_id" validate:"required"`
+	Name        string     `db:"name" json:"name" validate:"required"`
+	Description *string    `db:"description" json:"description"`
+	IsTemplate  bool       `db:"is_template" json:"is_template"`
+	ArchivedAt  *time.Time `db:"archived_at" json:"archived_at"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Version     int64      `db:"version" json:"version"`
+}
+
+// CloneProject asks for a deep copy of a project with its tasks, subtasks,
+// dependencies and labels.
+type CloneProject struct {
+	Name        string  `json:"name" validate:"required"`
+	Description *string `json:"description"`
+	IsTemplate  bool    `json:"is_template"`
+	// StartAt moves every due date by the same amount, so that the earliest
+	// one falls on it. Without it due dates are copied as they are.
+	StartAt *time.Time `json:"start_at"`
+	// Copies start as todo and unassigned unless asked otherwise.
+	KeepStatus    bool `json:"keep_status"`
+	KeepAssignees bool `json:"keep_assignees"`
+}
+
+// Shift is how far due dates move, given the earliest due date of the
+// source project.
+func (cp *CloneProject) Shift(earliest *time.Time) time.Duration {
+	if cp.StartAt == nil || earliest == nil {
+		return 0
+	}
+	return cp.StartAt.Sub(*earliest)
 }
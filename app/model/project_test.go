@@ -0,0 +1,17 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneProjectShift(t *testing.T) {
+	earliest := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 56*24*time.Hour, (&CloneProject{StartAt: &start}).Shift(&earliest))
+	assert.Zero(t, (&CloneProject{}).Shift(&earliest))
+	assert.Zero(t, (&CloneProject{StartAt: &start}).Shift(nil))
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// Board positions are lexorank-style keys: strings over 0-9a-z read as base
+// 36 fractions, so "i" sits halfway down a column and a key can always be
+// found between two others by growing it a digit. Keys never end in "0",
+// which keeps byte order and numeric order the same.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// MaxRankLength is how long a key may grow before its column is spread out
+// again with RankSpread.
+const MaxRankLength = 32
+
+// ErrNoRank is returned when no key fits between two positions, which
+// happens when they are equal or out of order.
+var ErrNoRank = errors.New("no position between the given keys")
+
+// RankBetween returns a key that sorts after lower and before upper. An
+// empty lower means the top of the column, an empty upper its bottom.
+func RankBetween(lower, upper string) (string, error) {
+	if !validRank(lower) || !validRank(upper) || (upper != "" && lower >= upper) {
+		return "", ErrNoRank
+	}
+
+	var out []byte
+	open := upper == ""
+	for i := 0; ; i++ {
+		lo := 0
+		if i < len(lower) {
+			lo = strings.IndexByte(rankDigits, lower[i])
+		}
+		hi := len(rankDigits)
+		if !open {
+			hi = 0
+			if i < len(upper) {
+				hi = strings.IndexByte(rankDigits, upper[i])
+			}
+		}
+
+		switch {
+		case lo == hi:
+			out = append(out, rankDigits[lo])
+		case hi-lo > 1:
+			return string(append(out, rankDigits[(lo+hi)/2])), nil
+		default:
+			// Adjacent digits: keep lo and look for room below the next
+			// digit of lower, where nothing above bounds us any more.
+			out = append(out, rankDigits[lo])
+			open = true
+		}
+	}
+}
+
+// RankSpread returns n keys in increasing order, evenly spaced and as short
+// as n allows.
+func RankSpread(n int) []string {
+	width, space := 1, len(rankDigits)
+	for space <= n {
+		width++
+		space *= len(rankDigits)
+	}
+
+	out := make([]string, n)
+	step := space / (n + 1)
+	for i := range out {
+		v := (i + 1) * step
+		key := make([]byte, width)
+		for j := width - 1; j >= 0; j-- {
+			key[j] = rankDigits[v%len(rankDigits)]
+			v /= len(rankDigits)
+		}
+		out[i] = strings.TrimRight(string(key), "0")
+	}
+	return out
+}
+
+func validRank(key string) bool {
+	if strings.HasSuffix(key, "0") {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(rankDigits, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
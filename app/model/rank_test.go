@@ -0,0 +1,59 @@
+package model
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankBetween(t *testing.T) {
+	cases := []struct{ lower, upper, want string }{
+		{"", "", "i"},
+		{"i", "", "r"},
+		{"", "i", "9"},
+		{"a", "c", "b"},
+		{"a", "b", "ai"},
+		{"az", "b", "azi"},
+		{"a", "a5", "a2"},
+		{"", "1", "0i"},
+	}
+	for _, tc := range cases {
+		got, err := RankBetween(tc.lower, tc.upper)
+		require.NoError(t, err, "%q..%q", tc.lower, tc.upper)
+		assert.Equal(t, tc.want, got, "%q..%q", tc.lower, tc.upper)
+	}
+
+	for _, bad := range [][2]string{{"b", "a"}, {"a", "a"}, {"a0", ""}, {"A", ""}, {"", "0"}} {
+		_, err := RankBetween(bad[0], bad[1])
+		assert.ErrorIs(t, err, ErrNoRank, "%q..%q", bad[0], bad[1])
+	}
+}
+
+func TestRankBetweenKeepsOrder(t *testing.T) {
+	// keep inserting right below the first key, the worst case for growth
+	keys := []string{"i"}
+	for i := 0; i < 200; i++ {
+		k, err := RankBetween("", keys[0])
+		require.NoError(t, err)
+		keys = append([]string{k}, keys...)
+	}
+	assert.True(t, sort.StringsAreSorted(keys))
+}
+
+func TestRankSpread(t *testing.T) {
+	assert.Empty(t, RankSpread(0))
+	assert.Equal(t, []string{"i"}, RankSpread(1))
+
+	keys := RankSpread(1000)
+	require.Len(t, keys, 1000)
+	assert.True(t, sort.StringsAreSorted(keys))
+	for i, k := range keys {
+		assert.LessOrEqual(t, len(k), 2)
+		if i > 0 {
+			_, err := RankBetween(keys[i-1], k)
+			assert.NoError(t, err)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskRecurrence repeats a task on a schedule. Each occurrence is a task of
+// its own; the series remembers the last one it created.
+type TaskRecurrence struct {
+	ID       uuid.UUID `db:"id" json:"id"`
+	TaskID   uuid.UUID `db:"task_id" json:"task_id"` // first occurrence
+	Rule     string    `db:"rule" json:"rule"`
+	Timezone string    `db:"timezone" json:"timezone"`
+	StartsAt time.Time `db:"starts_at" json:"starts_at"`
+	// DueOffset is the distance from an occurrence to its due_at, if any.
+	DueOffset        *int64    `db:"due_offset_seconds" json:"due_offset_seconds"`
+	LastOccurrenceAt time.Time `db:"last_occurrence_at" json:"last_occurrence_at"`
+	CreatedAt        time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// WriteTaskRecurrence is the payload to make a task recurring. StartsAt
+// defaults to the task's due_at, or its creation time when it has none.
+type WriteTaskRecurrence struct {
+	Rule     string     `json:"rule" validate:"required,lte=255"`
+	Timezone string     `json:"timezone" validate:"lte=64"`
+	StartsAt *time.Time `json:"starts_at"`
+}
+
+// Schedule parses the rule and time zone of the series.
+func (r *TaskRecurrence) Schedule() (*RRule, *time.Location, error) {
+	rule, err := ParseRRule(r.Rule)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown time zone %q", r.Timezone)
+	}
+	return rule, loc, nil
+}
+
+// Frequency is the FREQ of a recurrence rule.
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY entry: a weekday, optionally the Nth (or, when
+// negative, Nth last) of the month, e.g. 2TU or -1FR.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// RRule is the subset of RFC 5545 recurrence rules tasks support: FREQ
+// (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY and BYMONTH. Weeks start on Monday.
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRRule reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH". A
+// leading "RRULE:" is accepted.
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := &RRule{Interval: 1}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(name)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("rrule: %q is not NAME=VALUE", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("rrule: %s given twice", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+			switch r.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = fmt.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "UNTIL":
+			var until time.Time
+			until, err = parseUntil(value)
+			r.Until = &until
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				var wd WeekdayNum
+				if wd, err = parseWeekdayNum(v); err != nil {
+					break
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, perr := strconv.Atoi(v)
+				if perr != nil || n == 0 || n < -31 || n > 31 {
+					err = fmt.Errorf("invalid month day %q", v)
+					break
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				n, perr := strconv.Atoi(v)
+				if perr != nil || n < 1 || n > 12 {
+					err = fmt.Errorf("invalid month %q", v)
+					break
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported part %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rrule: %s: %w", name, err)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("rrule: FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, errors.New("rrule: COUNT and UNTIL can't be combined")
+	}
+	for _, wd := range r.ByDay {
+		switch {
+		case wd.N == 0:
+		case r.Freq == Monthly || (r.Freq == Yearly && len(r.ByMonth) > 0):
+			if wd.N < -5 || wd.N > 5 {
+				return nil, fmt.Errorf("rrule: BYDAY: no %d%s in a month", wd.N, dayCode(wd.Day))
+			}
+		default:
+			return nil, errors.New("rrule: BYDAY: numbered weekdays need FREQ=MONTHLY, or YEARLY with BYMONTH")
+		}
+	}
+	return r, nil
+}
+
+// String formats the rule the way ParseRRule reads it.
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = dayCode(wd.Day)
+			if wd.N != 0 {
+				days[i] = strconv.Itoa(wd.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]int, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = int(m)
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+	return strings.Join(parts, ";")
+}
+
+// maxPeriods bounds the search for rules that never match, such as
+// FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30.
+const maxPeriods = 50000
+
+// Next returns the first occurrence after the given time of the series that
+// starts at dtstart. dtstart is always the first occurrence, and every
+// occurrence keeps its wall clock time in dtstart's location, across
+// daylight saving changes. ok is false once the series has ended.
+func (r *RRule) Next(dtstart, after time.Time) (next time.Time, ok bool) {
+	occurrences := r.Occurrences(dtstart, after, 1)
+	if len(occurrences) == 0 {
+		return time.Time{}, false
+	}
+	return occurrences[0], true
+}
+
+// Occurrences returns up to n occurrences after the given time.
+func (r *RRule) Occurrences(dtstart, after time.Time, n int) []time.Time {
+	var out []time.Time
+	if n <= 0 {
+		return out
+	}
+
+	count := 0
+	// emit reports whether to go on
+	emit := func(t time.Time) bool {
+		if r.Until != nil && t.After(*r.Until) {
+			return false
+		}
+		count++
+		if t.After(after) {
+			out = append(out, t)
+		}
+		return len(out) < n && (r.Count == 0 || count < r.Count)
+	}
+
+	if !emit(dtstart) {
+		return out
+	}
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.expand(dtstart, period) {
+			if !t.After(dtstart) {
+				continue
+			}
+			if !emit(t) {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// expand returns the candidate occurrences of the given period after
+// dtstart's, in order.
+func (r *RRule) expand(dtstart time.Time, period int) []time.Time {
+	y, m, d := dtstart.Date()
+	step := period * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := dateOf(dtstart, y, m, d+step)
+		if r.matches(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		// Monday of dtstart's week, then `step` weeks on
+		monday := d - (int(dtstart.Weekday())+6)%7 + 7*step
+		byDay := r.ByDay
+		if len(byDay) == 0 {
+			byDay = []WeekdayNum{{Day: dtstart.Weekday()}}
+		}
+		for _, wd := range byDay {
+			day := dateOf(dtstart, y, m, monday+(int(wd.Day)+6)%7)
+			if r.inMonths(day.Month()) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		first := dateOf(dtstart, y, m+time.Month(step), 1)
+		if r.inMonths(first.Month()) {
+			days = r.monthDays(dtstart, first)
+		}
+	case Yearly:
+		// without BYMONTH, day filters pick days of every month
+		months := r.ByMonth
+		switch {
+		case len(months) > 0:
+		case len(r.ByDay) > 0 || len(r.ByMonthDay) > 0:
+			for month := time.January; month <= time.December; month++ {
+				months = append(months, month)
+			}
+		default:
+			months = []time.Month{m}
+		}
+		for _, month := range months {
+			days = append(days, r.monthDays(dtstart, dateOf(dtstart, y+step, month, 1))...)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return dedupTimes(days)
+}
+
+// monthDays returns the days of the month starting at first that the rule
+// picks, defaulting to dtstart's day of the month.
+func (r *RRule) monthDays(dtstart, first time.Time) []time.Time {
+	y, m, _ := first.Date()
+	last := daysIn(y, m)
+
+	var days []time.Time
+	add := func(d int) {
+		if d >= 1 && d <= last {
+			days = append(days, dateOf(dtstart, y, m, d))
+		}
+	}
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = last + md + 1
+			}
+			if md >= 1 && md <= last && r.dayMatches(dateOf(dtstart, y, m, md), first) {
+				add(md)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for d := 1; d <= last; d++ {
+			if r.dayMatches(dateOf(dtstart, y, m, d), first) {
+				add(d)
+			}
+		}
+	default:
+		add(dtstart.Day())
+	}
+	return days
+}
+
+// dayMatches reports whether day passes BYDAY within its month.
+func (r *RRule) dayMatches(day, first time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	last := daysIn(first.Year(), first.Month())
+	for _, wd := range r.ByDay {
+		if day.Weekday() != wd.Day {
+			continue
+		}
+		nth := (day.Day()-1)/7 + 1
+		nthLast := -((last-day.Day())/7 + 1)
+		if wd.N == 0 || wd.N == nth || wd.N == nthLast {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies the BY* filters to a daily candidate.
+func (r *RRule) matches(day time.Time) bool {
+	if !r.inMonths(day.Month()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		last := daysIn(day.Year(), day.Month())
+		ok := false
+		for _, md := range r.ByMonthDay {
+			if md == day.Day() || last+md+1 == day.Day() {
+				ok = true
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == day.Weekday() {
+			return true
+		}
+	}
+	return len(r.ByDay) == 0
+}
+
+func (r *RRule) inMonths(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
+
+// dateOf is the given day at dtstart's wall clock time and location.
+func dateOf(dtstart time.Time, y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+}
+
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func dedupTimes(ts []time.Time) []time.Time {
+	out := ts[:0]
+	for i, t := range ts {
+		if i == 0 || !t.Equal(ts[i-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func positive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a positive number", s)
+	}
+	return n, nil
+}
+
+func parseUntil(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			if layout == "20060102" {
+				// a date includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a UTC date-time such as 20261231T235959Z", s)
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+	}
+	day, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+	}
+	wd := WeekdayNum{Day: day}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 {
+			return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+func dayCode(d time.Weekday) string {
+	for code, wd := range weekdays {
+		if wd == d {
+			return code
+		}
+	}
+	return ""
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
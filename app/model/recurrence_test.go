@@ -0,0 +1,109 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRRule(t *testing.T) {
+	r, err := ParseRRule("RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR,2TU;COUNT=4")
+	require.NoError(t, err)
+	assert.Equal(t, Monthly, r.Freq)
+	assert.Equal(t, 2, r.Interval)
+	assert.Equal(t, []WeekdayNum{{-1, time.Friday}, {2, time.Tuesday}}, r.ByDay)
+	assert.Equal(t, "FREQ=MONTHLY;INTERVAL=2;COUNT=4;BYDAY=-1FR,2TU", r.String())
+
+	for _, bad := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261231T000000Z",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;FREQ=WEEKLY",
+	} {
+		_, err := ParseRRule(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestRRuleOccurrences(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	at := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, berlin) }
+
+	tests := []struct {
+		rule    string
+		dtstart time.Time
+		want    []time.Time
+	}{
+		{
+			"FREQ=DAILY;INTERVAL=2;COUNT=3", at(2026, 10, 19, 9),
+			[]time.Time{at(2026, 10, 19, 9), at(2026, 10, 21, 9), at(2026, 10, 23, 9)},
+		},
+		{
+			// keeps 09:00 local across the end of summer time on Oct 25
+			"FREQ=WEEKLY;BYDAY=MO,TH", at(2026, 10, 19, 9),
+			[]time.Time{at(2026, 10, 19, 9), at(2026, 10, 22, 9), at(2026, 10, 26, 9), at(2026, 10, 29, 9)},
+		},
+		{
+			"FREQ=WEEKLY;INTERVAL=2;UNTIL=20261110T000000Z", at(2026, 10, 19, 9),
+			[]time.Time{at(2026, 10, 19, 9), at(2026, 11, 2, 9)},
+		},
+		{
+			// months without a 31st are skipped
+			"FREQ=MONTHLY", at(2026, 10, 31, 9),
+			[]time.Time{at(2026, 10, 31, 9), at(2026, 12, 31, 9), at(2027, 1, 31, 9), at(2027, 3, 31, 9)},
+		},
+		{
+			"FREQ=MONTHLY;BYMONTHDAY=-1", at(2027, 1, 31, 18),
+			[]time.Time{at(2027, 1, 31, 18), at(2027, 2, 28, 18), at(2027, 3, 31, 18), at(2027, 4, 30, 18)},
+		},
+		{
+			"FREQ=MONTHLY;BYDAY=-1FR", at(2026, 10, 30, 16),
+			[]time.Time{at(2026, 10, 30, 16), at(2026, 11, 27, 16), at(2026, 12, 25, 16), at(2027, 1, 29, 16)},
+		},
+		{
+			"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", at(2028, 2, 29, 9),
+			[]time.Time{at(2028, 2, 29, 9), at(2032, 2, 29, 9), at(2036, 2, 29, 9), at(2040, 2, 29, 9)},
+		},
+		{
+			"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=3", at(2026, 10, 23, 9),
+			[]time.Time{at(2026, 10, 23, 9), at(2026, 10, 26, 9), at(2026, 10, 27, 9)},
+		},
+		{
+			"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", at(2026, 1, 1, 9),
+			[]time.Time{at(2026, 1, 1, 9)},
+		},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRRule(test.rule)
+		require.NoError(t, err, test.rule)
+		got := r.Occurrences(test.dtstart, test.dtstart.Add(-time.Second), 4)
+		assert.Equal(t, test.want, got, test.rule)
+	}
+}
+
+func TestRRuleNext(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	r, err := ParseRRule("FREQ=WEEKLY;COUNT=3")
+	require.NoError(t, err)
+
+	next, ok := r.Next(start, start)
+	require.True(t, ok)
+	assert.Equal(t, start.AddDate(0, 0, 7), next)
+
+	next, ok = r.Next(start, start.AddDate(0, 0, 10))
+	require.True(t, ok)
+	assert.Equal(t, start.AddDate(0, 0, 14), next)
+
+	_, ok = r.Next(start, start.AddDate(0, 0, 14))
+	assert.False(t, ok, "COUNT=3 ends the series")
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatusCount is one row of the per-status aggregate behind ProjectStats.
+type StatusCount struct {
+	Status  TaskStatus `db:"status"`
+	Tasks   int        `db:"tasks"`
+	Overdue int        `db:"overdue"`
+	// CycleHours is the average time from start (or creation) to completion
+	// of the done tasks in this row.
+	CycleHours *float64 `db:"cycle_hours"`
+}
+
+// ProjectStats summarizes the live tasks of a project.
+type ProjectStats struct {
+	ProjectID uuid.UUID          `json:"project_id"`
+	Total     int                `json:"total"`
+	ByStatus  map[TaskStatus]int `json:"by_status"`
+	Open      int                `json:"open"`
+	Overdue   int                `json:"overdue"` // open and past due_at
+	// CompletionRate is done / (total - cancelled), 0 without such tasks.
+	CompletionRate    float64   `json:"completion_rate"`
+	AvgCycleTimeHours *float64  `json:"avg_cycle_time_hours"`
+	AsOf              time.Time `json:"as_of"`
+}
+
+// NewProjectStats folds per-status counts into the project's stats. Every
+// status is listed, with 0 when it has no tasks.
+func NewProjectStats(projectID uuid.UUID, counts []*StatusCount, asOf time.Time) *ProjectStats {
+	s := &ProjectStats{ProjectID: projectID, ByStatus: make(map[TaskStatus]int, len(TaskStatuses)), AsOf: asOf}
+	for _, status := range TaskStatuses {
+		s.ByStatus[status] = 0
+	}
+	for _, c := range counts {
+		s.ByStatus[c.Status] += c.Tasks
+		s.Total += c.Tasks
+		s.Overdue += c.Overdue
+		if !c.Status.Closed() {
+			s.Open += c.Tasks
+		}
+		if c.Status == TaskDone && c.CycleHours != nil {
+			s.AvgCycleTimeHours = c.CycleHours
+		}
+	}
+	if n := s.Total - s.ByStatus[TaskCancelled]; n > 0 {
+		s.CompletionRate = float64(s.ByStatus[TaskDone]) / float64(n)
+	}
+	return s
+}
+
+// ReportPeriod is how throughput is bucketed.
+type ReportPeriod string
+
+const (
+	PeriodDay   ReportPeriod = "day"
+	PeriodWeek  ReportPeriod = "week"
+	PeriodMonth ReportPeriod = "month"
+)
+
+// Valid reports whether p is a known period.
+func (p ReportPeriod) Valid() bool {
+	return p == PeriodDay || p == PeriodWeek || p == PeriodMonth
+}
+
+// Start returns the UTC start of the period holding t. Weeks start on
+// Monday, as with Postgres' date_trunc.
+func (p ReportPeriod) Start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PeriodWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case PeriodMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+// Next returns the start of the period after the one starting at start.
+func (p ReportPeriod) Next(start time.Time) time.Time {
+	switch p {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Throughput is how many tasks were done in one period and how long they
+// took on average.
+type Throughput struct {
+	PeriodStart       time.Time `db:"period" json:"period_start"`
+	Completed         int       `db:"completed" json:"completed"`
+	AvgCycleTimeHours *float64  `db:"cycle_hours" json:"avg_cycle_time_hours"`
+}
+
+// MaxReportPeriods bounds the number of periods one report may span.
+const MaxReportPeriods = 1000
+
+// FillThroughput returns one entry per period from the one holding from up
+// to to (exclusive), taking the counts from rows and zero elsewhere.
+func FillThroughput(rows []*Throughput, from, to time.Time, p ReportPeriod) ([]*Throughput, error) {
+	byStart := make(map[time.Time]*Throughput, len(rows))
+	for _, r := range rows {
+		byStart[r.PeriodStart.UTC()] = r
+	}
+
+	out := []*Throughput{}
+	for start := p.Start(from); start.Before(to); start = p.Next(start) {
+		if len(out) == MaxReportPeriods {
+			return nil, fmt.Errorf("report spans more than %d periods", MaxReportPeriods)
+		}
+		if r, ok := byStart[start]; ok {
+			r.PeriodStart = start
+			out = append(out, r)
+		} else {
+			out = append(out, &Throughput{PeriodStart: start})
+		}
+	}
+	return out, nil
+}
+
+// ThroughputTotals returns the tasks done over all periods and their average
+// cycle time, weighted by the tasks of each period.
+func ThroughputTotals(periods []*Throughput) (int, *float64) {
+	completed, weighted := 0, 0.0
+	for _, p := range periods {
+		if p.AvgCycleTimeHours == nil {
+			continue
+		}
+		completed += p.Completed
+		weighted += *p.AvgCycleTimeHours * float64(p.Completed)
+	}
+	if completed == 0 {
+		return 0, nil
+	}
+	avg := weighted / float64(completed)
+	return completed, &avg
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProjectStats(t *testing.T) {
+	cycle := 36.0
+	s := NewProjectStats(uuid.New(), []*StatusCount{
+		{Status: TaskTodo, Tasks: 3, Overdue: 1},
+		{Status: TaskDoing, Tasks: 2, Overdue: 1},
+		{Status: TaskDone, Tasks: 4, CycleHours: &cycle},
+		{Status: TaskCancelled, Tasks: 1},
+	}, time.Now())
+
+	assert.Equal(t, 10, s.Total)
+	assert.Equal(t, 5, s.Open)
+	assert.Equal(t, 2, s.Overdue)
+	assert.InDelta(t, 4.0/9, s.CompletionRate, 1e-9) // cancelled tasks don't count
+	assert.Equal(t, &cycle, s.AvgCycleTimeHours)
+
+	empty := NewProjectStats(uuid.New(), nil, time.Now())
+	assert.Equal(t, map[TaskStatus]int{TaskTodo: 0, TaskDoing: 0, TaskDone: 0, TaskCancelled: 0}, empty.ByStatus)
+	assert.Zero(t, empty.CompletionRate)
+	assert.Nil(t, empty.AvgCycleTimeHours)
+}
+
+func TestReportPeriodStart(t *testing.T) {
+	// a Sunday
+	at := time.Date(2026, 10, 18, 22, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), PeriodDay.Start(at))
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), PeriodWeek.Start(at))
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), PeriodMonth.Start(at))
+	assert.Equal(t, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), PeriodWeek.Start(at.AddDate(0, 0, 1)))
+}
+
+func TestFillThroughput(t *testing.T) {
+	from := time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	h8, h2 := 8.0, 2.0
+	rows := []*Throughput{
+		{PeriodStart: time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC), Completed: 1, AvgCycleTimeHours: &h8},
+		{PeriodStart: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), Completed: 3, AvgCycleTimeHours: &h2},
+	}
+
+	periods, err := FillThroughput(rows, from, to, PeriodWeek)
+	require.NoError(t, err)
+	require.Len(t, periods, 4)
+	assert.Equal(t, time.Date(2026, 9, 28, 0, 0, 0, 0, time.UTC), periods[0].PeriodStart)
+	assert.Equal(t, []int{0, 1, 0, 3}, []int{periods[0].Completed, periods[1].Completed, periods[2].Completed, periods[3].Completed})
+
+	completed, cycle := ThroughputTotals(periods)
+	assert.Equal(t, 4, completed)
+	require.NotNil(t, cycle)
+	assert.InDelta(t, 3.5, *cycle, 1e-9)
+
+	_, err = FillThroughput(nil, from, from.AddDate(10, 0, 0), PeriodDay)
+	assert.Error(t, err)
+}
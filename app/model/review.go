@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookReview is a user's rating of a book, with optional text. A user
+// reviews a book once.
+type BookReview struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	BookID    uuid.UUID `db:"book_id" json:"book_id"`
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	Rating    int       `db:"rating" json:"rating"`
+	Body      *string   `db:"body" json:"body"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	Version   int64     `db:"version" json:"version"`
+}
+
+// WriteBookReview creates or rewrites a review. Ratings use the scale of
+// Meta.Rating.
+type WriteBookReview struct {
+	Rating int     `json:"rating" validate:"required,min=1,max=10"`
+	Body   *string `json:"body" validate:"omitempty,lte=10000"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/byeblogs/go-boilerplate/pkg/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBookReviewRating(t *testing.T) {
+	validate := validator.NewValidator()
+	for rating, ok := range map[int]bool{0: false, 1: true, 7: true, 10: true, 11: false, -3: false} {
+		err := validate.Struct(&WriteBookReview{Rating: rating})
+		assert.Equal(t, ok, err == nil, "rating %d", rating)
+	}
+}
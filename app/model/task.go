@@ -1,17 +1,143 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Task struct {
-	ID        uuid.UUID  `db:"id" json:"id"`
-	ProjectID uuid.UUID  `db:"project_id" json:"project_id" validate:"required"`
-	Title     string     `db:"title" json:"title" validate:"required"`
-	Status    string     `db:"status" json:"status"` // todo|doing|done (we’ll default to todo)
-	DueAt     *time.Time `db:"due_at" json:"due_at"`
-	CreatedAt time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	ID              uuid.UUID    `db:"id" json:"id"`
+	ProjectID       uuid.UUID    `db:"project_id" json:"project_id" validate:"required"`
+	ParentTaskID    *uuid.UUID   `db:"parent_task_id" json:"parent_task_id"`
+	Title           string       `db:"title" json:"title" validate:"required"`
+	Description     *string      `db:"description" json:"description"`
+	Priority        TaskPriority `db:"priority" json:"priority" validate:"required,oneof=low medium high urgent"`
+	Assignees       []uuid.UUID  `db:"-" json:"assignees"`
+	Labels          []string     `db:"-" json:"labels" validate:"dive,required,lte=50"`
+	Status          TaskStatus   `db:"status" json:"status" validate:"required,oneof=todo doing done cancelled"`
+	DueAt           *time.Time   `db:"due_at" json:"due_at"`
+	EstimateMinutes *int         `db:"estimate_minutes" json:"estimate_minutes" validate:"omitempty,min=0,max=1000000"`
+	StartedAt       *time.Time   `db:"started_at" json:"started_at"`     // first move to doing
+	CompletedAt     *time.Time   `db:"completed_at" json:"completed_at"` // moved to done or cancelled
+	Position        string       `db:"position" json:"position"`         // rank within the status column, see RankBetween
+	RecurrenceID    *uuid.UUID   `db:"recurrence_id" json:"recurrence_id,omitempty"`
+	OccurrenceAt    *time.Time   `db:"occurrence_at" json:"occurrence_at,omitempty"` // scheduled start within the series
+	CreatedAt       time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time    `db:"updated_at" json:"updated_at"`
+	DeletedAt       *time.Time   `db:"deleted_at" json:"deleted_at,omitempty"`
+	Version         int64        `db:"version" json:"version"`
+}
+
+// TaskNode is a task with its subtasks, as returned by the tree endpoint.
+type TaskNode struct {
+	*Task
+	Subtasks []*TaskNode `json:"subtasks"`
+}
+
+// NewTaskTree nests tasks under their parents. The first task is the root;
+// the others must be its descendants, listed after their parent.
+func NewTaskTree(tasks []*Task) *TaskNode {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	nodes := make(map[uuid.UUID]*TaskNode, len(tasks))
+	root := &TaskNode{Task: tasks[0], Subtasks: []*TaskNode{}}
+	nodes[root.ID] = root
+	for _, t := range tasks[1:] {
+		if t.ParentTaskID == nil {
+			continue
+		}
+		parent, ok := nodes[*t.ParentTaskID]
+		if !ok {
+			continue
+		}
+		n := &TaskNode{Task: t, Subtasks: []*TaskNode{}}
+		parent.Subtasks = append(parent.Subtasks, n)
+		nodes[t.ID] = n
+	}
+	return root
+}
+
+// TaskDependency asks to block a task on another one.
+type TaskDependency struct {
+	BlockedByID uuid.UUID `json:"blocked_by" validate:"required"`
+}
+
+// TaskTransition asks to move a task to another status.
+type TaskTransition struct {
+	Status TaskStatus `json:"status" validate:"required,oneof=todo doing done cancelled"`
+}
+
+// TaskMove asks to move a task on the board: into another status column
+// and/or right below After or right above Before. Without neighbours the
+// task goes to the bottom of the column.
+type TaskMove struct {
+	Status   TaskStatus `json:"status" validate:"omitempty,oneof=todo doing done cancelled"`
+	AfterID  *uuid.UUID `json:"after_id"`
+	BeforeID *uuid.UUID `json:"before_id"`
+}
+
+// BoardColumn is one status column of a project board, tasks in order.
+type BoardColumn struct {
+	Status TaskStatus `json:"status"`
+	Tasks  []*Task    `json:"tasks"`
+}
+
+// NewBoard groups tasks, already sorted by position, into one column per
+// status in workflow order. Empty columns are kept.
+func NewBoard(tasks []*Task) []*BoardColumn {
+	columns := make([]*BoardColumn, len(TaskStatuses))
+	byStatus := make(map[TaskStatus]*BoardColumn, len(TaskStatuses))
+	for i, s := range TaskStatuses {
+		columns[i] = &BoardColumn{Status: s, Tasks: []*Task{}}
+		byStatus[s] = columns[i]
+	}
+	for _, t := range tasks {
+		if col, ok := byStatus[t.Status]; ok {
+			col.Tasks = append(col.Tasks, t)
+		}
+	}
+	return columns
+}
+
+// TaskPriority ranks tasks for planning.
+type TaskPriority string
+
+const (
+	TaskPriorityLow    TaskPriority = "low"
+	TaskPriorityMedium TaskPriority = "medium"
+	TaskPriorityHigh   TaskPriority = "high"
+	TaskPriorityUrgent TaskPriority = "urgent"
+)
+
+// TaskPriorities lists every priority, lowest first.
+var TaskPriorities = []TaskPriority{TaskPriorityLow, TaskPriorityMedium, TaskPriorityHigh, TaskPriorityUrgent}
+
+// Valid reports whether p is a known priority.
+func (p TaskPriority) Valid() bool {
+	for _, known := range TaskPriorities {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLabels lower-cases and trims label names and drops duplicates,
+// keeping the first occurrence.
+func NormalizeLabels(names []string) []string {
+	out := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out
 }
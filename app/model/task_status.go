@@ -0,0 +1,176 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TaskStatus is the workflow state of a task.
+type TaskStatus string
+
+const (
+	TaskTodo      TaskStatus = "todo"
+	TaskDoing     TaskStatus = "doing"
+	TaskDone      TaskStatus = "done"
+	TaskCancelled TaskStatus = "cancelled"
+)
+
+// TaskStatuses lists every status, in workflow order. It must match the
+// CHECK constraint on tasks.status.
+var TaskStatuses = []TaskStatus{TaskTodo, TaskDoing, TaskDone, TaskCancelled}
+
+// Valid reports whether s is a known status.
+func (s TaskStatus) Valid() bool {
+	for _, known := range TaskStatuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Closed reports whether s finishes the work on a task, which is what
+// CompletedAt records. Whether a closed task can be reopened is up to the
+// workflow.
+func (s TaskStatus) Closed() bool {
+	return s == TaskDone || s == TaskCancelled
+}
+
+// DefaultTaskTransitions is the workflow used when TASK_TRANSITIONS is unset:
+// work moves freely between todo and doing, and done and cancelled are
+// terminal.
+const DefaultTaskTransitions = "todo=doing,done,cancelled;doing=todo,done,cancelled"
+
+// TaskWorkflow is the state machine tasks move through. Statuses without
+// outgoing transitions are terminal.
+type TaskWorkflow struct {
+	transitions map[TaskStatus][]TaskStatus
+}
+
+// ParseTaskWorkflow reads a workflow written as semicolon separated rules of
+// the form "from=to,to", e.g. "todo=doing;doing=done". An empty spec yields
+// the default workflow.
+func ParseTaskWorkflow(spec string) (*TaskWorkflow, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultTaskTransitions
+	}
+
+	w := &TaskWorkflow{transitions: map[TaskStatus][]TaskStatus{}}
+	for _, rule := range strings.Split(spec, ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		from, targets, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("task workflow rule %q: want from=to,to", rule)
+		}
+
+		f := TaskStatus(strings.TrimSpace(from))
+		if !f.Valid() {
+			return nil, fmt.Errorf("task workflow rule %q: unknown status %q", rule, f)
+		}
+		for _, to := range strings.Split(targets, ",") {
+			t := TaskStatus(strings.TrimSpace(to))
+			if t == "" {
+				continue
+			}
+			if !t.Valid() {
+				return nil, fmt.Errorf("task workflow rule %q: unknown status %q", rule, t)
+			}
+			if t != f {
+				w.transitions[f] = append(w.transitions[f], t)
+			}
+		}
+	}
+	return w, nil
+}
+
+// DefaultTaskWorkflow returns the workflow described by DefaultTaskTransitions.
+func DefaultTaskWorkflow() *TaskWorkflow {
+	w, err := ParseTaskWorkflow(DefaultTaskTransitions)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+// Next returns the statuses a task in status s may move to.
+func (w *TaskWorkflow) Next(s TaskStatus) []TaskStatus {
+	next := append([]TaskStatus(nil), w.transitions[s]...)
+	sort.SliceStable(next, func(i, j int) bool { return statusOrder(next[i]) < statusOrder(next[j]) })
+	return next
+}
+
+// CanTransition reports whether a task may move from one status to another.
+// Staying in the same status is always allowed.
+func (w *TaskWorkflow) CanTransition(from, to TaskStatus) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range w.transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether s ends the workflow.
+func (w *TaskWorkflow) IsTerminal(s TaskStatus) bool {
+	return len(w.transitions[s]) == 0
+}
+
+// Transition moves t to status to, stamping StartedAt and CompletedAt.
+func (w *TaskWorkflow) Transition(t *Task, to TaskStatus, now time.Time) error {
+	if !to.Valid() {
+		return fmt.Errorf("%w %q", ErrUnknownTaskStatus, to)
+	}
+	if !w.CanTransition(t.Status, to) {
+		return &TransitionError{From: t.Status, To: to, Allowed: w.Next(t.Status)}
+	}
+	t.Status = to
+	w.Stamp(t, now)
+	return nil
+}
+
+// Stamp keeps the lifecycle timestamps of t in line with its status: the
+// first move to doing sets StartedAt, closing the task sets CompletedAt and
+// reopening it clears CompletedAt again.
+func (w *TaskWorkflow) Stamp(t *Task, now time.Time) {
+	if t.Status == TaskDoing && t.StartedAt == nil {
+		t.StartedAt = &now
+	}
+	if t.Status.Closed() {
+		if t.CompletedAt == nil {
+			t.CompletedAt = &now
+		}
+	} else {
+		t.CompletedAt = nil
+	}
+}
+
+// ErrUnknownTaskStatus is returned for a move to a status that does not
+// exist.
+var ErrUnknownTaskStatus = errors.New("unknown task status")
+
+// TransitionError is returned for a move the workflow does not allow.
+type TransitionError struct {
+	From, To TaskStatus
+	Allowed  []TaskStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("task can't move from %s to %s", e.From, e.To)
+}
+
+func statusOrder(s TaskStatus) int {
+	for i, known := range TaskStatuses {
+		if s == known {
+			return i
+		}
+	}
+	return len(TaskStatuses)
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTaskWorkflow(t *testing.T) {
+	w, err := ParseTaskWorkflow("")
+	require.NoError(t, err)
+	assert.True(t, w.CanTransition(TaskTodo, TaskDoing))
+	assert.True(t, w.CanTransition(TaskDoing, TaskDone))
+	assert.False(t, w.CanTransition(TaskDone, TaskTodo))
+	assert.True(t, w.IsTerminal(TaskDone))
+	assert.True(t, w.IsTerminal(TaskCancelled))
+	assert.Equal(t, []TaskStatus{TaskTodo, TaskDone, TaskCancelled}, w.Next(TaskDoing))
+
+	w, err = ParseTaskWorkflow("todo=doing; doing=done; done=todo")
+	require.NoError(t, err)
+	assert.False(t, w.CanTransition(TaskTodo, TaskDone))
+	assert.True(t, w.CanTransition(TaskDone, TaskTodo))
+	assert.False(t, w.IsTerminal(TaskDone))
+
+	for _, spec := range []string{"todo", "todo=later", "someday=todo"} {
+		_, err := ParseTaskWorkflow(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestTaskWorkflowTransition(t *testing.T) {
+	w, err := ParseTaskWorkflow("todo=doing;doing=todo,done;done=doing")
+	require.NoError(t, err)
+
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	task := &Task{Status: TaskTodo}
+
+	var te *TransitionError
+	err = w.Transition(task, TaskDone, start)
+	require.True(t, errors.As(err, &te))
+	assert.Equal(t, []TaskStatus{TaskDoing}, te.Allowed)
+	assert.Equal(t, TaskTodo, task.Status)
+
+	require.NoError(t, w.Transition(task, TaskDoing, start))
+	assert.Equal(t, start, *task.StartedAt)
+	assert.Nil(t, task.CompletedAt)
+
+	require.NoError(t, w.Transition(task, TaskDone, start.Add(time.Hour)))
+	assert.Equal(t, start.Add(time.Hour), *task.CompletedAt)
+
+	require.NoError(t, w.Transition(task, TaskDoing, start.Add(2*time.Hour)))
+	assert.Equal(t, start, *task.StartedAt, "reopening keeps the first start")
+	assert.Nil(t, task.CompletedAt, "reopening clears the completion")
+
+	assert.Error(t, w.Transition(task, TaskStatus("later"), start))
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeLabels(t *testing.T) {
+	assert.Equal(t, []string{"bug", "ui"}, NormalizeLabels([]string{" Bug", "ui", "", "BUG "}))
+	assert.Equal(t, []string{}, NormalizeLabels(nil))
+}
+
+func TestNewTaskTree(t *testing.T) {
+	root := &Task{ID: uuid.New()}
+	child := &Task{ID: uuid.New(), ParentTaskID: &root.ID}
+	grandchild := &Task{ID: uuid.New(), ParentTaskID: &child.ID}
+	sibling := &Task{ID: uuid.New(), ParentTaskID: &root.ID}
+
+	tree := NewTaskTree([]*Task{root, child, sibling, grandchild})
+	require.NotNil(t, tree)
+	assert.Equal(t, root.ID, tree.ID)
+	require.Len(t, tree.Subtasks, 2)
+	assert.Equal(t, child.ID, tree.Subtasks[0].ID)
+	assert.Equal(t, sibling.ID, tree.Subtasks[1].ID)
+	require.Len(t, tree.Subtasks[0].Subtasks, 1)
+	assert.Equal(t, grandchild.ID, tree.Subtasks[0].Subtasks[0].ID)
+	assert.Empty(t, tree.Subtasks[1].Subtasks)
+
+	assert.Nil(t, NewTaskTree(nil))
+}
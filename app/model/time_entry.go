@@ -0,0 +1,87 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TimeEntry is time a user spent on a task. While its timer runs EndedAt is
+// nil and the duration counts up to now.
+type TimeEntry struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	TaskID    uuid.UUID  `db:"task_id" json:"task_id"`
+	ProjectID uuid.UUID  `db:"project_id" json:"project_id"` // of the task
+	TaskTitle string     `db:"task_title" json:"-"`
+	UserID    uuid.UUID  `db:"user_id" json:"user_id"`
+	StartedAt time.Time  `db:"started_at" json:"started_at"`
+	EndedAt   *time.Time `db:"ended_at" json:"ended_at"`
+	Note      *string    `db:"note" json:"note"`
+	// Running and DurationSeconds are computed when the entry is read.
+	Running         bool      `db:"running" json:"running"`
+	DurationSeconds int64     `db:"duration_seconds" json:"duration_seconds"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+	Version         int64     `db:"version" json:"version"`
+}
+
+// WriteTimeEntry records time after the fact: from started_at until
+// ended_at, or for duration_minutes.
+type WriteTimeEntry struct {
+	StartedAt       time.Time  `json:"started_at" validate:"required"`
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationMinutes *int       `json:"duration_minutes" validate:"omitempty,min=1,max=14400"`
+	Note            *string    `json:"note" validate:"omitempty,lte=2000"`
+}
+
+// StartTimer starts a timer, optionally with a note.
+type StartTimer struct {
+	Note *string `json:"note" validate:"omitempty,lte=2000"`
+}
+
+// End returns when the entry ends, taken from ended_at or the duration.
+// Exactly one of them must be set, and the entry can't end in the future or
+// before it started.
+func (w *WriteTimeEntry) End(now time.Time) (time.Time, error) {
+	var end time.Time
+	switch {
+	case (w.EndedAt == nil) == (w.DurationMinutes == nil):
+		return end, errors.New("set either ended_at or duration_minutes")
+	case w.EndedAt != nil:
+		end = *w.EndedAt
+	default:
+		end = w.StartedAt.Add(time.Duration(*w.DurationMinutes) * time.Minute)
+	}
+	if end.Before(w.StartedAt) {
+		return end, errors.New("ended_at is before started_at")
+	}
+	if end.After(now) {
+		return end, errors.New("time entries can't end in the future")
+	}
+	return end, nil
+}
+
+// TimeGroup is what time summaries are grouped by.
+type TimeGroup string
+
+const (
+	TimeByProject TimeGroup = "project"
+	TimeByTask    TimeGroup = "task"
+	TimeByUser    TimeGroup = "user"
+)
+
+// Valid reports whether g is a known grouping.
+func (g TimeGroup) Valid() bool {
+	return g == TimeByProject || g == TimeByTask || g == TimeByUser
+}
+
+// TimeSummary is the time tracked on one project, task or user.
+type TimeSummary struct {
+	ID      uuid.UUID `db:"id" json:"id"`
+	Name    string    `db:"name" json:"name"`
+	Entries int       `db:"entries" json:"entries"`
+	Seconds int64     `db:"seconds" json:"seconds"`
+	// Estimated effort, summed over the tasks; not set for users.
+	EstimateMinutes *int64 `db:"estimate_minutes" json:"estimate_minutes,omitempty"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTimeEntryEnd(t *testing.T) {
+	now := time.Date(2026, 10, 19, 17, 0, 0, 0, time.UTC)
+	start := now.Add(-3 * time.Hour)
+	ninety, ended := 90, now.Add(-time.Hour)
+
+	end, err := (&WriteTimeEntry{StartedAt: start, DurationMinutes: &ninety}).End(now)
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(90*time.Minute), end)
+
+	end, err = (&WriteTimeEntry{StartedAt: start, EndedAt: &ended}).End(now)
+	require.NoError(t, err)
+	assert.Equal(t, ended, end)
+
+	_, err = (&WriteTimeEntry{StartedAt: start}).End(now)
+	assert.Error(t, err, "neither end nor duration")
+	_, err = (&WriteTimeEntry{StartedAt: start, EndedAt: &ended, DurationMinutes: &ninety}).End(now)
+	assert.Error(t, err, "both end and duration")
+	before := start.Add(-time.Minute)
+	_, err = (&WriteTimeEntry{StartedAt: start, EndedAt: &before}).End(now)
+	assert.Error(t, err, "ends before it starts")
+	long := 600
+	_, err = (&WriteTimeEntry{StartedAt: start, DurationMinutes: &long}).End(now)
+	assert.Error(t, err, "ends in the future")
+}
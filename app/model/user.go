@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,26 +9,29 @@ import (
 
 // User struct to describe User object.
 type User struct {
-	ID           uuid.UUID `db:"id" json:"id"`
-	Email        string    `db:"email" json:"email"`
-	Name         string    `db:"name" json:"name"`
-	Username     *string   `db:"username" json:"username,omitempty"`
-	PasswordHash *string   `db:"password_hash" json:"-"` // never expose
-	IsActive     bool      `db:"is_active" json:"is_active"`
-	IsAdmin      bool      `db:"is_admin" json:"is_admin"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
-	IsDeleted    bool      `db:"is_deleted"`
-	UserName     string    `db:"username"`
-	Password     string    `db:"password"`
-	FirstName    string    `db:"first_name"`
-	LastName     string    `db:"last_name"`
+	ID           uuid.UUID  `db:"id" json:"id"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt    *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Version      int64      `db:"version" json:"version"`
+	IsActive     bool       `db:"is_active" json:"is_active"`
+	IsAdmin      bool       `db:"is_admin" json:"is_admin"`
+	UserName     string     `db:"username" json:"username"`
+	Email        string     `db:"email" json:"email" validate:"required,email,lte=150"`
+	PasswordHash string     `db:"password_hash" json:"-"` // never expose
+	FirstName    string     `db:"first_name" json:"first_name" validate:"required,lte=100"`
+	LastName     string     `db:"last_name" json:"last_name" validate:"required,lte=100"`
 }
 
 func NewUser() *User {
 	return &User{}
 }
 
+// FullName returns the display name of the user.
+func (u *User) FullName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
 type CreateUser struct {
 	IsAdmin   bool   `json:"is_admin"`
 	IsActive  bool   `json:"is_active"`
@@ -38,9 +42,27 @@ type CreateUser struct {
 	LastName  string `json:"last_name" validate:"required,lte=100"`
 }
 
+// UpdateUser only changes the fields present in the payload; flags are
+// pointers so an omitted flag doesn't reset it to false.
 type UpdateUser struct {
-	IsAdmin   bool   `json:"is_admin"`
-	IsActive  bool   `json:"is_active"`
+	IsAdmin   *bool  `json:"is_admin"`
+	IsActive  *bool  `json:"is_active"`
+	Email     string `json:"email" validate:"omitempty,email,lte=150"`
 	FirstName string `json:"first_name" validate:"required,lte=100"`
 	LastName  string `json:"last_name" validate:"required,lte=100"`
 }
+
+// Apply copies the update onto u.
+func (uu *UpdateUser) Apply(u *User) {
+	if uu.IsAdmin != nil {
+		u.IsAdmin = *uu.IsAdmin
+	}
+	if uu.IsActive != nil {
+		u.IsActive = *uu.IsActive
+	}
+	if uu.Email != "" {
+		u.Email = uu.Email
+	}
+	u.FirstName = uu.FirstName
+	u.LastName = uu.LastName
+}
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// WebhookEvent is a domain event of the outbox, such as task.created or
+// book.status_changed. Data is the row after the change (before it, for
+// purges); Previous holds the old values of the columns an update changed.
+// ProjectID is set for the events of projects and their tasks.
+type WebhookEvent struct {
+	ID           int64           `db:"id" json:"id"`
+	Type         string          `db:"type" json:"type"`
+	ResourceType string          `db:"resource_type" json:"resource_type"`
+	ResourceID   uuid.UUID       `db:"resource_id" json:"resource_id"`
+	ActorUserID  *uuid.UUID      `db:"actor_user_id" json:"actor_user_id"`
+	ProjectID    *uuid.UUID      `db:"project_id" json:"project_id,omitempty"`
+	Data         types.JSONText  `db:"data" json:"data"`
+	Previous     *types.JSONText `db:"previous" json:"previous,omitempty"`
+	CreatedAt    time.Time       `db:"created_at" json:"created_at"`
+	DispatchedAt *time.Time      `db:"dispatched_at" json:"-"`
+}
+
+// WebhookEventKinds are what an event says happened to its resource.
+var WebhookEventKinds = []string{"created", "updated", "status_changed", "deleted", "restored", "purged"}
+
+// WebhookResourceTypes are the resources whose changes are published.
+var WebhookResourceTypes = []string{"book", "project", "task"}
+
+// EventPatterns select event types: "*" matches all of them, "task.*" those
+// of a resource, anything else one type.
+type EventPatterns []string
+
+// Match reports whether eventType matches one of the patterns.
+func (p EventPatterns) Match(eventType string) bool {
+	resource, _, _ := strings.Cut(eventType, ".")
+	for _, pattern := range p {
+		if pattern == "*" || pattern == eventType || pattern == resource+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every pattern can match some event.
+func (p EventPatterns) Validate() error {
+	if len(p) == 0 {
+		return errors.New("subscribe to at least one event, or *")
+	}
+	for _, pattern := range p {
+		if pattern == "*" {
+			continue
+		}
+		resource, kind, _ := strings.Cut(pattern, ".")
+		if !slices.Contains(WebhookResourceTypes, resource) || (kind != "*" && !slices.Contains(WebhookEventKinds, kind)) {
+			return fmt.Errorf("unknown event %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Value stores the patterns as a JSON array.
+func (p EventPatterns) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan reads the patterns from a JSON array.
+func (p *EventPatterns) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("can't scan %T into event patterns", value)
+	}
+}
+
+// WebhookSubscription is an endpoint that receives the events matching its
+// patterns. Each request is signed with the subscription's secret, which is
+// only shown when the subscription is created.
+type WebhookSubscription struct {
+	ID              uuid.UUID     `db:"id" json:"id"`
+	URL             string        `db:"url" json:"url" validate:"required,url,lte=2048"`
+	Secret          string        `db:"secret" json:"secret,omitempty" validate:"omitempty,min=16,max=256"`
+	Events          EventPatterns `db:"events" json:"events"`
+	Description     *string       `db:"description" json:"description" validate:"omitempty,lte=500"`
+	Active          bool          `db:"active" json:"active"`
+	CreatedByUserID *uuid.UUID    `db:"created_by_user_id" json:"created_by_user_id"`
+	CreatedAt       time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time     `db:"updated_at" json:"updated_at"`
+	Version         int64         `db:"version" json:"version"`
+}
+
+// WebhookDeliveryStatus is where a delivery stands: pending until the
+// endpoint accepts it or the retries run out, then delivered or dead.
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliveryDelivered WebhookDeliveryStatus = "delivered"
+	DeliveryDead      WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one event sent, or to be sent, to one subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `db:"id" json:"id"`
+	SubscriptionID uuid.UUID             `db:"subscription_id" json:"subscription_id"`
+	EventID        int64                 `db:"event_id" json:"event_id"`
+	EventType      string                `db:"event_type" json:"event_type"` // computed
+	Status         WebhookDeliveryStatus `db:"status" json:"status"`
+	Attempts       int                   `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time             `db:"next_attempt_at" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time            `db:"last_attempt_at" json:"last_attempt_at"`
+	ResponseStatus *int                  `db:"response_status" json:"response_status"`
+	LastError      *string               `db:"last_error" json:"last_error"`
+	DeliveredAt    *time.Time            `db:"delivered_at" json:"delivered_at"`
+	CreatedAt      time.Time             `db:"created_at" json:"created_at"`
+}
+
+// WebhookRetry is the retry policy of deliveries: the n-th failed attempt
+// is retried after Base * 2^(n-1), at most Max, until MaxAttempts attempts
+// failed.
+type WebhookRetry struct {
+	Base, Max   time.Duration
+	MaxAttempts int
+}
+
+// Backoff returns how long to wait after the given number of failed
+// attempts.
+func (r WebhookRetry) Backoff(attempts int) time.Duration {
+	d := r.Base
+	for i := 1; i < attempts && d < r.Max; i++ {
+		d *= 2
+	}
+	if d > r.Max {
+		d = r.Max
+	}
+	return d
+}
+
+// Failed records a failed attempt made at now: the delivery is retried
+// later, or dead once it ran out of attempts.
+func (d *WebhookDelivery) Failed(r WebhookRetry, now time.Time, status int, err error) {
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatus = nil
+	if status != 0 {
+		d.ResponseStatus = &status
+	}
+	msg := err.Error()
+	d.LastError = &msg
+	if d.Attempts >= r.MaxAttempts {
+		d.Status = DeliveryDead
+		return
+	}
+	d.Status = DeliveryPending
+	d.NextAttemptAt = now.Add(r.Backoff(d.Attempts))
+}
+
+// Delivered records the attempt made at now that the endpoint accepted.
+func (d *WebhookDelivery) Delivered(now time.Time, status int) {
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatus = &status
+	d.LastError = nil
+	d.Status = DeliveryDelivered
+	d.DeliveredAt = &now
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventPatternsMatch(t *testing.T) {
+	assert.True(t, EventPatterns{"*"}.Match("task.created"))
+	assert.True(t, EventPatterns{"book.*"}.Match("book.status_changed"))
+	assert.True(t, EventPatterns{"book.created", "task.deleted"}.Match("task.deleted"))
+	assert.False(t, EventPatterns{"book.*"}.Match("task.created"))
+	assert.False(t, EventPatterns{"task.updated"}.Match("task.status_changed"))
+	assert.False(t, EventPatterns{}.Match("task.created"))
+}
+
+func TestEventPatternsValidate(t *testing.T) {
+	assert.NoError(t, EventPatterns{"*"}.Validate())
+	assert.NoError(t, EventPatterns{"task.*", "book.purged"}.Validate())
+	assert.Error(t, EventPatterns{}.Validate(), "subscribes to nothing")
+	assert.Error(t, EventPatterns{"user.created"}.Validate(), "unknown resource")
+	assert.Error(t, EventPatterns{"task.archived"}.Validate(), "unknown kind")
+	assert.Error(t, EventPatterns{"task"}.Validate())
+}
+
+func TestEventPatternsScan(t *testing.T) {
+	var p EventPatterns
+	require.NoError(t, p.Scan([]byte(`["task.*","book.created"]`)))
+	assert.Equal(t, EventPatterns{"task.*", "book.created"}, p)
+
+	v, err := p.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `["task.*","book.created"]`, string(v.([]byte)))
+
+	assert.Error(t, p.Scan(42))
+}
+
+func TestWebhookRetryBackoff(t *testing.T) {
+	r := WebhookRetry{Base: 30 * time.Second, Max: 10 * time.Minute, MaxAttempts: 5}
+	assert.Equal(t, 30*time.Second, r.Backoff(1))
+	assert.Equal(t, time.Minute, r.Backoff(2))
+	assert.Equal(t, 4*time.Minute, r.Backoff(4))
+	assert.Equal(t, 8*time.Minute, r.Backoff(5))
+	assert.Equal(t, 10*time.Minute, r.Backoff(6), "capped")
+	assert.Equal(t, 10*time.Minute, r.Backoff(100))
+}
+
+func TestWebhookDeliveryAttempts(t *testing.T) {
+	r := WebhookRetry{Base: time.Minute, Max: time.Hour, MaxAttempts: 2}
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	d := &WebhookDelivery{Status: DeliveryPending, NextAttemptAt: now}
+
+	d.Failed(r, now, 503, errors.New("503 Service Unavailable"))
+	assert.Equal(t, DeliveryPending, d.Status)
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, now.Add(time.Minute), d.NextAttemptAt)
+	assert.Equal(t, 503, *d.ResponseStatus)
+	assert.Equal(t, "503 Service Unavailable", *d.LastError)
+
+	later := now.Add(time.Minute)
+	d.Failed(r, later, 0, errors.New("connection refused"))
+	assert.Equal(t, DeliveryDead, d.Status, "out of attempts")
+	assert.Equal(t, 2, d.Attempts)
+	assert.Nil(t, d.ResponseStatus, "no response")
+	assert.Equal(t, later, *d.LastAttemptAt)
+
+	d = &WebhookDelivery{Status: DeliveryPending, Attempts: 1}
+	d.Delivered(later, 204)
+	assert.Equal(t, DeliveryDelivered, d.Status)
+	assert.Equal(t, 2, d.Attempts)
+	assert.Equal(t, later, *d.DeliveredAt)
+	assert.Nil(t, d.LastError)
+}
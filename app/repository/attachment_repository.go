@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type AttachmentRepo struct {
+	db          *database.DB
+	withDeleted bool
+}
+
+func NewAttachmentRepo(db *database.DB) AttachmentRepository {
+	return &AttachmentRepo{db: db}
+}
+
+// WithDeleted returns a copy of the repository whose reads include soft-deleted attachments.
+func (repo *AttachmentRepo) WithDeleted() AttachmentRepository {
+	return &AttachmentRepo{db: repo.db, withDeleted: true}
+}
+
+// Create records the attachment and, the first time its content is seen, the
+// blob. A new book cover replaces the previous one.
+func (repo *AttachmentRepo) Create(a *model.Attachment) error {
+	now := time.Now().UTC()
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO blobs (sha256, size, content_type, created_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (sha256) DO NOTHING
+		`, a.SHA256, a.Size, a.ContentType, now)
+		if err != nil {
+			return err
+		}
+
+		if a.Kind == model.AttachmentCover {
+			_, err := tx.Exec(`
+				UPDATE attachments SET deleted_at = $2
+				WHERE book_id = $1 AND kind = 'cover' AND deleted_at IS NULL
+			`, a.BookID, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO attachments
+				(id, sha256, task_id, book_id, kind, filename, content_type, size, uploaded_by_user_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, a.ID, a.SHA256, a.TaskID, a.BookID, a.Kind, a.Filename, a.ContentType, a.Size, a.UploadedBy, now)
+		return err
+	})
+}
+
+func (repo *AttachmentRepo) Get(id uuid.UUID) (*model.Attachment, error) {
+	a := model.Attachment{}
+	query := fmt.Sprintf(`SELECT * FROM attachments WHERE id = $1 AND %s`, notDeleted(repo.withDeleted))
+	if err := repo.db.Get(&a, query, id); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// AllByTask returns the files of the task, oldest first.
+func (repo *AttachmentRepo) AllByTask(taskID uuid.UUID) ([]*model.Attachment, error) {
+	var out []*model.Attachment
+	query := fmt.Sprintf(`
+		SELECT * FROM attachments WHERE task_id = $1 AND %s ORDER BY created_at, id
+	`, notDeleted(repo.withDeleted))
+	err := repo.db.Select(&out, query, taskID)
+	return out, err
+}
+
+// Cover returns the current cover of the book.
+func (repo *AttachmentRepo) Cover(bookID uuid.UUID) (*model.Attachment, error) {
+	a := model.Attachment{}
+	query := `
+		SELECT * FROM attachments
+		WHERE book_id = $1 AND kind = 'cover' AND deleted_at IS NULL
+		ORDER BY created_at DESC LIMIT 1
+	`
+	if err := repo.db.Get(&a, query, bookID); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Delete soft-deletes the attachment. Its blob stays until PurgeBlobs finds
+// it unreferenced.
+func (repo *AttachmentRepo) Delete(id uuid.UUID) error {
+	_, err := repo.db.Exec(`UPDATE attachments SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`, id, time.Now().UTC())
+	return err
+}
+
+// Purge permanently removes attachments soft-deleted before the given time.
+func (repo *AttachmentRepo) Purge(before time.Time) (int64, error) {
+	res, err := repo.db.Exec(`DELETE FROM attachments WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeBlobs removes blobs created before the given time that no attachment
+// refers to any more. remove deletes the content from the blob store; a blob
+// whose content can't be removed is kept for the next run.
+func (repo *AttachmentRepo) PurgeBlobs(before time.Time, remove func(sha256 string) error) (int64, error) {
+	var sums []string
+	err := repo.db.Select(&sums, `
+		SELECT b.sha256 FROM blobs b
+		WHERE b.created_at < $1 AND NOT EXISTS (SELECT 1 FROM attachments a WHERE a.sha256 = b.sha256)
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, sum := range sums {
+		err := repo.db.InTx(func(tx *sqlx.Tx) error {
+			// re-checked under the row lock, in case an upload reused the blob
+			var gone string
+			err := tx.Get(&gone, `
+				DELETE FROM blobs b
+				WHERE b.sha256 = $1 AND NOT EXISTS (SELECT 1 FROM attachments a WHERE a.sha256 = b.sha256)
+				RETURNING b.sha256
+			`, sum)
+			if err != nil {
+				return err
+			}
+			return remove(gone)
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+)
+
+// AuditFilter narrows down audit events; zero fields don't filter. From
+// and To bound occurred_at as [From, To).
+type AuditFilter struct {
+	ActorID      uuid.UUID
+	Action       string
+	ResourceType string
+	ResourceID   uuid.UUID
+	RequestID    string
+	From, To     time.Time
+}
+
+// AuditRepo reads the audit log. Events are written by database triggers,
+// in the transaction of the change they record.
+type AuditRepo struct {
+	db *database.DB
+}
+
+func NewAuditRepo(db *database.DB) AuditRepository {
+	return &AuditRepo{db: db}
+}
+
+// Find lists the events matching f, newest first.
+func (repo *AuditRepo) Find(f AuditFilter, limit int, offset uint) ([]*model.AuditEvent, error) {
+	where, args := f.where()
+	query := fmt.Sprintf(`SELECT * FROM audit_events WHERE %s ORDER BY id DESC`, where)
+	if limit > 0 {
+		args = append(args, limit, offset)
+		query = fmt.Sprintf("%s LIMIT $%d OFFSET $%d", query, len(args)-1, len(args))
+	}
+
+	var out []*model.AuditEvent
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (repo *AuditRepo) Get(id int64) (*model.AuditEvent, error) {
+	e := model.AuditEvent{}
+	if err := repo.db.Get(&e, `SELECT * FROM audit_events WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (f AuditFilter) where() (string, []interface{}) {
+	where := []string{"TRUE"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.ActorID != uuid.Nil {
+		where = append(where, "actor_user_id = "+arg(f.ActorID))
+	}
+	if f.Action != "" {
+		where = append(where, "action = "+arg(f.Action))
+	}
+	if f.ResourceType != "" {
+		where = append(where, "resource_type = "+arg(f.ResourceType))
+	}
+	if f.ResourceID != uuid.Nil {
+		where = append(where, "resource_id = "+arg(f.ResourceID))
+	}
+	if f.RequestID != "" {
+		where = append(where, "request_id = "+arg(f.RequestID))
+	}
+	if !f.From.IsZero() {
+		where = append(where, "occurred_at >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "occurred_at < "+arg(f.To))
+	}
+	return strings.Join(where, " AND "), args
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// boardOrder sorts tasks into board columns in workflow order, then by
+// position. Ties, e.g. from concurrent inserts, fall back to age.
+var boardOrder = func() string {
+	whens := make([]string, len(model.TaskStatuses))
+	for i, s := range model.TaskStatuses {
+		whens[i] = fmt.Sprintf("WHEN '%s' THEN %d", s, i)
+	}
+	return fmt.Sprintf("CASE status %s END, position, created_at, id", strings.Join(whens, " "))
+}()
+
+// boardSlot is a task's place in a column.
+type boardSlot struct {
+	ID       uuid.UUID `db:"id"`
+	Position string    `db:"position"`
+}
+
+// Move puts the task into the column of t.Status, right below after or right
+// above before, or at the bottom without either. t carries the new status
+// and lifecycle stamps; t.Version makes the write conditional as in Update.
+// Neighbours outside the target column fail with ErrBadNeighbour.
+func (repo *TaskRepo) Move(id uuid.UUID, t *model.Task, after, before *uuid.UUID) error {
+	query := `
+		UPDATE tasks
+		SET status = $2, started_at = $3, completed_at = $4, position = $5, updated_at = $6, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($7 = 0 OR version = $7)
+	`
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := checkBlockers(tx, id, t.Status); err != nil {
+			return err
+		}
+		position, err := placeInColumn(tx, t.ProjectID, t.Status, id, after, before)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(query, id, t.Status, t.StartedAt, t.CompletedAt, position, time.Now().UTC(), t.Version)
+		if err != nil {
+			return err
+		}
+		return checkVersion(res, t.Version)
+	})
+}
+
+// bottomRank returns a position below every other task of the column.
+func bottomRank(tx *sqlx.Tx, projectID uuid.UUID, status model.TaskStatus, id uuid.UUID) (string, error) {
+	return placeInColumn(tx, projectID, status, id, nil, nil)
+}
+
+// placeInColumn finds the position for task id between its new neighbours.
+// Writers to a board are serialized per project for the rest of the
+// transaction. When the neighbours leave no room, the column is spread out
+// first.
+func placeInColumn(tx *sqlx.Tx, projectID uuid.UUID, status model.TaskStatus, id uuid.UUID, after, before *uuid.UUID) (string, error) {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('task_board'), hashtext($1::text))`, projectID); err != nil {
+		return "", err
+	}
+
+	var column []*boardSlot
+	query := `
+		SELECT id, position FROM tasks
+		WHERE project_id = $1 AND status = $2 AND id <> $3 AND deleted_at IS NULL
+		ORDER BY position, created_at, id
+	`
+	if err := tx.Select(&column, query, projectID, status, id); err != nil {
+		return "", err
+	}
+
+	position, err := rankIn(column, after, before)
+	if err != model.ErrNoRank {
+		return position, err
+	}
+	if err := spreadColumn(tx, column); err != nil {
+		return "", err
+	}
+	return rankIn(column, after, before)
+}
+
+// rankIn picks a position in the ordered column right below after or right
+// above before (both when they are adjacent), else at the bottom. It returns
+// model.ErrNoRank when the column has to be spread out first.
+func rankIn(column []*boardSlot, after, before *uuid.UUID) (string, error) {
+	index := func(id uuid.UUID) int {
+		for i, s := range column {
+			if s.ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// the neighbours sit at column[i-1] and column[i]
+	i := len(column)
+	switch {
+	case after != nil:
+		if i = index(*after) + 1; i == 0 {
+			return "", ErrBadNeighbour
+		}
+		if before != nil && (i == len(column) || column[i].ID != *before) {
+			return "", ErrBadNeighbour
+		}
+	case before != nil:
+		if i = index(*before); i < 0 {
+			return "", ErrBadNeighbour
+		}
+	}
+
+	var lower, upper string
+	if i > 0 {
+		lower = column[i-1].Position
+	}
+	if i < len(column) {
+		upper = column[i].Position
+	}
+	position, err := model.RankBetween(lower, upper)
+	if err != nil || len(position) > model.MaxRankLength {
+		return "", model.ErrNoRank
+	}
+	return position, nil
+}
+
+// spreadColumn gives the column evenly spaced positions, in its current
+// order. It is bookkeeping, so neither updated_at nor the version change.
+func spreadColumn(tx *sqlx.Tx, column []*boardSlot) error {
+	for i, position := range model.RankSpread(len(column)) {
+		if _, err := tx.Exec(`UPDATE tasks SET position = $2 WHERE id = $1`, column[i].ID, position); err != nil {
+			return err
+		}
+		column[i].Position = position
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankIn(t *testing.T) {
+	a := &boardSlot{ID: uuid.New(), Position: "a"}
+	c := &boardSlot{ID: uuid.New(), Position: "c"}
+	column := []*boardSlot{a, c}
+
+	pos, err := rankIn(column, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "o", pos)
+
+	pos, err = rankIn(column, &a.ID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", pos)
+
+	pos, err = rankIn(column, nil, &a.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "5", pos)
+
+	pos, err = rankIn(column, &a.ID, &c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", pos)
+
+	_, err = rankIn(column, &c.ID, &a.ID)
+	assert.ErrorIs(t, err, ErrBadNeighbour)
+	stranger := uuid.New()
+	_, err = rankIn(column, nil, &stranger)
+	assert.ErrorIs(t, err, ErrBadNeighbour)
+
+	tied := []*boardSlot{{ID: uuid.New(), Position: "i"}, {ID: uuid.New(), Position: "i"}}
+	_, err = rankIn(tied, &tied[0].ID, nil)
+	assert.ErrorIs(t, err, model.ErrNoRank)
+}
@@ -2,67 +2,406 @@ package repository
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/byeblogs/go-boilerplate/app/model"
 	"github.com/byeblogs/go-boilerplate/platform/database"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 type BookRepo struct {
-	db *database.DB
+	db          *database.DB
+	withDeleted bool
 }
 
 func NewBookRepo(db *database.DB) BookRepository {
-	return &BookRepo{db}
+	return &BookRepo{db: db}
 }
 
+// WithDeleted returns a copy of the repository whose reads include soft-deleted books.
+func (repo *BookRepo) WithDeleted() BookRepository {
+	return &BookRepo{db: repo.db, withDeleted: true}
+}
+
+// BookFilter narrows down book listings; zero fields don't filter. ISBN
+// must be normalized, see validator.NormalizeISBN.
+type BookFilter struct {
+	ISBN   string
+	Author string // case-insensitive, any author of the byline
+	Genre  string
+	Tag    string
+	// Statuses keeps books in any of the given statuses.
+	Statuses []model.BookStatus
+	// ByRating orders by average rating, best first, instead of newest first.
+	ByRating bool
+}
+
+// Create inserts the book with its authors, genres and tags. A live book
+// with the same ISBN fails it with ErrDuplicateISBN.
 func (repo *BookRepo) Create(b *model.Book) error {
 	query := `
 		INSERT INTO public.book
-			(id, created_at, updated_at, is_deleted, user_id, title, author, status, meta)
+			(id, created_at, updated_at, user_id, title, author, status, meta,
+			isbn, edition, publisher, published_year, language, page_count)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+	now := time.Now().UTC()
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := checkISBN(tx, b.ID, b.ISBN); err != nil {
+			return err
+		}
+		_, err := tx.Exec(query,
+			b.ID, now, now,
+			b.UserID, b.Title, b.Author, b.Status, b.Meta,
+			b.ISBN, b.Edition, b.Publisher, b.PublishedYear, b.Language, b.PageCount,
+		)
+		if err != nil {
+			return err
+		}
+		return setBookRelations(tx, b)
+	})
+}
+
+// Upsert inserts the book or overwrites the row with the same id.
+func (repo *BookRepo) Upsert(b *model.Book) error {
+	query := `
+		INSERT INTO public.book
+			(id, created_at, updated_at, user_id, title, author, status, meta,
+			isbn, edition, publisher, published_year, language, page_count)
 		VALUES
-			($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE
+		SET updated_at = EXCLUDED.updated_at, deleted_at = NULL, version = book.version + 1, user_id = EXCLUDED.user_id,
+			title = EXCLUDED.title, author = EXCLUDED.author, status = EXCLUDED.status, meta = EXCLUDED.meta,
+			isbn = EXCLUDED.isbn, edition = EXCLUDED.edition, publisher = EXCLUDED.publisher,
+			published_year = EXCLUDED.published_year, language = EXCLUDED.language, page_count = EXCLUDED.page_count
 	`
 	now := time.Now().UTC()
-	_, err := repo.db.Exec(query,
-		b.ID, now, now, false,
-		b.UserID, b.Title, b.Author, b.Status, b.Meta,
-	)
-	return err
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := checkISBN(tx, b.ID, b.ISBN); err != nil {
+			return err
+		}
+		_, err := tx.Exec(query,
+			b.ID, now, now,
+			b.UserID, b.Title, b.Author, b.Status, b.Meta,
+			b.ISBN, b.Edition, b.Publisher, b.PublishedYear, b.Language, b.PageCount,
+		)
+		if err != nil {
+			return err
+		}
+		return setBookRelations(tx, b)
+	})
 }
 
 func (repo *BookRepo) All(limit int, offset uint) ([]*model.Book, error) {
+	return repo.Find(BookFilter{}, limit, offset)
+}
+
+// Find lists the books matching every set field of the filter, newest or
+// best rated first.
+func (repo *BookRepo) Find(f BookFilter, limit int, offset uint) ([]*model.Book, error) {
+	where, args := f.where(repo.withDeleted)
+	order := "created_at DESC, id"
+	if f.ByRating {
+		order = "rating_avg DESC NULLS LAST, rating_count DESC, " + order
+	}
+	query := fmt.Sprintf(`SELECT * FROM book WHERE %s ORDER BY %s`, where, order)
+	if limit > 0 {
+		args = append(args, limit, offset)
+		query = fmt.Sprintf("%s LIMIT $%d OFFSET $%d", query, len(args)-1, len(args))
+	}
+
 	var books []*model.Book
-	query := `SELECT * FROM book WHERE is_deleted = FALSE`
-	var err error
+	if err := repo.db.Select(&books, query, args...); err != nil {
+		return nil, err
+	}
+	return books, loadBookRelations(repo.db, books...)
+}
 
-	if limit > 0 && offset >= 0 {
-		query = fmt.Sprintf("%s LIMIT $1 OFFSET $2", query)
-		err = repo.db.Select(&books, query, limit, offset)
-	} else {
-		err = repo.db.Select(&books, query)
+// Each calls fn with the books matching the filter, oldest first, in
+// batches of at most size books read from a database cursor, so that
+// listings of any length can be streamed. The filter's order is ignored.
+func (repo *BookRepo) Each(f BookFilter, size int, fn func([]*model.Book) error) error {
+	where, args := f.where(repo.withDeleted)
+	query := `SELECT * FROM book WHERE ` + where + ` ORDER BY created_at, id`
+	return eachBatch(repo.db, size, query, args, func(books []*model.Book) error {
+		if err := loadBookRelations(repo.db, books...); err != nil {
+			return err
+		}
+		return fn(books)
+	})
+}
+
+func (f BookFilter) where(withDeleted bool) (string, []interface{}) {
+	where := []string{notDeleted(withDeleted)}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
 
-	return books, err
+	if f.ISBN != "" {
+		where = append(where, "isbn = "+arg(f.ISBN))
+	}
+	if len(f.Statuses) > 0 {
+		in := make([]string, len(f.Statuses))
+		for i, status := range f.Statuses {
+			in[i] = arg(status)
+		}
+		where = append(where, "status IN ("+strings.Join(in, ", ")+")")
+	}
+	if f.Author != "" {
+		where = append(where, `EXISTS (
+			SELECT 1 FROM book_authors ba JOIN authors a ON a.id = ba.author_id
+			WHERE ba.book_id = book.id AND lower(a.name) = lower(`+arg(strings.TrimSpace(f.Author))+`))`)
+	}
+	if f.Genre != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM book_genres g WHERE g.book_id = book.id AND g.name = "+arg(strings.ToLower(strings.TrimSpace(f.Genre)))+")")
+	}
+	if f.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM book_tags t WHERE t.book_id = book.id AND t.name = "+arg(strings.ToLower(strings.TrimSpace(f.Tag)))+")")
+	}
+	return strings.Join(where, " AND "), args
 }
 
 func (repo *BookRepo) Get(ID uuid.UUID) (*model.Book, error) {
 	book := model.Book{}
-	query := `SELECT * FROM book WHERE id = $1 AND is_deleted = FALSE`
-	err := repo.db.Get(&book, query, ID)
+	query := fmt.Sprintf(`SELECT * FROM book WHERE id = $1 AND %s`, notDeleted(repo.withDeleted))
+	if err := repo.db.Get(&book, query, ID); err != nil {
+		return &book, err
+	}
+	return &book, loadBookRelations(repo.db, &book)
+}
+
+// GetByISBN returns the live book with the given normalized ISBN.
+func (repo *BookRepo) GetByISBN(isbn string) (*model.Book, error) {
+	book := model.Book{}
+	if err := repo.db.Get(&book, `SELECT * FROM book WHERE isbn = $1 AND deleted_at IS NULL`, isbn); err != nil {
+		return nil, err
+	}
+	return &book, loadBookRelations(repo.db, &book)
+}
 
-	return &book, err
+// GetByTitleAuthor returns the oldest live book with the given title and
+// first author, compared case-insensitively.
+func (repo *BookRepo) GetByTitleAuthor(title, author string) (*model.Book, error) {
+	book := model.Book{}
+	query := `
+		SELECT * FROM book
+		WHERE lower(title) = lower($1) AND lower(author) = lower($2) AND deleted_at IS NULL
+		ORDER BY created_at, id
+		LIMIT 1
+	`
+	if err := repo.db.Get(&book, query, strings.TrimSpace(title), strings.TrimSpace(author)); err != nil {
+		return nil, err
+	}
+	return &book, loadBookRelations(repo.db, &book)
 }
 
+// Update overwrites the book with its authors, genres and tags. When
+// b.Version is set, the write only applies to that version of the row and
+// fails with ErrVersionConflict otherwise.
 func (repo *BookRepo) Update(ID uuid.UUID, b *model.Book) error {
-	query := `UPDATE book SET updated_at = $2, title = $3, author = $4, status = $5, meta = $6 WHERE id = $1`
-	_, err := repo.db.Exec(query, ID, time.Now(), b.Title, b.Author, b.Status, b.Meta)
-	return err
+	query := `
+		UPDATE book SET updated_at = $2, title = $3, author = $4, status = $5, meta = $6,
+			isbn = $8, edition = $9, publisher = $10, published_year = $11, language = $12, page_count = $13,
+			version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($7 = 0 OR version = $7)
+	`
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := checkISBN(tx, ID, b.ISBN); err != nil {
+			return err
+		}
+		res, err := tx.Exec(query, ID, time.Now(), b.Title, b.Author, b.Status, b.Meta, b.Version,
+			b.ISBN, b.Edition, b.Publisher, b.PublishedYear, b.Language, b.PageCount)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(res, b.Version); err != nil {
+			return err
+		}
+		b.ID = ID
+		return setBookRelations(tx, b)
+	})
+}
+
+// Patch writes the columns that differ between before and after, and the
+// authors, genres or tags when those changed. As with Update, after.Version
+// makes the write conditional when set.
+func (repo *BookRepo) Patch(ID uuid.UUID, before, after *model.Book) error {
+	ch := diff(before, after, "title", "author", "status", "meta",
+		"isbn", "edition", "publisher", "published_year", "language", "page_count")
+	relations := !sameJSON(before.Authors, after.Authors) || !sameJSON(before.Genres, after.Genres) || !sameJSON(before.Tags, after.Tags)
+	ch.touch = relations
+
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := checkISBN(tx, ID, after.ISBN); err != nil {
+			return err
+		}
+		if err := patchRow(tx, "book", ID, after.Version, ch); err != nil {
+			return err
+		}
+		if !relations {
+			return nil
+		}
+		after.ID = ID
+		return setBookRelations(tx, after)
+	})
+}
+
+// Delete soft-deletes the book; a non-zero version makes the delete conditional.
+func (repo *BookRepo) Delete(ID uuid.UUID, version int64) error {
+	query := `
+		UPDATE book SET deleted_at = $2, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
+	`
+	res, err := repo.db.Exec(query, ID, time.Now(), version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, version)
+}
+
+// Restore undoes a soft delete. It fails with ErrDuplicateISBN when a live
+// book has taken the ISBN in the meantime.
+func (repo *BookRepo) Restore(ID uuid.UUID) error {
+	query := `UPDATE book SET deleted_at = NULL, updated_at = $2, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		var isbn *string
+		if err := tx.Get(&isbn, `SELECT isbn FROM book WHERE id = $1`, ID); err != nil {
+			return err
+		}
+		if err := checkISBN(tx, ID, isbn); err != nil {
+			return err
+		}
+		_, err := tx.Exec(query, ID, time.Now())
+		return err
+	})
+}
+
+// Purge permanently removes books soft-deleted before the given time.
+func (repo *BookRepo) Purge(before time.Time) (int64, error) {
+	res, err := repo.db.Exec(`DELETE FROM book WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// checkISBN refuses an ISBN that another live book already has.
+func checkISBN(tx *sqlx.Tx, id uuid.UUID, isbn *string) error {
+	if isbn == nil {
+		return nil
+	}
+	var taken bool
+	query := `SELECT EXISTS (SELECT 1 FROM book WHERE isbn = $1 AND id <> $2 AND deleted_at IS NULL)`
+	if err := tx.Get(&taken, query, *isbn, id); err != nil {
+		return err
+	}
+	if taken {
+		return ErrDuplicateISBN
+	}
+	return nil
 }
 
-func (repo *BookRepo) Delete(ID uuid.UUID) error {
-	query := `UPDATE book SET is_deleted = TRUE, updated_at = $2 WHERE id = $1`
-	_, err := repo.db.Exec(query, ID, time.Now())
-	return err
+// setBookRelations replaces the byline, genres and tags of b. Authors are
+// looked up by name and created on first use; without Authors the byline is
+// the single Author.
+func setBookRelations(tx *sqlx.Tx, b *model.Book) error {
+	if _, err := tx.Exec(`DELETE FROM book_authors WHERE book_id = $1`, b.ID); err != nil {
+		return err
+	}
+	authors := b.Authors
+	if len(authors) == 0 && strings.TrimSpace(b.Author) != "" {
+		authors = []string{strings.TrimSpace(b.Author)}
+	}
+	for i, name := range authors {
+		query := `
+			WITH ins AS (
+				INSERT INTO authors (id, name) VALUES ($2, $3)
+				ON CONFLICT ((lower(name))) DO NOTHING
+				RETURNING id
+			)
+			INSERT INTO book_authors (book_id, author_id, position)
+			SELECT $1, id, $4 FROM ins
+			UNION ALL
+			SELECT $1, id, $4 FROM authors WHERE lower(name) = lower($3)
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.Exec(query, b.ID, uuid.New(), name, i); err != nil {
+			return err
+		}
+	}
+
+	for _, terms := range []struct {
+		table string
+		names []string
+	}{{"book_genres", b.Genres}, {"book_tags", b.Tags}} {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE book_id = $1`, terms.table), b.ID); err != nil {
+			return err
+		}
+		for _, name := range terms.names {
+			query := fmt.Sprintf(`INSERT INTO %s (book_id, name) VALUES ($1, $2) ON CONFLICT DO NOTHING`, terms.table)
+			if _, err := tx.Exec(query, b.ID, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadBookRelations fills in the authors, genres and tags of the given books.
+func loadBookRelations(db *database.DB, books ...*model.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*model.Book, len(books))
+	ids := make([]uuid.UUID, len(books))
+	for i, b := range books {
+		b.Authors, b.Genres, b.Tags = []string{}, []string{}, []string{}
+		byID[b.ID] = b
+		ids[i] = b.ID
+	}
+
+	var names []struct {
+		BookID uuid.UUID `db:"book_id"`
+		Name   string    `db:"name"`
+	}
+	query, args, err := sqlx.In(`
+		SELECT ba.book_id, a.name FROM book_authors ba JOIN authors a ON a.id = ba.author_id
+		WHERE ba.book_id IN (?) ORDER BY ba.position
+	`, ids)
+	if err != nil {
+		return err
+	}
+	if err := db.Select(&names, db.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, n := range names {
+		byID[n.BookID].Authors = append(byID[n.BookID].Authors, n.Name)
+	}
+
+	for _, table := range []string{"book_genres", "book_tags"} {
+		names = names[:0]
+		query, args, err := sqlx.In(fmt.Sprintf(`SELECT book_id, name FROM %s WHERE book_id IN (?) ORDER BY name`, table), ids)
+		if err != nil {
+			return err
+		}
+		if err := db.Select(&names, db.Rebind(query), args...); err != nil {
+			return err
+		}
+		for _, n := range names {
+			b := byID[n.BookID]
+			if table == "book_genres" {
+				b.Genres = append(b.Genres, n.Name)
+			} else {
+				b.Tags = append(b.Tags, n.Name)
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type CommentRepo struct {
+	db          *database.DB
+	withDeleted bool
+}
+
+func NewCommentRepo(db *database.DB) CommentRepository {
+	return &CommentRepo{db: db}
+}
+
+// WithDeleted returns a copy of the repository whose reads include soft-deleted comments.
+func (repo *CommentRepo) WithDeleted() CommentRepository {
+	return &CommentRepo{db: repo.db, withDeleted: true}
+}
+
+// Create inserts the comment and its mentions.
+func (repo *CommentRepo) Create(cm *model.Comment) error {
+	query := `
+		INSERT INTO comments (id, task_id, project_id, author_user_id, body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+	`
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(query, cm.ID, cm.TaskID, cm.ProjectID, cm.AuthorID, cm.Body, time.Now().UTC()); err != nil {
+			return err
+		}
+		return setMentions(tx, cm)
+	})
+}
+
+func (repo *CommentRepo) Get(id uuid.UUID) (*model.Comment, error) {
+	cm := model.Comment{}
+	query := fmt.Sprintf(`SELECT * FROM comments WHERE id = $1 AND %s`, notDeleted(repo.withDeleted))
+	if err := repo.db.Get(&cm, query, id); err != nil {
+		return nil, err
+	}
+	return &cm, loadMentions(repo.db, &cm)
+}
+
+// AllByTask returns up to limit comments of the task, oldest first, after the cursor.
+func (repo *CommentRepo) AllByTask(taskID uuid.UUID, after *Cursor, limit int) ([]*model.Comment, error) {
+	return repo.thread("task_id", taskID, after, limit)
+}
+
+// AllByProject returns up to limit comments of the project, oldest first, after the cursor.
+func (repo *CommentRepo) AllByProject(projectID uuid.UUID, after *Cursor, limit int) ([]*model.Comment, error) {
+	return repo.thread("project_id", projectID, after, limit)
+}
+
+func (repo *CommentRepo) thread(column string, id uuid.UUID, after *Cursor, limit int) ([]*model.Comment, error) {
+	if after == nil {
+		after = &Cursor{}
+	}
+	query := fmt.Sprintf(`
+		SELECT * FROM comments
+		WHERE %s = $1 AND %s AND (created_at, id) > ($2, $3)
+		ORDER BY created_at, id
+		LIMIT $4
+	`, column, notDeleted(repo.withDeleted))
+
+	var out []*model.Comment
+	if err := repo.db.Select(&out, query, id, after.CreatedAt, after.ID, limit); err != nil {
+		return nil, err
+	}
+	return out, loadMentions(repo.db, out...)
+}
+
+// Update replaces the body, keeping the previous one as a revision edited by
+// editorID. When cm.Version is set, the write only applies to that version
+// of the row and fails with ErrVersionConflict otherwise.
+func (repo *CommentRepo) Update(id uuid.UUID, cm *model.Comment, editorID uuid.UUID) error {
+	query := `
+		WITH old AS (
+			SELECT id, body FROM comments
+			WHERE id = $1 AND deleted_at IS NULL AND ($4 = 0 OR version = $4)
+			FOR UPDATE
+		), rev AS (
+			INSERT INTO comment_revisions (comment_id, body, edited_by_user_id, created_at)
+			SELECT id, body, $3, $5 FROM old
+		)
+		UPDATE comments SET body = $2, edited_at = $5, updated_at = $5, version = version + 1
+		FROM old WHERE comments.id = old.id
+	`
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		res, err := tx.Exec(query, id, cm.Body, editorID, cm.Version, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(res, cm.Version); err != nil {
+			return err
+		}
+		cm.ID = id
+		return setMentions(tx, cm)
+	})
+}
+
+// Revisions returns the previous bodies of the comment, oldest first.
+func (repo *CommentRepo) Revisions(id uuid.UUID) ([]*model.CommentRevision, error) {
+	out := []*model.CommentRevision{}
+	err := repo.db.Select(&out, `SELECT * FROM comment_revisions WHERE comment_id = $1 ORDER BY id`, id)
+	return out, err
+}
+
+// Delete soft-deletes the comment; a non-zero version makes the delete conditional.
+func (repo *CommentRepo) Delete(id uuid.UUID, version int64) error {
+	query := `
+		UPDATE comments SET deleted_at = $2, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
+	`
+	res, err := repo.db.Exec(query, id, time.Now().UTC(), version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, version)
+}
+
+// Purge permanently removes comments soft-deleted before the given time.
+func (repo *CommentRepo) Purge(before time.Time) (int64, error) {
+	res, err := repo.db.Exec(`DELETE FROM comments WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func setMentions(tx *sqlx.Tx, cm *model.Comment) error {
+	if _, err := tx.Exec(`DELETE FROM comment_mentions WHERE comment_id = $1`, cm.ID); err != nil {
+		return err
+	}
+	for _, userID := range cm.Mentions {
+		if _, err := tx.Exec(`INSERT INTO comment_mentions (comment_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, cm.ID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadMentions(db *database.DB, comments ...*model.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*model.Comment, len(comments))
+	ids := make([]uuid.UUID, len(comments))
+	for i, cm := range comments {
+		cm.Mentions = []uuid.UUID{}
+		byID[cm.ID] = cm
+		ids[i] = cm.ID
+	}
+
+	var mentions []struct {
+		CommentID uuid.UUID `db:"comment_id"`
+		UserID    uuid.UUID `db:"user_id"`
+	}
+	query, args, err := sqlx.In(`SELECT comment_id, user_id FROM comment_mentions WHERE comment_id IN (?)`, ids)
+	if err != nil {
+		return err
+	}
+	if err := db.Select(&mentions, db.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, m := range mentions {
+		byID[m.CommentID].Mentions = append(byID[m.CommentID].Mentions, m.UserID)
+	}
+	return nil
+}
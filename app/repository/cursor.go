@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor marks a position in a listing ordered by (created_at, id). It is
+// opaque to clients.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+var errBadCursor = errors.New("invalid cursor")
+
+// String encodes the cursor for use in a query string.
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor produced by Cursor.String. An empty string
+// yields nil, the start of the listing.
+func ParseCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errBadCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	return &Cursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: uid}, nil
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	in := Cursor{CreatedAt: time.Date(2026, 10, 19, 9, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+
+	out, err := ParseCursor(in.String())
+	require.NoError(t, err)
+	assert.Equal(t, in, *out)
+
+	out, err = ParseCursor("")
+	assert.NoError(t, err)
+	assert.Nil(t, out)
+
+	for _, bad := range []string{"!!", "bm90LWEtY3Vyc29y", "MTIzOm5vdC1hLXV1aWQ"} {
+		_, err := ParseCursor(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+)
+
+// ErrVersionConflict is returned by conditional writes when the stored row
+// no longer has the version the caller based its change on.
+var ErrVersionConflict = errors.New("resource was modified by someone else")
+
+// ErrCycle is returned for a parent or dependency link that would make a
+// task (indirectly) depend on itself.
+var ErrCycle = errors.New("link would create a cycle")
+
+// checkVersion turns "no row written" into ErrVersionConflict for writes
+// conditioned on a version. Unconditional writes (version 0) pass through.
+func checkVersion(res sql.Result, version int64) error {
+	if version == 0 {
+		return nil
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// BlockedError refuses to finish a task whose blockers are still open.
+type BlockedError struct {
+	Blockers []*model.Task
+}
+
+func (e *BlockedError) Error() string {
+	return "task has unfinished blockers"
+}
+
+// ErrBadNeighbour is returned for a board move next to a task that is not
+// in the target column, or between two tasks that are not adjacent.
+var ErrBadNeighbour = errors.New("neighbour is not in the target column")
+
+// ErrTimerRunning is returned when a user starts a timer while another one
+// of theirs is still running.
+var ErrTimerRunning = errors.New("another timer is running")
+
+// ErrDuplicateISBN is returned when a book gets an ISBN that another live
+// book already has.
+var ErrDuplicateISBN = errors.New("another book has this ISBN")
+
+// ErrNoCopyAvailable is returned when a checkout finds no copy of the book
+// that may be lent: all are out or held for the waitlist.
+var ErrNoCopyAvailable = errors.New("no copy of the book is available")
+
+// ErrAlreadyBorrowed is returned when a user checks out or reserves a book
+// they have on loan.
+var ErrAlreadyBorrowed = errors.New("user has this book on loan already")
+
+// ErrAlreadyReserved is returned when a user joins the waitlist of a book
+// twice.
+var ErrAlreadyReserved = errors.New("user is on the waitlist already")
+
+// ErrCopyOnLoan is returned when a copy that is lent out is withdrawn.
+var ErrCopyOnLoan = errors.New("copy is on loan")
+
+// ErrAlreadyReviewed is returned when a user reviews a book a second time.
+var ErrAlreadyReviewed = errors.New("user has reviewed this book already")
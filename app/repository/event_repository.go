@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+)
+
+// EventFilter selects the outbox events of the projects a user can see:
+// those they own or have a task assigned in, or every project with All.
+type EventFilter struct {
+	UserID    uuid.UUID
+	All       bool
+	ProjectID uuid.UUID // one project only, when set
+}
+
+// EventRepo reads the outbox events of projects and tasks for the live
+// event streams.
+type EventRepo struct {
+	db *database.DB
+}
+
+func NewEventRepo(db *database.DB) EventRepository {
+	return &EventRepo{db: db}
+}
+
+// LastID returns the id of the latest event, 0 without events.
+func (repo *EventRepo) LastID() (int64, error) {
+	var id int64
+	err := repo.db.Get(&id, `SELECT coalesce(max(id), 0) FROM outbox_events`)
+	return id, err
+}
+
+// After lists up to limit events matching f with ids above after, in id
+// order.
+func (repo *EventRepo) After(f EventFilter, after int64, limit int) ([]*model.WebhookEvent, error) {
+	where, args := f.where()
+	args = append(args, after, limit)
+	query := fmt.Sprintf(`SELECT * FROM outbox_events e WHERE %s AND e.id > $%d ORDER BY e.id LIMIT $%d`,
+		where, len(args)-1, len(args))
+
+	var out []*model.WebhookEvent
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (f EventFilter) where() (string, []interface{}) {
+	where := []string{"e.project_id IS NOT NULL"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.ProjectID != uuid.Nil {
+		where = append(where, "e.project_id = "+arg(f.ProjectID))
+	}
+	if !f.All {
+		user := arg(f.UserID)
+		where = append(where, `e.project_id IN (
+			SELECT id FROM projects WHERE owner_user_id = `+user+`
+			UNION
+			SELECT t.project_id FROM tasks t JOIN task_assignees a ON a.task_id = t.id WHERE a.user_id = `+user+`
+		)`)
+	}
+	return strings.Join(where, " AND "), args
+}
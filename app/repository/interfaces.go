@@ -1,41 +1,197 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/byeblogs/go-boilerplate/app/model"
 	"github.com/google/uuid"
 )
 
 type UserRepository interface {
 	Create(u *model.User) error
+	Upsert(u *model.User) error
 	All(limit int, offset uint) ([]*model.User, error)
+	Each(size int, fn func([]*model.User) error) error
 	Get(id uuid.UUID) (*model.User, error)
 	GetByUsername(username string) (*model.User, error)
 	Update(id uuid.UUID, u *model.User) error
-	Delete(id uuid.UUID) error
+	Patch(id uuid.UUID, before, after *model.User) error
+	Delete(id uuid.UUID, version int64) error
+	Restore(id uuid.UUID) error
+	Purge(before time.Time) (int64, error)
+	WithDeleted() UserRepository
 }
 
 type BookRepository interface {
 	Create(b *model.Book) error
+	Upsert(b *model.Book) error
 	All(limit int, offset uint) ([]*model.Book, error)
+	Find(f BookFilter, limit int, offset uint) ([]*model.Book, error)
+	Each(f BookFilter, size int, fn func([]*model.Book) error) error
 	Get(ID uuid.UUID) (*model.Book, error)
+	GetByISBN(isbn string) (*model.Book, error)
+	GetByTitleAuthor(title, author string) (*model.Book, error)
 	Update(ID uuid.UUID, b *model.Book) error
-	Delete(ID uuid.UUID) error
+	Patch(ID uuid.UUID, before, after *model.Book) error
+	Delete(ID uuid.UUID, version int64) error
+	Restore(ID uuid.UUID) error
+	Purge(before time.Time) (int64, error)
+	WithDeleted() BookRepository
+}
+
+type LendingRepository interface {
+	AddCopy(c *model.BookCopy) error
+	GetCopy(id uuid.UUID) (*model.BookCopy, error)
+	Copies(bookID uuid.UUID) ([]*model.BookCopy, error)
+	WithdrawCopy(c *model.BookCopy, now time.Time) error
+	Checkout(l *model.Loan) error
+	Return(id uuid.UUID, now time.Time) error
+	GetLoan(id uuid.UUID) (*model.Loan, error)
+	FindLoans(f LoanFilter, limit int, offset uint) ([]*model.Loan, error)
+	Reserve(r *model.Reservation) error
+	CancelReservation(id uuid.UUID, now time.Time) error
+	GetReservation(id uuid.UUID) (*model.Reservation, error)
+	FindReservations(f ReservationFilter) ([]*model.Reservation, error)
+	ExpireHolds(now time.Time) (int, error)
+}
+
+type ReviewRepository interface {
+	Create(r *model.BookReview) error
+	Get(id uuid.UUID) (*model.BookReview, error)
+	Find(bookID uuid.UUID, limit int, offset uint) ([]*model.BookReview, error)
+	Update(r *model.BookReview) error
+	Delete(id uuid.UUID, version int64) error
 }
 
 type ProjectRepository interface {
 	Create(p *model.Project) error
+	Upsert(p *model.Project) error
 	All(limit int, offset uint) ([]*model.Project, error)
 	AllByOwner(ownerID uuid.UUID, limit int, offset uint) ([]*model.Project, error)
+	Find(f ProjectFilter, limit int, offset uint) ([]*model.Project, error)
+	Each(f ProjectFilter, size int, fn func([]*model.Project) error) error
 	Get(id uuid.UUID) (*model.Project, error)
 	Update(id uuid.UUID, p *model.Project) error
-	Delete(id uuid.UUID) error
+	Patch(id uuid.UUID, before, after *model.Project) error
+	Archive(id uuid.UUID, version int64) error
+	Unarchive(id uuid.UUID, version int64) error
+	Clone(src uuid.UUID, dst *model.Project, opts *model.CloneProject) (int, error)
+	Delete(id uuid.UUID, version int64) error
+	Restore(id uuid.UUID) error
+	Purge(before time.Time) (int64, error)
+	WithDeleted() ProjectRepository
 }
 
 type TaskRepository interface {
 	Create(t *model.Task) error
+	Upsert(t *model.Task) error
 	All(limit int, offset uint) ([]*model.Task, error)
 	AllByProject(projectID uuid.UUID, limit int, offset uint) ([]*model.Task, error)
+	Find(f TaskFilter, limit int, offset uint) ([]*model.Task, error)
+	Each(f TaskFilter, size int, fn func([]*model.Task) error) error
+	Tree(id uuid.UUID) ([]*model.Task, error)
+	Blockers(id uuid.UUID) ([]*model.Task, error)
+	Blocking(id uuid.UUID) ([]*model.Task, error)
+	AddDependency(id, blockedBy uuid.UUID) error
+	RemoveDependency(id, blockedBy uuid.UUID) error
 	Get(id uuid.UUID) (*model.Task, error)
 	Update(id uuid.UUID, t *model.Task) error
+	Patch(id uuid.UUID, before, after *model.Task) error
+	Move(id uuid.UUID, t *model.Task, after, before *uuid.UUID) error
+	Delete(id uuid.UUID, version int64) error
+	Restore(id uuid.UUID) error
+	Purge(before time.Time) (int64, error)
+	WithDeleted() TaskRepository
+}
+
+type LabelRepository interface {
+	Create(l *model.Label) error
+	AllByProject(projectID uuid.UUID) ([]*model.Label, error)
+	Get(id uuid.UUID) (*model.Label, error)
 	Delete(id uuid.UUID) error
 }
+
+type CommentRepository interface {
+	Create(cm *model.Comment) error
+	Get(id uuid.UUID) (*model.Comment, error)
+	AllByTask(taskID uuid.UUID, after *Cursor, limit int) ([]*model.Comment, error)
+	AllByProject(projectID uuid.UUID, after *Cursor, limit int) ([]*model.Comment, error)
+	Update(id uuid.UUID, cm *model.Comment, editorID uuid.UUID) error
+	Revisions(id uuid.UUID) ([]*model.CommentRevision, error)
+	Delete(id uuid.UUID, version int64) error
+	Purge(before time.Time) (int64, error)
+	WithDeleted() CommentRepository
+}
+
+type AttachmentRepository interface {
+	Create(a *model.Attachment) error
+	Get(id uuid.UUID) (*model.Attachment, error)
+	AllByTask(taskID uuid.UUID) ([]*model.Attachment, error)
+	Cover(bookID uuid.UUID) (*model.Attachment, error)
+	Delete(id uuid.UUID) error
+	Purge(before time.Time) (int64, error)
+	PurgeBlobs(before time.Time, remove func(sha256 string) error) (int64, error)
+	WithDeleted() AttachmentRepository
+}
+
+type RecurrenceRepository interface {
+	Create(r *model.TaskRecurrence) error
+	Get(id uuid.UUID) (*model.TaskRecurrence, error)
+	Update(r *model.TaskRecurrence) error
+	Delete(id uuid.UUID) error
+	IDs() ([]uuid.UUID, error)
+	Advance(id uuid.UUID, upTo time.Time, ensureOpen bool) ([]*model.Task, error)
+}
+
+type ReminderRepository interface {
+	Preferences(userID uuid.UUID) (*model.NotificationPreferences, error)
+	SetPreferences(p *model.NotificationPreferences) error
+	Due(now time.Time, lead time.Duration, channel string, limit int) ([]*model.Reminder, error)
+	Claim(r *model.Reminder) (bool, error)
+	Release(r *model.Reminder) error
+}
+
+type ReportRepository interface {
+	ProjectStats(projectID uuid.UUID, now time.Time) (*model.ProjectStats, error)
+	Throughput(f ThroughputFilter) ([]*model.Throughput, error)
+	MaterializedThroughput(f ThroughputFilter) ([]*model.Throughput, error)
+	RefreshThroughput() error
+}
+
+type TimeEntryRepository interface {
+	Create(e *model.TimeEntry) error
+	Get(id uuid.UUID) (*model.TimeEntry, error)
+	Find(f TimeEntryFilter, limit int, offset uint) ([]*model.TimeEntry, error)
+	Update(e *model.TimeEntry) error
+	Delete(id uuid.UUID, version int64) error
+	Running(userID uuid.UUID) (*model.TimeEntry, error)
+	Start(e *model.TimeEntry) error
+	Stop(userID uuid.UUID, now time.Time) (uuid.UUID, error)
+	Summary(group model.TimeGroup, f TimeEntryFilter) ([]*model.TimeSummary, error)
+}
+
+type AuditRepository interface {
+	Find(f AuditFilter, limit int, offset uint) ([]*model.AuditEvent, error)
+	Get(id int64) (*model.AuditEvent, error)
+}
+
+type WebhookRepository interface {
+	CreateSubscription(s *model.WebhookSubscription) error
+	GetSubscription(id uuid.UUID) (*model.WebhookSubscription, error)
+	Subscriptions(limit int, offset uint) ([]*model.WebhookSubscription, error)
+	UpdateSubscription(s *model.WebhookSubscription) error
+	DeleteSubscription(id uuid.UUID, version int64) error
+	GetEvent(id int64) (*model.WebhookEvent, error)
+	Fanout(limit int) (int, error)
+	Due(now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error)
+	RecordAttempt(d *model.WebhookDelivery) error
+	GetDelivery(id uuid.UUID) (*model.WebhookDelivery, error)
+	Deliveries(f DeliveryFilter, limit int, offset uint) ([]*model.WebhookDelivery, error)
+	Replay(id uuid.UUID) error
+	ReplayAll(f DeliveryFilter, since time.Time) (int64, error)
+}
+
+type EventRepository interface {
+	LastID() (int64, error)
+	After(f EventFilter, after int64, limit int) ([]*model.WebhookEvent, error)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+)
+
+type LabelRepo struct {
+	db *database.DB
+}
+
+func NewLabelRepo(db *database.DB) LabelRepository {
+	return &LabelRepo{db: db}
+}
+
+// Create defines a label, or updates the color of the project's label with
+// the same name.
+func (repo *LabelRepo) Create(l *model.Label) error {
+	query := `
+		INSERT INTO labels (id, project_id, name, color, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id, name) DO UPDATE SET color = EXCLUDED.color
+		RETURNING id
+	`
+	return repo.db.Get(&l.ID, query, l.ID, l.ProjectID, l.Name, l.Color, time.Now().UTC())
+}
+
+func (repo *LabelRepo) AllByProject(projectID uuid.UUID) ([]*model.Label, error) {
+	var out []*model.Label
+	err := repo.db.Select(&out, `SELECT * FROM labels WHERE project_id = $1 ORDER BY name`, projectID)
+	return out, err
+}
+
+func (repo *LabelRepo) Get(id uuid.UUID) (*model.Label, error) {
+	l := model.Label{}
+	if err := repo.db.Get(&l, `SELECT * FROM labels WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Delete removes the label from the project and from its tasks.
+func (repo *LabelRepo) Delete(id uuid.UUID) error {
+	_, err := repo.db.Exec(`DELETE FROM labels WHERE id = $1`, id)
+	return err
+}
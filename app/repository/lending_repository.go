@@ -0,0 +1,419 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Every change to the copies, loans or waitlist of a book runs in a
+// transaction holding the book's lending lock, so availability is decided
+// by one writer at a time. The unique index on open loans backs this up: a
+// copy is never lent twice.
+
+// copySelect reads copies with their open loan.
+const copySelect = `
+	SELECT c.*, l.id AS loan_id
+	FROM book_copies c LEFT JOIN loans l ON l.copy_id = c.id AND l.returned_at IS NULL
+`
+
+// loanSelect reads loans with the book's title and whether they are overdue.
+const loanSelect = `
+	SELECT l.*, b.title AS book_title, (l.returned_at IS NULL AND l.due_at < now()) AS overdue
+	FROM loans l JOIN book b ON b.id = l.book_id
+`
+
+// reservationSelect reads reservations with their place in the queue.
+const reservationSelect = `
+	SELECT r.*, CASE WHEN r.status IN ('waiting', 'ready') THEN (
+		SELECT count(*) FROM reservations q
+		WHERE q.book_id = r.book_id AND q.status IN ('waiting', 'ready') AND (q.created_at, q.id) <= (r.created_at, r.id)
+	)::int END AS position
+	FROM reservations r
+`
+
+// LoanFilter narrows down loans; zero fields don't filter.
+type LoanFilter struct {
+	UserID  uuid.UUID
+	BookID  uuid.UUID
+	Open    bool // not returned yet
+	Overdue bool // open and past due
+}
+
+// ReservationFilter narrows down reservations; zero fields don't filter.
+type ReservationFilter struct {
+	UserID uuid.UUID
+	BookID uuid.UUID
+	Open   bool // waiting or ready
+}
+
+type LendingRepo struct {
+	db *database.DB
+	// hold is how long a ready reservation keeps a copy for its user.
+	hold time.Duration
+}
+
+func NewLendingRepo(db *database.DB, hold time.Duration) LendingRepository {
+	return &LendingRepo{db: db, hold: hold}
+}
+
+// AddCopy adds a copy to its book, which may serve the next reservation.
+func (repo *LendingRepo) AddCopy(c *model.BookCopy) error {
+	query := `
+		INSERT INTO book_copies (id, book_id, barcode, note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`
+	now := time.Now().UTC()
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := lockLending(tx, c.BookID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, c.ID, c.BookID, c.Barcode, c.Note, now); err != nil {
+			return err
+		}
+		return repo.promote(tx, c.BookID, now)
+	})
+}
+
+func (repo *LendingRepo) GetCopy(id uuid.UUID) (*model.BookCopy, error) {
+	c := model.BookCopy{}
+	if err := repo.db.Get(&c, copySelect+` WHERE c.id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Copies lists the copies of a book that haven't been withdrawn.
+func (repo *LendingRepo) Copies(bookID uuid.UUID) ([]*model.BookCopy, error) {
+	var out []*model.BookCopy
+	query := copySelect + ` WHERE c.book_id = $1 AND c.withdrawn_at IS NULL ORDER BY c.created_at, c.id`
+	if err := repo.db.Select(&out, query, bookID); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WithdrawCopy takes a copy out of circulation. It fails with ErrCopyOnLoan
+// while the copy is lent out.
+func (repo *LendingRepo) WithdrawCopy(c *model.BookCopy, now time.Time) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := lockLending(tx, c.BookID); err != nil {
+			return err
+		}
+		var lent bool
+		if err := tx.Get(&lent, `SELECT EXISTS (SELECT 1 FROM loans WHERE copy_id = $1 AND returned_at IS NULL)`, c.ID); err != nil {
+			return err
+		}
+		if lent {
+			return ErrCopyOnLoan
+		}
+		_, err := tx.Exec(`UPDATE book_copies SET withdrawn_at = $2, updated_at = $2 WHERE id = $1 AND withdrawn_at IS NULL`, c.ID, now)
+		return err
+	})
+}
+
+// Checkout lends a copy of l.BookID to l.UserID from l.CheckedOutAt until
+// l.DueAt, and sets l.CopyID to it. A copy held by a ready reservation goes
+// to the reservation's user; everyone else can only take copies no
+// reservation is waiting for, so the waitlist is served in order. A set
+// l.CopyID asks for that copy. Checkout fails with ErrNoCopyAvailable when
+// no copy may be lent, and with ErrAlreadyBorrowed when the user has the
+// book already.
+func (repo *LendingRepo) Checkout(l *model.Loan) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := lockLending(tx, l.BookID); err != nil {
+			return err
+		}
+		if err := repo.promote(tx, l.BookID, l.CheckedOutAt); err != nil {
+			return err
+		}
+
+		var borrowed bool
+		query := `SELECT EXISTS (SELECT 1 FROM loans WHERE book_id = $1 AND user_id = $2 AND returned_at IS NULL)`
+		if err := tx.Get(&borrowed, query, l.BookID, l.UserID); err != nil {
+			return err
+		}
+		if borrowed {
+			return ErrAlreadyBorrowed
+		}
+
+		var held *uuid.UUID
+		query = `SELECT id FROM reservations WHERE book_id = $1 AND user_id = $2 AND status = 'ready'`
+		if err := tx.Get(&held, query, l.BookID, l.UserID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if held == nil {
+			var spare int
+			query := `
+				SELECT (SELECT count(*) FROM book_copies c WHERE c.book_id = $1 AND c.withdrawn_at IS NULL
+						AND NOT EXISTS (SELECT 1 FROM loans WHERE copy_id = c.id AND returned_at IS NULL))
+					- (SELECT count(*) FROM reservations WHERE book_id = $1 AND status = 'ready')
+			`
+			if err := tx.Get(&spare, query, l.BookID); err != nil {
+				return err
+			}
+			if spare <= 0 {
+				return ErrNoCopyAvailable
+			}
+		}
+
+		query = `
+			SELECT c.id FROM book_copies c
+			WHERE c.book_id = $1 AND c.withdrawn_at IS NULL AND ($2::uuid IS NULL OR c.id = $2)
+				AND NOT EXISTS (SELECT 1 FROM loans WHERE copy_id = c.id AND returned_at IS NULL)
+			ORDER BY c.created_at, c.id
+			LIMIT 1
+		`
+		var want *uuid.UUID
+		if l.CopyID != uuid.Nil {
+			want = &l.CopyID
+		}
+		if err := tx.Get(&l.CopyID, query, l.BookID, want); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoCopyAvailable
+			}
+			return err
+		}
+
+		query = `
+			INSERT INTO loans (id, copy_id, book_id, user_id, checked_out_at, due_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $5, $5)
+		`
+		if _, err := tx.Exec(query, l.ID, l.CopyID, l.BookID, l.UserID, l.CheckedOutAt, l.DueAt); err != nil {
+			return err
+		}
+		if held != nil {
+			query := `UPDATE reservations SET status = 'fulfilled', closed_at = $2, updated_at = $2 WHERE id = $1`
+			if _, err := tx.Exec(query, *held, l.CheckedOutAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Return closes the loan and hands its copy to the next reservation, if
+// any. It fails with sql.ErrNoRows when the loan was returned already.
+func (repo *LendingRepo) Return(id uuid.UUID, now time.Time) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		var bookID uuid.UUID
+		if err := tx.Get(&bookID, `SELECT book_id FROM loans WHERE id = $1`, id); err != nil {
+			return err
+		}
+		if err := lockLending(tx, bookID); err != nil {
+			return err
+		}
+		query := `
+			UPDATE loans SET returned_at = GREATEST($2, checked_out_at), updated_at = $2
+			WHERE id = $1 AND returned_at IS NULL
+			RETURNING id
+		`
+		if err := tx.Get(&id, query, id, now); err != nil {
+			return err
+		}
+		return repo.promote(tx, bookID, now)
+	})
+}
+
+func (repo *LendingRepo) GetLoan(id uuid.UUID) (*model.Loan, error) {
+	l := model.Loan{}
+	if err := repo.db.Get(&l, loanSelect+` WHERE l.id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// FindLoans lists the loans matching the filter, latest first.
+func (repo *LendingRepo) FindLoans(f LoanFilter, limit int, offset uint) ([]*model.Loan, error) {
+	where := []string{"TRUE"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.UserID != uuid.Nil {
+		where = append(where, "l.user_id = "+arg(f.UserID))
+	}
+	if f.BookID != uuid.Nil {
+		where = append(where, "l.book_id = "+arg(f.BookID))
+	}
+	if f.Open || f.Overdue {
+		where = append(where, "l.returned_at IS NULL")
+	}
+	if f.Overdue {
+		where = append(where, "l.due_at < now()")
+	}
+
+	query := fmt.Sprintf(`%s WHERE %s ORDER BY l.checked_out_at DESC, l.id`, loanSelect, strings.Join(where, " AND "))
+	if limit > 0 {
+		query = fmt.Sprintf("%s LIMIT %s OFFSET %s", query, arg(limit), arg(offset))
+	}
+
+	var out []*model.Loan
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Reserve puts r.UserID at the end of the waitlist of r.BookID. If a copy
+// is free the reservation is ready right away. It fails with
+// ErrAlreadyReserved or ErrAlreadyBorrowed when the user is queued for, or
+// has, the book.
+func (repo *LendingRepo) Reserve(r *model.Reservation) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := lockLending(tx, r.BookID); err != nil {
+			return err
+		}
+
+		var borrowed, queued bool
+		query := `
+			SELECT EXISTS (SELECT 1 FROM loans WHERE book_id = $1 AND user_id = $2 AND returned_at IS NULL),
+				EXISTS (SELECT 1 FROM reservations WHERE book_id = $1 AND user_id = $2 AND status IN ('waiting', 'ready'))
+		`
+		if err := tx.QueryRowx(query, r.BookID, r.UserID).Scan(&borrowed, &queued); err != nil {
+			return err
+		}
+		if borrowed {
+			return ErrAlreadyBorrowed
+		}
+		if queued {
+			return ErrAlreadyReserved
+		}
+
+		query = `
+			INSERT INTO reservations (id, book_id, user_id, status, created_at, updated_at)
+			VALUES ($1, $2, $3, 'waiting', $4, $4)
+		`
+		if _, err := tx.Exec(query, r.ID, r.BookID, r.UserID, r.CreatedAt); err != nil {
+			return err
+		}
+		return repo.promote(tx, r.BookID, r.CreatedAt)
+	})
+}
+
+// CancelReservation leaves the waitlist; a copy the reservation held goes
+// to the next one. It fails with sql.ErrNoRows when the reservation is
+// closed already.
+func (repo *LendingRepo) CancelReservation(id uuid.UUID, now time.Time) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		var bookID uuid.UUID
+		if err := tx.Get(&bookID, `SELECT book_id FROM reservations WHERE id = $1`, id); err != nil {
+			return err
+		}
+		if err := lockLending(tx, bookID); err != nil {
+			return err
+		}
+		query := `
+			UPDATE reservations SET status = 'cancelled', closed_at = $2, updated_at = $2
+			WHERE id = $1 AND status IN ('waiting', 'ready')
+			RETURNING id
+		`
+		if err := tx.Get(&id, query, id, now); err != nil {
+			return err
+		}
+		return repo.promote(tx, bookID, now)
+	})
+}
+
+func (repo *LendingRepo) GetReservation(id uuid.UUID) (*model.Reservation, error) {
+	r := model.Reservation{}
+	if err := repo.db.Get(&r, reservationSelect+` WHERE r.id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// FindReservations lists the reservations matching the filter in queue
+// order, oldest first.
+func (repo *LendingRepo) FindReservations(f ReservationFilter) ([]*model.Reservation, error) {
+	where := []string{"TRUE"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.UserID != uuid.Nil {
+		where = append(where, "r.user_id = "+arg(f.UserID))
+	}
+	if f.BookID != uuid.Nil {
+		where = append(where, "r.book_id = "+arg(f.BookID))
+	}
+	if f.Open {
+		where = append(where, "r.status IN ('waiting', 'ready')")
+	}
+
+	var out []*model.Reservation
+	query := fmt.Sprintf(`%s WHERE %s ORDER BY r.created_at, r.id`, reservationSelect, strings.Join(where, " AND "))
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExpireHolds ends the ready reservations whose hold ran out and passes
+// their copies down the waitlists. It returns how many books it updated.
+func (repo *LendingRepo) ExpireHolds(now time.Time) (int, error) {
+	var books []uuid.UUID
+	query := `SELECT DISTINCT book_id FROM reservations WHERE status = 'ready' AND expires_at <= $1`
+	if err := repo.db.Select(&books, query, now); err != nil {
+		return 0, err
+	}
+	for i, bookID := range books {
+		err := repo.db.InTx(func(tx *sqlx.Tx) error {
+			if err := lockLending(tx, bookID); err != nil {
+				return err
+			}
+			return repo.promote(tx, bookID, now)
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(books), nil
+}
+
+// promote expires the book's stale holds and makes the oldest waiting
+// reservations ready, one for every copy on the shelf that isn't held yet.
+// The caller holds the book's lending lock.
+func (repo *LendingRepo) promote(tx *sqlx.Tx, bookID uuid.UUID, now time.Time) error {
+	query := `
+		UPDATE reservations SET status = 'expired', closed_at = $2, updated_at = $2
+		WHERE book_id = $1 AND status = 'ready' AND expires_at <= $2
+	`
+	if _, err := tx.Exec(query, bookID, now); err != nil {
+		return err
+	}
+
+	query = `
+		WITH next AS (
+			SELECT id FROM reservations
+			WHERE book_id = $1 AND status = 'waiting'
+			ORDER BY created_at, id
+			LIMIT GREATEST(
+				(SELECT count(*) FROM book_copies c WHERE c.book_id = $1 AND c.withdrawn_at IS NULL
+					AND NOT EXISTS (SELECT 1 FROM loans WHERE copy_id = c.id AND returned_at IS NULL))
+				- (SELECT count(*) FROM reservations WHERE book_id = $1 AND status = 'ready'),
+				0)
+		)
+		UPDATE reservations SET status = 'ready', ready_at = $2, expires_at = $3, updated_at = $2
+		WHERE id IN (SELECT id FROM next)
+	`
+	_, err := tx.Exec(query, bookID, now, now.Add(repo.hold))
+	return err
+}
+
+// lockLending serializes changes to the lending state of a book.
+func lockLending(tx *sqlx.Tx, bookID uuid.UUID) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('book_lending'), hashtext($1::text))`, bookID)
+	return err
+}
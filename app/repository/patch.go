@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// changes lists the columns whose values differ between two copies of a
+// model, in the order they were asked for. Values are compared through their
+// JSON encoding, so a timestamp that only changed location is not a change.
+type changes struct {
+	columns []string
+	values  []interface{}
+	// touch bumps updated_at and the version even without column changes,
+	// for rows whose related records changed.
+	touch bool
+}
+
+func diff(before, after interface{}, columns ...string) changes {
+	b, a := reflect.Indirect(reflect.ValueOf(before)), reflect.Indirect(reflect.ValueOf(after))
+	out := changes{}
+
+	for _, col := range columns {
+		i, ok := fieldByColumn(b.Type(), col)
+		if !ok {
+			panic(fmt.Sprintf("repository: %s has no column %q", b.Type(), col))
+		}
+		if !sameJSON(b.Field(i).Interface(), a.Field(i).Interface()) {
+			out.columns = append(out.columns, col)
+			out.values = append(out.values, a.Field(i).Interface())
+		}
+	}
+	return out
+}
+
+// sameJSON reports whether two values have the same JSON encoding.
+func sameJSON(x, y interface{}) bool {
+	a, _ := json.Marshal(x)
+	b, _ := json.Marshal(y)
+	return bytes.Equal(a, b)
+}
+
+func fieldByColumn(t reflect.Type, col string) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("db"), ",")[0] == col {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// patchRow writes only the changed columns of a live row. Like Update, a
+// non-zero version makes the write conditional. Nothing is written, and the
+// version is not bumped, when there are no changes.
+func patchRow(db sqlx.Execer, table string, id uuid.UUID, version int64, ch changes) error {
+	if len(ch.columns) == 0 && !ch.touch {
+		return nil
+	}
+
+	set := make([]string, len(ch.columns), len(ch.columns)+1)
+	args := []interface{}{id, version, time.Now().UTC()}
+	for i, col := range ch.columns {
+		set[i] = fmt.Sprintf("%s = $%d", col, i+4)
+		args = append(args, ch.values[i])
+	}
+	set = append(set, "updated_at = $3")
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET %s, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+	`, table, strings.Join(set, ", "))
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, version)
+}
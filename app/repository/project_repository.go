@@ -2,80 +2,344 @@ package repository
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/byeblogs/go-boilerplate/app/model"
 	"github.com/byeblogs/go-boilerplate/platform/database"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 type ProjectRepo struct {
-	db *database.DB
+	db          *database.DB
+	withDeleted bool
 }
 
 func NewProjectRepo(db *database.DB) ProjectRepository {
-	return &ProjectRepo{db}
+	return &ProjectRepo{db: db}
+}
+
+// WithDeleted returns a copy of the repository whose reads include soft-deleted projects.
+func (repo *ProjectRepo) WithDeleted() ProjectRepository {
+	return &ProjectRepo{db: repo.db, withDeleted: true}
 }
 
 func (repo *ProjectRepo) Create(p *model.Project) error {
+	return insertProject(repo.db, p, time.Now().UTC())
+}
+
+func insertProject(db sqlx.Execer, p *model.Project, now time.Time) error {
+	query := `
+		INSERT INTO projects (id, owner_user_id, name, description, is_template, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+	`
+	_, err := db.Exec(query, p.ID, p.OwnerUserID, p.Name, p.Description, p.IsTemplate, now)
+	return err
+}
+
+// Upsert inserts the project or overwrites the row with the same id.
+func (repo *ProjectRepo) Upsert(p *model.Project) error {
 	query := `
-		INSERT INTO projects (id, owner_user_id, name, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO projects (id, owner_user_id, name, description, is_template, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE
+		SET owner_user_id = EXCLUDED.owner_user_id, name = EXCLUDED.name,
+			description = EXCLUDED.description, is_template = EXCLUDED.is_template,
+			updated_at = EXCLUDED.updated_at, deleted_at = NULL, version = projects.version + 1
 	`
 	now := time.Now().UTC()
-	_, err := repo.db.Exec(query, p.ID, p.OwnerUserID, p.Name, p.Description, now, now)
+	_, err := repo.db.Exec(query, p.ID, p.OwnerUserID, p.Name, p.Description, p.IsTemplate, now, now)
 	return err
 }
 
+// ProjectFilter narrows a project listing. The zero value lists live,
+// unarchived projects that aren't templates.
+type ProjectFilter struct {
+	OwnerID   uuid.UUID
+	Archived  ArchivedFilter
+	Templates bool // list templates instead of projects
+}
+
+// ArchivedFilter says what a listing does with archived projects.
+type ArchivedFilter int
+
+const (
+	ExcludeArchived ArchivedFilter = iota
+	IncludeArchived
+	OnlyArchived
+)
+
 func (repo *ProjectRepo) All(limit int, offset uint) ([]*model.Project, error) {
-	var out []*model.Project
-	query := `SELECT * FROM projects ORDER BY created_at DESC`
-	var err error
+	return repo.Find(ProjectFilter{}, limit, offset)
+}
+
+func (repo *ProjectRepo) AllByOwner(ownerID uuid.UUID, limit int, offset uint) ([]*model.Project, error) {
+	return repo.Find(ProjectFilter{OwnerID: ownerID}, limit, offset)
+}
 
+// Find lists the projects matching f, newest first.
+func (repo *ProjectRepo) Find(f ProjectFilter, limit int, offset uint) ([]*model.Project, error) {
+	query, args := f.query(repo.withDeleted)
 	if limit > 0 {
-		query = fmt.Sprintf("%s LIMIT $1 OFFSET $2", query)
-		err = repo.db.Select(&out, query, limit, offset)
-	} else {
-		err = repo.db.Select(&out, query)
+		query = fmt.Sprintf("%s LIMIT $%d OFFSET $%d", query, len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
 	}
+
+	var out []*model.Project
+	err := repo.db.Select(&out, query, args...)
 	return out, err
 }
 
-func (repo *ProjectRepo) AllByOwner(ownerID uuid.UUID, limit int, offset uint) ([]*model.Project, error) {
-	var out []*model.Project
-	query := `SELECT * FROM projects WHERE owner_user_id = $1 ORDER BY created_at DESC`
-	var err error
+// Each calls fn with the projects Find would list, in the same order, in
+// batches of at most size projects read from a database cursor.
+func (repo *ProjectRepo) Each(f ProjectFilter, size int, fn func([]*model.Project) error) error {
+	query, args := f.query(repo.withDeleted)
+	return eachBatch(repo.db, size, query, args, fn)
+}
 
-	if limit > 0 {
-		query = fmt.Sprintf("%s LIMIT $2 OFFSET $3", query)
-		err = repo.db.Select(&out, query, ownerID, limit, offset)
-	} else {
-		err = repo.db.Select(&out, query, ownerID)
+func (f ProjectFilter) query(withDeleted bool) (string, []interface{}) {
+	where := []string{notDeleted(withDeleted)}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
-	return out, err
+
+	if f.OwnerID != uuid.Nil {
+		where = append(where, "owner_user_id = "+arg(f.OwnerID))
+	}
+	switch f.Archived {
+	case ExcludeArchived:
+		where = append(where, "archived_at IS NULL")
+	case OnlyArchived:
+		where = append(where, "archived_at IS NOT NULL")
+	}
+	where = append(where, "is_template = "+arg(f.Templates))
+
+	return fmt.Sprintf(`SELECT * FROM projects WHERE %s ORDER BY created_at DESC`, strings.Join(where, " AND ")), args
 }
 
 func (repo *ProjectRepo) Get(id uuid.UUID) (*model.Project, error) {
 	p := model.Project{}
-	query := `SELECT * FROM projects WHERE id = $1`
+	query := fmt.Sprintf(`SELECT * FROM projects WHERE id = $1 AND %s`, notDeleted(repo.withDeleted))
 	if err := repo.db.Get(&p, query, id); err != nil {
 		return nil, err
 	}
 	return &p, nil
 }
 
+// Update overwrites the project. When p.Version is set, the write only applies
+// to that version of the row and fails with ErrVersionConflict otherwise.
 func (repo *ProjectRepo) Update(id uuid.UUID, p *model.Project) error {
 	query := `
 		UPDATE projects
-		SET updated_at = $2, name = $3, description = $4
-		WHERE id = $1
+		SET updated_at = $2, name = $3, description = $4, is_template = $5, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($6 = 0 OR version = $6)
 	`
-	_, err := repo.db.Exec(query, id, time.Now().UTC(), p.Name, p.Description)
-	return err
+	res, err := repo.db.Exec(query, id, time.Now().UTC(), p.Name, p.Description, p.IsTemplate, p.Version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, p.Version)
+}
+
+// Patch writes the columns that differ between before and after. As with
+// Update, after.Version makes the write conditional when set.
+func (repo *ProjectRepo) Patch(id uuid.UUID, before, after *model.Project) error {
+	return patchRow(repo.db, "projects", id, after.Version, diff(before, after, "name", "description", "is_template"))
 }
 
-func (repo *ProjectRepo) Delete(id uuid.UUID) error {
-	query := `DELETE FROM projects WHERE id = $1`
-	_, err := repo.db.Exec(query, id)
+// Archive hides the project from listings without deleting anything. A
+// non-zero version makes it conditional.
+func (repo *ProjectRepo) Archive(id uuid.UUID, version int64) error {
+	return repo.setArchived(id, version, true)
+}
+
+// Unarchive lists the project again.
+func (repo *ProjectRepo) Unarchive(id uuid.UUID, version int64) error {
+	return repo.setArchived(id, version, false)
+}
+
+func (repo *ProjectRepo) setArchived(id uuid.UUID, version int64, archived bool) error {
+	query := `
+		UPDATE projects
+		SET archived_at = CASE WHEN $4 THEN $2::timestamptz END, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR version = $3) AND (archived_at IS NULL) = $4
+	`
+	res, err := repo.db.Exec(query, id, time.Now().UTC(), version, archived)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, version)
+}
+
+// Clone copies the live tasks of project src into the new project dst, with
+// their subtasks, dependencies within the project and labels. Comments,
+// attachments and recurrences stay with the source. It returns how many
+// tasks were copied.
+func (repo *ProjectRepo) Clone(src uuid.UUID, dst *model.Project, opts *model.CloneProject) (int, error) {
+	var tasks []*model.Task
+	err := repo.db.InTx(func(tx *sqlx.Tx) error {
+		now := time.Now().UTC()
+		if err := insertProject(tx, dst, now); err != nil {
+			return err
+		}
+
+		// labels first, so the copies keep their colors
+		_, err := tx.Exec(`
+			INSERT INTO labels (id, project_id, name, color, created_at)
+			SELECT uuid_generate_v4(), $2, name, color, $3 FROM labels WHERE project_id = $1
+		`, src, dst.ID, now)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Select(&tasks, `SELECT * FROM tasks WHERE project_id = $1 AND deleted_at IS NULL ORDER BY `+boardOrder, src); err != nil {
+			return err
+		}
+		if err := loadRelations(tx, tasks...); err != nil {
+			return err
+		}
+
+		var earliest *time.Time
+		for _, t := range tasks {
+			if t.DueAt != nil && (earliest == nil || t.DueAt.Before(*earliest)) {
+				earliest = t.DueAt
+			}
+		}
+		shift := opts.Shift(earliest)
+
+		ids := make(map[uuid.UUID]uuid.UUID, len(tasks))
+		for _, t := range tasks {
+			ids[t.ID] = uuid.New()
+		}
+		for _, t := range parentsFirst(tasks) {
+			c := *t
+			c.ID, c.ProjectID = ids[t.ID], dst.ID
+			c.RecurrenceID, c.OccurrenceAt = nil, nil
+			if t.ParentTaskID != nil {
+				// a parent deleted in the source leaves a top-level copy
+				if parent, ok := ids[*t.ParentTaskID]; ok {
+					c.ParentTaskID = &parent
+				} else {
+					c.ParentTaskID = nil
+				}
+			}
+			if t.DueAt != nil {
+				due := t.DueAt.Add(shift)
+				c.DueAt = &due
+			}
+			if !opts.KeepStatus {
+				// everything lands in todo, appended in board order
+				c.Status, c.StartedAt, c.CompletedAt, c.Position = model.TaskTodo, nil, nil, ""
+			}
+			if !opts.KeepAssignees {
+				c.Assignees = nil
+			}
+			if err := insertTask(tx, &c, now); err != nil {
+				return err
+			}
+		}
+
+		var deps []struct {
+			TaskID    uuid.UUID `db:"task_id"`
+			BlockedBy uuid.UUID `db:"blocked_by_task_id"`
+		}
+		err = tx.Select(&deps, `
+			SELECT d.task_id, d.blocked_by_task_id FROM task_dependencies d
+			JOIN tasks t ON t.id = d.task_id JOIN tasks b ON b.id = d.blocked_by_task_id
+			WHERE t.project_id = $1 AND b.project_id = $1
+		`, src)
+		if err != nil {
+			return err
+		}
+		for _, d := range deps {
+			task, ok1 := ids[d.TaskID]
+			blocker, ok2 := ids[d.BlockedBy]
+			if !ok1 || !ok2 {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT INTO task_dependencies (task_id, blocked_by_task_id) VALUES ($1, $2)`, task, blocker); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(tasks), err
+}
+
+// parentsFirst orders tasks so that every parent comes before its subtasks.
+func parentsFirst(tasks []*model.Task) []*model.Task {
+	byID := make(map[uuid.UUID]bool, len(tasks))
+	children := map[uuid.UUID][]*model.Task{}
+	for _, t := range tasks {
+		byID[t.ID] = true
+	}
+	var out []*model.Task
+	for _, t := range tasks {
+		if t.ParentTaskID != nil && byID[*t.ParentTaskID] {
+			children[*t.ParentTaskID] = append(children[*t.ParentTaskID], t)
+		} else {
+			out = append(out, t)
+		}
+	}
+	for i := 0; i < len(out); i++ {
+		out = append(out, children[out[i].ID]...)
+	}
+	return out
+}
+
+// Delete soft-deletes the project together with its live tasks. The tasks
+// get the same deleted_at so Restore can bring back exactly that set.
+// A non-zero version makes the delete conditional.
+func (repo *ProjectRepo) Delete(id uuid.UUID, version int64) error {
+	query := `
+		WITH p AS (
+			UPDATE projects SET deleted_at = $2, updated_at = $2, version = version + 1
+			WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
+			RETURNING id
+		), t AS (
+			UPDATE tasks SET deleted_at = $2, updated_at = $2, version = version + 1
+			WHERE project_id IN (SELECT id FROM p) AND deleted_at IS NULL
+		)
+		SELECT count(*) FROM p
+	`
+	var n int64
+	err := repo.db.InTx(func(tx *sqlx.Tx) error {
+		return tx.Get(&n, query, id, time.Now().UTC(), version)
+	})
+	if err != nil {
+		return err
+	}
+	if version != 0 && n == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Restore undoes a soft delete, including the tasks deleted along with the project.
+func (repo *ProjectRepo) Restore(id uuid.UUID) error {
+	query := `
+		WITH old AS (
+			SELECT id, deleted_at FROM projects WHERE id = $1 AND deleted_at IS NOT NULL
+		), p AS (
+			UPDATE projects SET deleted_at = NULL, updated_at = $2, version = projects.version + 1
+			FROM old WHERE projects.id = old.id
+			RETURNING projects.id
+		)
+		UPDATE tasks SET deleted_at = NULL, updated_at = $2, version = tasks.version + 1
+		FROM old WHERE tasks.project_id = old.id AND tasks.deleted_at = old.deleted_at
+	`
+	_, err := repo.db.Exec(query, id, time.Now().UTC())
 	return err
 }
+
+// Purge permanently removes projects soft-deleted before the given time.
+func (repo *ProjectRepo) Purge(before time.Time) (int64, error) {
+	res, err := repo.db.Exec(`DELETE FROM projects WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParentsFirst(t *testing.T) {
+	root := &model.Task{ID: uuid.New()}
+	child := &model.Task{ID: uuid.New(), ParentTaskID: &root.ID}
+	grandchild := &model.Task{ID: uuid.New(), ParentTaskID: &child.ID}
+	gone := uuid.New()
+	orphan := &model.Task{ID: uuid.New(), ParentTaskID: &gone}
+
+	out := parentsFirst([]*model.Task{grandchild, child, orphan, root})
+	assert.Equal(t, []*model.Task{orphan, root, child, grandchild}, out)
+}
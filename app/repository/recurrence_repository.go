@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxAdvance bounds the occurrences one Advance creates, so a long window on
+// a busy rule can't flood a project.
+const maxAdvance = 100
+
+type RecurrenceRepo struct {
+	db *database.DB
+}
+
+func NewRecurrenceRepo(db *database.DB) RecurrenceRepository {
+	return &RecurrenceRepo{db: db}
+}
+
+// Create starts the series, with its task as the occurrence at r.StartsAt.
+func (repo *RecurrenceRepo) Create(r *model.TaskRecurrence) error {
+	now := time.Now().UTC()
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO task_recurrences
+				(id, task_id, rule, timezone, starts_at, due_offset_seconds, last_occurrence_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $5, $7, $7)
+		`, r.ID, r.TaskID, r.Rule, r.Timezone, r.StartsAt, r.DueOffset, now)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE tasks SET recurrence_id = $2, occurrence_at = $3, updated_at = $4, version = version + 1
+			WHERE id = $1
+		`, r.TaskID, r.ID, r.StartsAt, now)
+		return err
+	})
+}
+
+func (repo *RecurrenceRepo) Get(id uuid.UUID) (*model.TaskRecurrence, error) {
+	r := model.TaskRecurrence{}
+	if err := repo.db.Get(&r, `SELECT * FROM task_recurrences WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Update changes the schedule. Occurrences already created are kept.
+func (repo *RecurrenceRepo) Update(r *model.TaskRecurrence) error {
+	_, err := repo.db.Exec(`
+		UPDATE task_recurrences SET rule = $2, timezone = $3, starts_at = $4, due_offset_seconds = $5, updated_at = $6
+		WHERE id = $1
+	`, r.ID, r.Rule, r.Timezone, r.StartsAt, r.DueOffset, time.Now().UTC())
+	return err
+}
+
+// Delete ends the series; its tasks stay as one-off tasks.
+func (repo *RecurrenceRepo) Delete(id uuid.UUID) error {
+	_, err := repo.db.Exec(`DELETE FROM task_recurrences WHERE id = $1`, id)
+	return err
+}
+
+// IDs returns the ids of every series.
+func (repo *RecurrenceRepo) IDs() ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := repo.db.Select(&ids, `SELECT id FROM task_recurrences ORDER BY created_at`)
+	return ids, err
+}
+
+// Advance creates the occurrences of the series that start by upTo. With
+// ensureOpen, it also creates the next occurrence, however far ahead, when
+// every live occurrence is done or cancelled. New occurrences copy the
+// latest live one and start as todo, due at the series' offset from their
+// start.
+func (repo *RecurrenceRepo) Advance(id uuid.UUID, upTo time.Time, ensureOpen bool) ([]*model.Task, error) {
+	created := []*model.Task{}
+	err := repo.db.InTx(func(tx *sqlx.Tx) error {
+		// the row lock serializes schedulers working on the same series
+		r := model.TaskRecurrence{}
+		if err := tx.Get(&r, `SELECT * FROM task_recurrences WHERE id = $1 FOR UPDATE`, id); err != nil {
+			return err
+		}
+		rule, loc, err := r.Schedule()
+		if err != nil {
+			return err
+		}
+
+		src := model.Task{}
+		err = tx.Get(&src, `
+			SELECT * FROM tasks WHERE recurrence_id = $1 AND deleted_at IS NULL
+			ORDER BY occurrence_at DESC LIMIT 1
+		`, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			// every occurrence was deleted, there is nothing to repeat
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := loadRelations(tx, &src); err != nil {
+			return err
+		}
+
+		var open bool
+		err = tx.Get(&open, `
+			SELECT EXISTS (
+				SELECT 1 FROM tasks WHERE recurrence_id = $1 AND deleted_at IS NULL AND status NOT IN ($2, $3)
+			)
+		`, id, model.TaskDone, model.TaskCancelled)
+		if err != nil {
+			return err
+		}
+
+		start, last := r.StartsAt.In(loc), r.LastOccurrenceAt
+		now := time.Now().UTC()
+		for len(created) < maxAdvance {
+			next, ok := rule.Next(start, last)
+			if !ok || (next.After(upTo) && (open || !ensureOpen)) {
+				break
+			}
+
+			at := next.UTC()
+			t := &model.Task{
+				ID:              uuid.New(),
+				ProjectID:       src.ProjectID,
+				ParentTaskID:    src.ParentTaskID,
+				Title:           src.Title,
+				Description:     src.Description,
+				Priority:        src.Priority,
+				EstimateMinutes: src.EstimateMinutes,
+				Assignees:       src.Assignees,
+				Labels:          src.Labels,
+				Status:          model.TaskTodo,
+				RecurrenceID:    &r.ID,
+				OccurrenceAt:    &at,
+			}
+			if r.DueOffset != nil {
+				due := at.Add(time.Duration(*r.DueOffset) * time.Second)
+				t.DueAt = &due
+			}
+			if err := insertTask(tx, t, now); err != nil {
+				return err
+			}
+
+			created = append(created, t)
+			open, last = true, next
+		}
+		if len(created) == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(`UPDATE task_recurrences SET last_occurrence_at = $2, updated_at = $3 WHERE id = $1`, id, last, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+)
+
+type ReminderRepo struct {
+	db *database.DB
+}
+
+func NewReminderRepo(db *database.DB) ReminderRepository {
+	return &ReminderRepo{db: db}
+}
+
+// Preferences returns the user's notification preferences, or the defaults
+// when they never set any.
+func (repo *ReminderRepo) Preferences(userID uuid.UUID) (*model.NotificationPreferences, error) {
+	p := model.NotificationPreferences{}
+	err := repo.db.Get(&p, `SELECT * FROM notification_preferences WHERE user_id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.DefaultNotificationPreferences(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SetPreferences replaces the user's notification preferences.
+func (repo *ReminderRepo) SetPreferences(p *model.NotificationPreferences) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, channel, webhook_url, remind_before_minutes, due_soon, overdue, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET channel = EXCLUDED.channel, webhook_url = EXCLUDED.webhook_url, remind_before_minutes = EXCLUDED.remind_before_minutes,
+			due_soon = EXCLUDED.due_soon, overdue = EXCLUDED.overdue, updated_at = EXCLUDED.updated_at
+	`
+	_, err := repo.db.Exec(query, p.UserID, p.Channel, p.WebhookURL, p.RemindBeforeMinutes, p.DueSoon, p.Overdue, time.Now().UTC())
+	return err
+}
+
+// Due returns up to limit reminders owed at now, soonest due first: a
+// due_soon reminder once a task is within the assignee's lead time of its
+// due date, and an overdue one after it. lead and channel are the defaults
+// for users without preferences; muted users, archived projects, templates
+// and reminders already sent are left out.
+func (repo *ReminderRepo) Due(now time.Time, lead time.Duration, channel string, limit int) ([]*model.Reminder, error) {
+	query := `
+		SELECT t.id AS task_id, t.project_id, t.title, t.due_at, k.kind,
+			u.id AS user_id, u.email, u.first_name, u.last_name,
+			COALESCE(p.channel, $3) AS channel, p.webhook_url
+		FROM tasks t
+		JOIN projects pr ON pr.id = t.project_id AND pr.archived_at IS NULL AND NOT pr.is_template
+		JOIN task_assignees ta ON ta.task_id = t.id
+		JOIN users u ON u.id = ta.user_id AND u.deleted_at IS NULL AND u.is_active
+		LEFT JOIN notification_preferences p ON p.user_id = u.id
+		CROSS JOIN LATERAL (
+			SELECT CASE WHEN t.due_at <= $1 THEN 'overdue' ELSE 'due_soon' END AS kind
+		) k
+		WHERE t.deleted_at IS NULL AND t.due_at IS NOT NULL AND t.status NOT IN ($4, $5)
+			AND t.due_at <= $1 + make_interval(mins => COALESCE(p.remind_before_minutes, $2))
+			AND CASE k.kind WHEN 'overdue' THEN COALESCE(p.overdue, true) ELSE COALESCE(p.due_soon, true) END
+			AND COALESCE(p.channel, $3) <> 'none'
+			AND NOT EXISTS (
+				SELECT 1 FROM task_reminders r
+				WHERE r.task_id = t.id AND r.user_id = u.id AND r.kind = k.kind AND r.due_at = t.due_at
+			)
+		ORDER BY t.due_at, t.id, u.id
+		LIMIT $6
+	`
+	var out []*model.Reminder
+	err := repo.db.Select(&out, query, now, int(lead/time.Minute), channel, model.TaskDone, model.TaskCancelled, limit)
+	return out, err
+}
+
+// Claim records the reminder as sent. It reports false when another worker
+// got there first.
+func (repo *ReminderRepo) Claim(r *model.Reminder) (bool, error) {
+	res, err := repo.db.Exec(`
+		INSERT INTO task_reminders (task_id, user_id, kind, due_at, channel, sent_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING
+	`, r.TaskID, r.UserID, r.Kind, r.DueAt, r.Channel, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Release forgets a claimed reminder that could not be delivered, so the
+// next run tries again.
+func (repo *ReminderRepo) Release(r *model.Reminder) error {
+	_, err := repo.db.Exec(`
+		DELETE FROM task_reminders WHERE task_id = $1 AND user_id = $2 AND kind = $3 AND due_at = $4
+	`, r.TaskID, r.UserID, r.Kind, r.DueAt)
+	return err
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+)
+
+// ThroughputFilter selects the tasks done in [From, To), bucketed by
+// GroupBy; a zero ProjectID covers all projects.
+type ThroughputFilter struct {
+	From, To  time.Time
+	GroupBy   model.ReportPeriod
+	ProjectID uuid.UUID
+}
+
+// ReportRepo aggregates tasks for the reporting endpoints.
+type ReportRepo struct {
+	db *database.DB
+}
+
+func NewReportRepo(db *database.DB) ReportRepository {
+	return &ReportRepo{db: db}
+}
+
+// ProjectStats counts the live tasks of the project by status, with those
+// past due at now.
+func (repo *ReportRepo) ProjectStats(projectID uuid.UUID, now time.Time) (*model.ProjectStats, error) {
+	query := `
+		SELECT status, count(*) AS tasks,
+			count(*) FILTER (WHERE due_at < $2 AND status NOT IN ('done', 'cancelled')) AS overdue,
+			avg(extract(epoch FROM completed_at - COALESCE(started_at, created_at))) FILTER (WHERE status = 'done') / 3600 AS cycle_hours
+		FROM tasks
+		WHERE project_id = $1 AND deleted_at IS NULL
+		GROUP BY status
+	`
+	var counts []*model.StatusCount
+	if err := repo.db.Select(&counts, query, projectID, now); err != nil {
+		return nil, err
+	}
+	return model.NewProjectStats(projectID, counts, now), nil
+}
+
+// Throughput counts the tasks done per period, straight from tasks. Periods
+// without any are left out.
+func (repo *ReportRepo) Throughput(f ThroughputFilter) ([]*model.Throughput, error) {
+	args := []interface{}{string(f.GroupBy), f.From, f.To}
+	where := "status = 'done' AND deleted_at IS NULL AND completed_at >= $2 AND completed_at < $3"
+	if f.ProjectID != uuid.Nil {
+		args = append(args, f.ProjectID)
+		where += " AND project_id = $4"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT date_trunc($1, completed_at AT TIME ZONE 'UTC') AS period, count(*) AS completed,
+			avg(extract(epoch FROM completed_at - COALESCE(started_at, created_at))) / 3600 AS cycle_hours
+		FROM tasks WHERE %s
+		GROUP BY 1 ORDER BY 1
+	`, where)
+	var out []*model.Throughput
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MaterializedThroughput is Throughput read from the task_throughput_daily
+// view, so it is only as fresh as the last refresh and From and To are
+// rounded down to whole UTC days.
+func (repo *ReportRepo) MaterializedThroughput(f ThroughputFilter) ([]*model.Throughput, error) {
+	args := []interface{}{string(f.GroupBy), f.From.UTC().Format(time.DateOnly), f.To.UTC().Format(time.DateOnly)}
+	where := "day >= $2::date AND day < $3::date"
+	if f.ProjectID != uuid.Nil {
+		args = append(args, f.ProjectID)
+		where += " AND project_id = $4"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT date_trunc($1, day::timestamp) AS period, sum(completed)::bigint AS completed,
+			sum(cycle_seconds) / sum(completed) / 3600 AS cycle_hours
+		FROM task_throughput_daily WHERE %s
+		GROUP BY 1 ORDER BY 1
+	`, where)
+	var out []*model.Throughput
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RefreshThroughput recomputes the task_throughput_daily view without
+// blocking readers.
+func (repo *ReportRepo) RefreshThroughput() error {
+	_, err := repo.db.Exec(`REFRESH MATERIALIZED VIEW CONCURRENTLY task_throughput_daily`)
+	return err
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Every review write adjusts rating_count and rating_sum of its book in the
+// same transaction, so the aggregates never need a recount.
+
+type ReviewRepo struct {
+	db *database.DB
+}
+
+func NewReviewRepo(db *database.DB) ReviewRepository {
+	return &ReviewRepo{db: db}
+}
+
+// Create adds the review and counts it into the book's rating. It fails
+// with ErrAlreadyReviewed when the user reviewed the book before.
+func (repo *ReviewRepo) Create(r *model.BookReview) error {
+	query := `
+		INSERT INTO book_reviews (id, book_id, user_id, rating, body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (book_id, user_id) DO NOTHING
+	`
+	now := time.Now().UTC()
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		res, err := tx.Exec(query, r.ID, r.BookID, r.UserID, r.Rating, r.Body, now)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrAlreadyReviewed
+		}
+		return addRating(tx, r.BookID, 1, int64(r.Rating))
+	})
+}
+
+func (repo *ReviewRepo) Get(id uuid.UUID) (*model.BookReview, error) {
+	r := model.BookReview{}
+	if err := repo.db.Get(&r, `SELECT * FROM book_reviews WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Find lists the reviews of a book, newest first.
+func (repo *ReviewRepo) Find(bookID uuid.UUID, limit int, offset uint) ([]*model.BookReview, error) {
+	query := `SELECT * FROM book_reviews WHERE book_id = $1 ORDER BY created_at DESC, id`
+	args := []interface{}{bookID}
+	if limit > 0 {
+		query = fmt.Sprintf("%s LIMIT $2 OFFSET $3", query)
+		args = append(args, limit, offset)
+	}
+
+	var out []*model.BookReview
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update rewrites the rating and text of the review and moves the book's
+// rating by the difference. A non-zero r.Version makes the write
+// conditional.
+func (repo *ReviewRepo) Update(r *model.BookReview) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		var before model.BookReview
+		if err := tx.Get(&before, `SELECT * FROM book_reviews WHERE id = $1 FOR UPDATE`, r.ID); err != nil {
+			return err
+		}
+		if r.Version != 0 && r.Version != before.Version {
+			return ErrVersionConflict
+		}
+		query := `
+			UPDATE book_reviews SET rating = $2, body = $3, updated_at = $4, version = version + 1
+			WHERE id = $1
+		`
+		if _, err := tx.Exec(query, r.ID, r.Rating, r.Body, time.Now().UTC()); err != nil {
+			return err
+		}
+		return addRating(tx, before.BookID, 0, int64(r.Rating-before.Rating))
+	})
+}
+
+// Delete removes the review and takes it out of the book's rating; a
+// non-zero version makes it conditional.
+func (repo *ReviewRepo) Delete(id uuid.UUID, version int64) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		var r model.BookReview
+		query := `DELETE FROM book_reviews WHERE id = $1 AND ($2 = 0 OR version = $2) RETURNING *`
+		if err := tx.Get(&r, query, id, version); err != nil {
+			if version != 0 && errors.Is(err, sql.ErrNoRows) {
+				return ErrVersionConflict
+			}
+			return err
+		}
+		return addRating(tx, r.BookID, -1, -int64(r.Rating))
+	})
+}
+
+// addRating moves the review aggregates of a book. It leaves updated_at and
+// the version alone: the aggregates are no edit of the book.
+func addRating(tx *sqlx.Tx, bookID uuid.UUID, count int, sum int64) error {
+	query := `UPDATE book SET rating_count = rating_count + $2, rating_sum = rating_sum + $3 WHERE id = $1`
+	_, err := tx.Exec(query, bookID, count, sum)
+	return err
+}
@@ -0,0 +1,10 @@
+package repository
+
+// notDeleted returns the WHERE condition that hides soft-deleted rows,
+// unless the repository was built through WithDeleted.
+func notDeleted(withDeleted bool) string {
+	if withDeleted {
+		return "TRUE"
+	}
+	return "deleted_at IS NULL"
+}
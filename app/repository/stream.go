@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/jmoiron/sqlx"
+)
+
+// eachBatch runs query behind a server-side cursor and calls fn with its
+// rows, at most size at a time, until the rows run out or fn fails. Only
+// one batch is held in memory, whatever the number of rows. The query runs
+// in a read-only transaction, so every batch sees the same snapshot.
+func eachBatch[T any](db *database.DB, size int, query string, args []interface{}, fn func([]*T) error) error {
+	if size <= 0 {
+		size = 500
+	}
+	return db.InTx(func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(`SET TRANSACTION READ ONLY`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DECLARE batch_cursor NO SCROLL CURSOR FOR `+query, args...); err != nil {
+			return err
+		}
+		fetch := fmt.Sprintf(`FETCH FORWARD %d FROM batch_cursor`, size)
+		for {
+			var batch []*T
+			if err := tx.Select(&batch, fetch); err != nil {
+				return err
+			}
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := fn(batch); err != nil {
+				return err
+			}
+			if len(batch) < size {
+				return nil
+			}
+		}
+	})
+}
@@ -1,81 +1,532 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/byeblogs/go-boilerplate/app/model"
 	"github.com/byeblogs/go-boilerplate/platform/database"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
+// TaskFilter narrows down task listings; zero fields don't filter.
+type TaskFilter struct {
+	ProjectID  uuid.UUID
+	AssigneeID uuid.UUID
+	Label      string
+	Priority   model.TaskPriority
+	// ByPosition orders by board column and position instead of newest first.
+	ByPosition bool
+}
+
 type TaskRepo struct {
-	db *database.DB
+	db          *database.DB
+	withDeleted bool
 }
 
 func NewTaskRepo(db *database.DB) TaskRepository {
-	return &TaskRepo{db}
+	return &TaskRepo{db: db}
+}
+
+// WithDeleted returns a copy of the repository whose reads include soft-deleted tasks.
+func (repo *TaskRepo) WithDeleted() TaskRepository {
+	return &TaskRepo{db: repo.db, withDeleted: true}
 }
 
+// Create inserts the task together with its assignees and labels.
 func (repo *TaskRepo) Create(t *model.Task) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		return insertTask(tx, t, time.Now().UTC())
+	})
+}
+
+// insertTask inserts t with its assignees and labels. A task without a
+// position goes to the bottom of its board column.
+func insertTask(tx *sqlx.Tx, t *model.Task, now time.Time) error {
+	if t.Position == "" {
+		position, err := bottomRank(tx, t.ProjectID, t.Status, t.ID)
+		if err != nil {
+			return err
+		}
+		t.Position = position
+	}
+
 	query := `
-		INSERT INTO tasks (id, project_id, title, status, due_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO tasks (id, project_id, parent_task_id, title, description, status, priority, due_at, started_at, completed_at,
+			recurrence_id, occurrence_at, position, estimate_minutes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $15)
+	`
+	_, err := tx.Exec(query, t.ID, t.ProjectID, t.ParentTaskID, t.Title, t.Description, t.Status, t.Priority, t.DueAt, t.StartedAt, t.CompletedAt,
+		t.RecurrenceID, t.OccurrenceAt, t.Position, t.EstimateMinutes, now)
+	if err != nil {
+		return err
+	}
+	return setRelations(tx, t)
+}
+
+// Upsert inserts the task or overwrites the row with the same id. The board
+// position is kept unless the task changes column.
+func (repo *TaskRepo) Upsert(t *model.Task) error {
+	query := `
+		INSERT INTO tasks (id, project_id, parent_task_id, title, description, status, priority, due_at, started_at, completed_at,
+			position, estimate_minutes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE
+		SET project_id = EXCLUDED.project_id, parent_task_id = EXCLUDED.parent_task_id,
+			title = EXCLUDED.title, description = EXCLUDED.description,
+			status = EXCLUDED.status, priority = EXCLUDED.priority, due_at = EXCLUDED.due_at,
+			started_at = EXCLUDED.started_at, completed_at = EXCLUDED.completed_at,
+			estimate_minutes = EXCLUDED.estimate_minutes,
+			position = CASE WHEN tasks.project_id = EXCLUDED.project_id AND tasks.status = EXCLUDED.status
+				THEN tasks.position ELSE EXCLUDED.position END,
+			updated_at = EXCLUDED.updated_at, deleted_at = NULL,
+			version = tasks.version + 1
 	`
 	now := time.Now().UTC()
-	_, err := repo.db.Exec(query, t.ID, t.ProjectID, t.Title, t.Status, t.DueAt, now, now)
-	return err
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		position, err := bottomRank(tx, t.ProjectID, t.Status, t.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(query, t.ID, t.ProjectID, t.ParentTaskID, t.Title, t.Description, t.Status, t.Priority, t.DueAt, t.StartedAt, t.CompletedAt,
+			position, t.EstimateMinutes, now, now)
+		if err != nil {
+			return err
+		}
+		return setRelations(tx, t)
+	})
 }
 
 func (repo *TaskRepo) All(limit int, offset uint) ([]*model.Task, error) {
-	var out []*model.Task
-	query := `SELECT * FROM tasks ORDER BY created_at DESC`
-	var err error
+	return repo.Find(TaskFilter{}, limit, offset)
+}
 
+func (repo *TaskRepo) AllByProject(projectID uuid.UUID, limit int, offset uint) ([]*model.Task, error) {
+	return repo.Find(TaskFilter{ProjectID: projectID}, limit, offset)
+}
+
+// Find lists the tasks matching every set field of the filter.
+func (repo *TaskRepo) Find(f TaskFilter, limit int, offset uint) ([]*model.Task, error) {
+	query, args := f.query(repo.withDeleted)
 	if limit > 0 {
-		query = fmt.Sprintf("%s LIMIT $1 OFFSET $2", query)
-		err = repo.db.Select(&out, query, limit, offset)
-	} else {
-		err = repo.db.Select(&out, query)
+		query = fmt.Sprintf("%s LIMIT $%d OFFSET $%d", query, len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
 	}
-	return out, err
-}
 
-func (repo *TaskRepo) AllByProject(projectID uuid.UUID, limit int, offset uint) ([]*model.Task, error) {
 	var out []*model.Task
-	query := `SELECT * FROM tasks WHERE project_id = $1 ORDER BY created_at DESC`
-	var err error
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, loadRelations(repo.db, out...)
+}
 
-	if limit > 0 {
-		query = fmt.Sprintf("%s LIMIT $2 OFFSET $3", query)
-		err = repo.db.Select(&out, query, projectID, limit, offset)
-	} else {
-		err = repo.db.Select(&out, query, projectID)
+// Each calls fn with the tasks Find would list, in the same order, in
+// batches of at most size tasks read from a database cursor.
+func (repo *TaskRepo) Each(f TaskFilter, size int, fn func([]*model.Task) error) error {
+	query, args := f.query(repo.withDeleted)
+	return eachBatch(repo.db, size, query, args, func(tasks []*model.Task) error {
+		if err := loadRelations(repo.db, tasks...); err != nil {
+			return err
+		}
+		return fn(tasks)
+	})
+}
+
+func (f TaskFilter) query(withDeleted bool) (string, []interface{}) {
+	where := []string{notDeleted(withDeleted)}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.ProjectID != uuid.Nil {
+		where = append(where, "project_id = "+arg(f.ProjectID))
+	}
+	if f.AssigneeID != uuid.Nil {
+		where = append(where, "EXISTS (SELECT 1 FROM task_assignees a WHERE a.task_id = tasks.id AND a.user_id = "+arg(f.AssigneeID)+")")
+	}
+	if f.Label != "" {
+		where = append(where, `EXISTS (
+			SELECT 1 FROM task_labels tl JOIN labels l ON l.id = tl.label_id
+			WHERE tl.task_id = tasks.id AND l.name = `+arg(strings.ToLower(strings.TrimSpace(f.Label)))+`)`)
+	}
+	if f.Priority != "" {
+		where = append(where, "priority = "+arg(f.Priority))
 	}
-	return out, err
+
+	order := "created_at DESC"
+	if f.ByPosition {
+		order = boardOrder
+	}
+	return fmt.Sprintf(`SELECT * FROM tasks WHERE %s ORDER BY %s`, strings.Join(where, " AND "), order), args
 }
 
 func (repo *TaskRepo) Get(id uuid.UUID) (*model.Task, error) {
 	t := model.Task{}
-	query := `SELECT * FROM tasks WHERE id = $1`
+	query := fmt.Sprintf(`SELECT * FROM tasks WHERE id = $1 AND %s`, notDeleted(repo.withDeleted))
 	if err := repo.db.Get(&t, query, id); err != nil {
 		return nil, err
 	}
-	return &t, nil
+	return &t, loadRelations(repo.db, &t)
 }
 
+// Update overwrites the task, its assignees and labels; a new status puts the
+// task at the bottom of that column. When t.Version is set, the write only
+// applies to that version of the row and fails with ErrVersionConflict
+// otherwise.
 func (repo *TaskRepo) Update(id uuid.UUID, t *model.Task) error {
 	query := `
 		UPDATE tasks
-		SET updated_at = $2, title = $3, status = $4, due_at = $5
-		WHERE id = $1
+		SET updated_at = $2, title = $3, description = $4, status = $5, priority = $6, due_at = $7,
+			started_at = $8, completed_at = $9, parent_task_id = $10, position = COALESCE($12, position),
+			estimate_minutes = $13, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($11 = 0 OR version = $11)
+	`
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		var stored struct {
+			Status       model.TaskStatus `db:"status"`
+			ParentTaskID *uuid.UUID       `db:"parent_task_id"`
+		}
+		err := tx.Get(&stored, `SELECT status, parent_task_id FROM tasks WHERE id = $1 AND deleted_at IS NULL`, id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		found := err == nil
+		if err := checkParent(tx, id, stored.ParentTaskID, t.ParentTaskID); err != nil {
+			return err
+		}
+		if err := checkBlockers(tx, id, t.Status); err != nil {
+			return err
+		}
+		var position *string
+		if found && stored.Status != t.Status {
+			bottom, err := bottomRank(tx, t.ProjectID, t.Status, id)
+			if err != nil {
+				return err
+			}
+			position = &bottom
+		}
+		res, err := tx.Exec(query, id, time.Now().UTC(), t.Title, t.Description, t.Status, t.Priority, t.DueAt,
+			t.StartedAt, t.CompletedAt, t.ParentTaskID, t.Version, position, t.EstimateMinutes)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(res, t.Version); err != nil {
+			return err
+		}
+		t.ID = id
+		return setRelations(tx, t)
+	})
+}
+
+// Patch writes the columns that differ between before and after, and the
+// assignees or labels when those changed. The position only changes with
+// the status, to the bottom of the new column; Move places a task freely.
+// As with Update, after.Version makes the write conditional when set.
+func (repo *TaskRepo) Patch(id uuid.UUID, before, after *model.Task) error {
+	relations := !sameJSON(before.Assignees, after.Assignees) || !sameJSON(before.Labels, after.Labels)
+
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		if err := checkParent(tx, id, before.ParentTaskID, after.ParentTaskID); err != nil {
+			return err
+		}
+		if err := checkBlockers(tx, id, after.Status); err != nil {
+			return err
+		}
+		after.Position = before.Position
+		if after.Status != before.Status {
+			position, err := bottomRank(tx, before.ProjectID, after.Status, id)
+			if err != nil {
+				return err
+			}
+			after.Position = position
+		}
+		ch := diff(before, after, "parent_task_id", "title", "description", "status", "priority", "due_at", "started_at", "completed_at", "position", "estimate_minutes")
+		ch.touch = relations
+		if err := patchRow(tx, "tasks", id, after.Version, ch); err != nil {
+			return err
+		}
+		if !relations {
+			return nil
+		}
+		after.ID, after.ProjectID = id, before.ProjectID
+		return setRelations(tx, after)
+	})
+}
+
+// Delete soft-deletes the task; a non-zero version makes the delete conditional.
+func (repo *TaskRepo) Delete(id uuid.UUID, version int64) error {
+	query := `
+		UPDATE tasks SET deleted_at = $2, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
 	`
-	_, err := repo.db.Exec(query, id, time.Now().UTC(), t.Title, t.Status, t.DueAt)
+	res, err := repo.db.Exec(query, id, time.Now().UTC(), version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, version)
+}
+
+// Restore undoes a soft delete.
+func (repo *TaskRepo) Restore(id uuid.UUID) error {
+	query := `UPDATE tasks SET deleted_at = NULL, updated_at = $2, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`
+	_, err := repo.db.Exec(query, id, time.Now().UTC())
 	return err
 }
 
-func (repo *TaskRepo) Delete(id uuid.UUID) error {
-	query := `DELETE FROM tasks WHERE id = $1`
-	_, err := repo.db.Exec(query, id)
+// Purge permanently removes tasks soft-deleted before the given time.
+func (repo *TaskRepo) Purge(before time.Time) (int64, error) {
+	res, err := repo.db.Exec(`DELETE FROM tasks WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Tree returns the task and all of its live descendants, parents first.
+func (repo *TaskRepo) Tree(id uuid.UUID) ([]*model.Task, error) {
+	query := fmt.Sprintf(`
+		WITH RECURSIVE tree AS (
+			SELECT tasks.*, 0 AS depth FROM tasks WHERE id = $1 AND %s
+			UNION ALL
+			SELECT t.*, tree.depth + 1 FROM tasks t JOIN tree ON t.parent_task_id = tree.id
+			WHERE t.deleted_at IS NULL
+		)
+		SELECT * FROM tree ORDER BY depth, created_at
+	`, notDeleted(repo.withDeleted))
+
+	var rows []struct {
+		model.Task
+		Depth int `db:"depth"`
+	}
+	if err := repo.db.Select(&rows, query, id); err != nil {
+		return nil, err
+	}
+	out := make([]*model.Task, len(rows))
+	for i := range rows {
+		out[i] = &rows[i].Task
+	}
+	return out, loadRelations(repo.db, out...)
+}
+
+// blockersQuery selects the live tasks task $1 is blocked by.
+const blockersQuery = `
+	SELECT tasks.* FROM tasks JOIN task_dependencies d ON d.blocked_by_task_id = tasks.id
+	WHERE d.task_id = $1 AND tasks.deleted_at IS NULL ORDER BY d.created_at
+`
+
+// Blockers returns the live tasks the task is blocked by.
+func (repo *TaskRepo) Blockers(id uuid.UUID) ([]*model.Task, error) {
+	var out []*model.Task
+	if err := repo.db.Select(&out, blockersQuery, id); err != nil {
+		return nil, err
+	}
+	return out, loadRelations(repo.db, out...)
+}
+
+// Blocking returns the live tasks blocked by the task.
+func (repo *TaskRepo) Blocking(id uuid.UUID) ([]*model.Task, error) {
+	query := `
+		SELECT tasks.* FROM tasks JOIN task_dependencies d ON d.task_id = tasks.id
+		WHERE d.blocked_by_task_id = $1 AND tasks.deleted_at IS NULL ORDER BY d.created_at
+	`
+	var out []*model.Task
+	if err := repo.db.Select(&out, query, id); err != nil {
+		return nil, err
+	}
+	return out, loadRelations(repo.db, out...)
+}
+
+// AddDependency blocks the task on another one. It fails with ErrCycle when
+// the blocker already (indirectly) waits for the task.
+func (repo *TaskRepo) AddDependency(id, blockedBy uuid.UUID) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		// Serialize dependency writes so two concurrent links can't close a cycle.
+		if _, err := tx.Exec(`LOCK TABLE task_dependencies IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+
+		query := `
+			WITH RECURSIVE up AS (
+				SELECT $1::uuid AS id
+				UNION
+				SELECT d.blocked_by_task_id FROM task_dependencies d JOIN up ON d.task_id = up.id
+			)
+			SELECT EXISTS (SELECT 1 FROM up WHERE id = $2)
+		`
+		var cycle bool
+		if err := tx.Get(&cycle, query, blockedBy, id); err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCycle
+		}
+
+		_, err := tx.Exec(`INSERT INTO task_dependencies (task_id, blocked_by_task_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, blockedBy)
+		return err
+	})
+}
+
+// RemoveDependency unblocks the task from another one.
+func (repo *TaskRepo) RemoveDependency(id, blockedBy uuid.UUID) error {
+	_, err := repo.db.Exec(`DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_task_id = $2`, id, blockedBy)
 	return err
 }
+
+// checkBlockers refuses to move a task that isn't done yet to done while
+// it has open blockers, with a *BlockedError listing them. The blockers are
+// share-locked for the rest of the transaction, so none can be reopened
+// before the task is written.
+func checkBlockers(tx *sqlx.Tx, id uuid.UUID, status model.TaskStatus) error {
+	if status != model.TaskDone {
+		return nil
+	}
+	var done bool
+	if err := tx.Get(&done, `SELECT status = $2 FROM tasks WHERE id = $1`, id, model.TaskDone); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if done {
+		return nil
+	}
+
+	var blockers []*model.Task
+	if err := tx.Select(&blockers, blockersQuery+` FOR SHARE OF tasks`, id); err != nil {
+		return err
+	}
+	open := []*model.Task{}
+	for _, b := range blockers {
+		if !b.Status.Closed() {
+			open = append(open, b)
+		}
+	}
+	if len(open) == 0 {
+		return nil
+	}
+	if err := loadRelations(tx, open...); err != nil {
+		return err
+	}
+	return &BlockedError{Blockers: open}
+}
+
+// checkParent refuses a new parent that is the task itself or one of its
+// descendants. Reparents are serialized for the rest of the transaction, so
+// that two concurrent ones can't close a cycle either; writes that keep the
+// parent are left alone.
+func checkParent(tx *sqlx.Tx, id uuid.UUID, from, to *uuid.UUID) error {
+	if to == nil || (from != nil && *from == *to) {
+		return nil
+	}
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('task_tree'))`); err != nil {
+		return err
+	}
+
+	query := `
+		WITH RECURSIVE up AS (
+			SELECT $1::uuid AS id
+			UNION
+			SELECT t.parent_task_id FROM tasks t JOIN up ON t.id = up.id WHERE t.parent_task_id IS NOT NULL
+		)
+		SELECT EXISTS (SELECT 1 FROM up WHERE id = $2)
+	`
+	var cycle bool
+	if err := tx.Get(&cycle, query, *to, id); err != nil {
+		return err
+	}
+	if cycle {
+		return ErrCycle
+	}
+	return nil
+}
+
+// setRelations replaces the assignees and labels of t. Labels are looked up
+// by name in the task's project and defined there on first use.
+func setRelations(tx *sqlx.Tx, t *model.Task) error {
+	if _, err := tx.Exec(`DELETE FROM task_assignees WHERE task_id = $1`, t.ID); err != nil {
+		return err
+	}
+	for _, userID := range t.Assignees {
+		if _, err := tx.Exec(`INSERT INTO task_assignees (task_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, t.ID, userID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM task_labels WHERE task_id = $1`, t.ID); err != nil {
+		return err
+	}
+	for _, name := range model.NormalizeLabels(t.Labels) {
+		query := `
+			WITH ins AS (
+				INSERT INTO labels (id, project_id, name) VALUES ($2, $3, $4)
+				ON CONFLICT (project_id, name) DO NOTHING
+				RETURNING id
+			)
+			INSERT INTO task_labels (task_id, label_id)
+			SELECT $1, id FROM ins
+			UNION ALL
+			SELECT $1, id FROM labels WHERE project_id = $3 AND name = $4
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.Exec(query, t.ID, uuid.New(), t.ProjectID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRelations fills in the assignees and labels of the given tasks, read
+// through q: the database, or the transaction the tasks were read in.
+func loadRelations(q sqlx.Ext, tasks ...*model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*model.Task, len(tasks))
+	ids := make([]uuid.UUID, len(tasks))
+	for i, t := range tasks {
+		t.Assignees, t.Labels = []uuid.UUID{}, []string{}
+		byID[t.ID] = t
+		ids[i] = t.ID
+	}
+
+	var assignees []struct {
+		TaskID uuid.UUID `db:"task_id"`
+		UserID uuid.UUID `db:"user_id"`
+	}
+	query, args, err := sqlx.In(`SELECT task_id, user_id FROM task_assignees WHERE task_id IN (?) ORDER BY created_at, user_id`, ids)
+	if err != nil {
+		return err
+	}
+	if err := sqlx.Select(q, &assignees, q.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, a := range assignees {
+		byID[a.TaskID].Assignees = append(byID[a.TaskID].Assignees, a.UserID)
+	}
+
+	var labels []struct {
+		TaskID uuid.UUID `db:"task_id"`
+		Name   string    `db:"name"`
+	}
+	query, args, err = sqlx.In(`
+		SELECT tl.task_id, l.name FROM task_labels tl JOIN labels l ON l.id = tl.label_id
+		WHERE tl.task_id IN (?) ORDER BY l.name
+	`, ids)
+	if err != nil {
+		return err
+	}
+	if err := sqlx.Select(q, &labels, q.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, l := range labels {
+		byID[l.TaskID].Labels = append(byID[l.TaskID].Labels, l.Name)
+	}
+	return nil
+}
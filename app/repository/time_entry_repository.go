@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// TimeEntryFilter narrows down time entries; zero fields don't filter. From
+// and To bound started_at as [From, To).
+type TimeEntryFilter struct {
+	TaskID    uuid.UUID
+	ProjectID uuid.UUID
+	UserID    uuid.UUID
+	From, To  time.Time
+}
+
+// timeEntrySelect reads entries with their task's project and title, and the
+// time tracked so far.
+const timeEntrySelect = `
+	SELECT e.*, t.project_id, t.title AS task_title, e.ended_at IS NULL AS running,
+		extract(epoch FROM COALESCE(e.ended_at, now()) - e.started_at)::bigint AS duration_seconds
+	FROM time_entries e JOIN tasks t ON t.id = e.task_id AND t.deleted_at IS NULL
+`
+
+type TimeEntryRepo struct {
+	db *database.DB
+}
+
+func NewTimeEntryRepo(db *database.DB) TimeEntryRepository {
+	return &TimeEntryRepo{db: db}
+}
+
+// Create records a finished entry.
+func (repo *TimeEntryRepo) Create(e *model.TimeEntry) error {
+	return insertTimeEntry(repo.db, e, time.Now().UTC())
+}
+
+func insertTimeEntry(db sqlx.Execer, e *model.TimeEntry, now time.Time) error {
+	query := `
+		INSERT INTO time_entries (id, task_id, user_id, started_at, ended_at, note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+	`
+	_, err := db.Exec(query, e.ID, e.TaskID, e.UserID, e.StartedAt, e.EndedAt, e.Note, now)
+	return err
+}
+
+func (repo *TimeEntryRepo) Get(id uuid.UUID) (*model.TimeEntry, error) {
+	e := model.TimeEntry{}
+	if err := repo.db.Get(&e, timeEntrySelect+` WHERE e.id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Find lists the entries matching the filter, latest first.
+func (repo *TimeEntryRepo) Find(f TimeEntryFilter, limit int, offset uint) ([]*model.TimeEntry, error) {
+	where, args := f.where()
+	query := fmt.Sprintf(`%s WHERE %s ORDER BY e.started_at DESC, e.id`, timeEntrySelect, where)
+	if limit > 0 {
+		args = append(args, limit, offset)
+		query = fmt.Sprintf("%s LIMIT $%d OFFSET $%d", query, len(args)-1, len(args))
+	}
+
+	var out []*model.TimeEntry
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (f TimeEntryFilter) where() (string, []interface{}) {
+	where := []string{"TRUE"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.TaskID != uuid.Nil {
+		where = append(where, "e.task_id = "+arg(f.TaskID))
+	}
+	if f.ProjectID != uuid.Nil {
+		where = append(where, "t.project_id = "+arg(f.ProjectID))
+	}
+	if f.UserID != uuid.Nil {
+		where = append(where, "e.user_id = "+arg(f.UserID))
+	}
+	if !f.From.IsZero() {
+		where = append(where, "e.started_at >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "e.started_at < "+arg(f.To))
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// Update overwrites when the entry started and ended, and its note. A
+// non-zero e.Version makes the write conditional.
+func (repo *TimeEntryRepo) Update(e *model.TimeEntry) error {
+	query := `
+		UPDATE time_entries SET started_at = $2, ended_at = $3, note = $4, updated_at = $5, version = version + 1
+		WHERE id = $1 AND ($6 = 0 OR version = $6)
+	`
+	res, err := repo.db.Exec(query, e.ID, e.StartedAt, e.EndedAt, e.Note, time.Now().UTC(), e.Version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, e.Version)
+}
+
+// Delete removes the entry; a non-zero version makes it conditional.
+func (repo *TimeEntryRepo) Delete(id uuid.UUID, version int64) error {
+	res, err := repo.db.Exec(`DELETE FROM time_entries WHERE id = $1 AND ($2 = 0 OR version = $2)`, id, version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, version)
+}
+
+// Running returns the user's running timer, or sql.ErrNoRows.
+func (repo *TimeEntryRepo) Running(userID uuid.UUID) (*model.TimeEntry, error) {
+	e := model.TimeEntry{}
+	if err := repo.db.Get(&e, timeEntrySelect+` WHERE e.user_id = $1 AND e.ended_at IS NULL`, userID); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Start starts a timer for e.UserID on e.TaskID at e.StartedAt. It fails
+// with ErrTimerRunning while the user has another timer running.
+func (repo *TimeEntryRepo) Start(e *model.TimeEntry) error {
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		// the unique index backs this up; the lock turns a race into a clean error
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('timer'), hashtext($1::text))`, e.UserID); err != nil {
+			return err
+		}
+		var running bool
+		if err := tx.Get(&running, `SELECT EXISTS (SELECT 1 FROM time_entries WHERE user_id = $1 AND ended_at IS NULL)`, e.UserID); err != nil {
+			return err
+		}
+		if running {
+			return ErrTimerRunning
+		}
+		e.EndedAt = nil
+		return insertTimeEntry(tx, e, time.Now().UTC())
+	})
+}
+
+// Stop ends the user's running timer at now and returns its id, or
+// sql.ErrNoRows when none runs.
+func (repo *TimeEntryRepo) Stop(userID uuid.UUID, now time.Time) (uuid.UUID, error) {
+	query := `
+		UPDATE time_entries SET ended_at = GREATEST($2, started_at), updated_at = $2, version = version + 1
+		WHERE user_id = $1 AND ended_at IS NULL
+		RETURNING id
+	`
+	var id uuid.UUID
+	err := repo.db.Get(&id, query, userID, now)
+	return id, err
+}
+
+// Summary totals the entries matching the filter per project, task or user,
+// most time first.
+func (repo *TimeEntryRepo) Summary(group model.TimeGroup, f TimeEntryFilter) ([]*model.TimeSummary, error) {
+	// grouping by primary keys lets the name and estimate follow from them
+	var key, name, estimate, join string
+	switch group {
+	case model.TimeByProject:
+		key, name, join = "p.id", "p.name", "JOIN projects p ON p.id = t.project_id"
+		estimate = "(SELECT sum(estimate_minutes) FROM tasks WHERE project_id = p.id AND deleted_at IS NULL)"
+	case model.TimeByTask:
+		key, name, estimate = "t.id", "t.title", "t.estimate_minutes::bigint"
+	case model.TimeByUser:
+		key, name, estimate = "u.id", "u.username", "NULL::bigint"
+		join = "JOIN users u ON u.id = e.user_id"
+	default:
+		return nil, fmt.Errorf("unknown time grouping %q", group)
+	}
+
+	where, args := f.where()
+	query := fmt.Sprintf(`
+		SELECT %[1]s AS id, %[2]s AS name, %[3]s AS estimate_minutes, count(*) AS entries,
+			sum(extract(epoch FROM COALESCE(e.ended_at, now()) - e.started_at))::bigint AS seconds
+		FROM time_entries e JOIN tasks t ON t.id = e.task_id AND t.deleted_at IS NULL %[4]s
+		WHERE %[5]s
+		GROUP BY %[1]s
+		ORDER BY seconds DESC, name
+	`, key, name, estimate, join, where)
+
+	var out []*model.TimeSummary
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -7,28 +7,53 @@ import (
 	"github.com/byeblogs/go-boilerplate/app/model"
 	"github.com/byeblogs/go-boilerplate/platform/database"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 type UserRepo struct {
-	db *database.DB
+	db          *database.DB
+	withDeleted bool
 }
 
 func NewUserRepo(db *database.DB) UserRepository {
-	return &UserRepo{db}
+	return &UserRepo{db: db}
+}
+
+// WithDeleted returns a copy of the repository whose reads include soft-deleted users.
+func (repo *UserRepo) WithDeleted() UserRepository {
+	return &UserRepo{db: repo.db, withDeleted: true}
 }
 
 func (repo *UserRepo) Create(u *model.User) error {
 	query := `
-		INSERT INTO users (id, email, name, username, password_hash, is_active, is_admin, created_at, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7, now(), now())
+		INSERT INTO users (id, email, username, password_hash, first_name, last_name, is_active, is_admin, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8, now(), now())
 	`
-	_, err := repo.db.Exec(query, u.ID, u.Email, u.Name, u.Username, u.PasswordHash, u.IsActive, u.IsAdmin)
+	_, err := repo.db.Exec(query, u.ID, u.Email, u.UserName, u.PasswordHash, u.FirstName, u.LastName, u.IsActive, u.IsAdmin)
 	return err
 }
 
+// Upsert inserts the user or, when the username is already taken, refreshes
+// the existing row in place. u.ID is set to the id of the stored row.
+func (repo *UserRepo) Upsert(u *model.User) error {
+	query := `
+		INSERT INTO users (id, email, username, password_hash, first_name, last_name, is_active, is_admin, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8, now(), now())
+		ON CONFLICT (username) DO UPDATE
+		SET email = EXCLUDED.email, password_hash = EXCLUDED.password_hash,
+			first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name,
+			is_active = EXCLUDED.is_active, is_admin = EXCLUDED.is_admin, updated_at = now(), deleted_at = NULL,
+			version = users.version + 1
+		RETURNING id
+	`
+	return repo.db.InTx(func(tx *sqlx.Tx) error {
+		return tx.Get(&u.ID, query, u.ID, u.Email, u.UserName, u.PasswordHash, u.FirstName, u.LastName, u.IsActive, u.IsAdmin)
+	})
+}
+
 func (repo *UserRepo) All(limit int, offset uint) ([]*model.User, error) {
 	var out []*model.User
-	query := `SELECT * FROM users ORDER BY created_at DESC`
+	query := fmt.Sprintf(`SELECT * FROM users WHERE %s ORDER BY created_at DESC`, notDeleted(repo.withDeleted))
 	var err error
 
 	if limit > 0 {
@@ -40,9 +65,16 @@ func (repo *UserRepo) All(limit int, offset uint) ([]*model.User, error) {
 	return out, err
 }
 
+// Each calls fn with every user, newest first as with All, in batches of at
+// most size users read from a database cursor.
+func (repo *UserRepo) Each(size int, fn func([]*model.User) error) error {
+	query := fmt.Sprintf(`SELECT * FROM users WHERE %s ORDER BY created_at DESC`, notDeleted(repo.withDeleted))
+	return eachBatch(repo.db, size, query, nil, fn)
+}
+
 func (repo *UserRepo) Get(id uuid.UUID) (*model.User, error) {
 	u := model.User{}
-	query := `SELECT * FROM users WHERE id = $1`
+	query := fmt.Sprintf(`SELECT * FROM users WHERE id = $1 AND %s`, notDeleted(repo.withDeleted))
 	if err := repo.db.Get(&u, query, id); err != nil {
 		return nil, err
 	}
@@ -51,25 +83,135 @@ func (repo *UserRepo) Get(id uuid.UUID) (*model.User, error) {
 
 func (repo *UserRepo) GetByUsername(username string) (*model.User, error) {
 	u := model.User{}
-	query := `SELECT * FROM users WHERE username = $1`
+	query := fmt.Sprintf(`SELECT * FROM users WHERE username = $1 AND %s`, notDeleted(repo.withDeleted))
 	if err := repo.db.Get(&u, query, username); err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
+// Update overwrites the user profile. When u.Version is set, the write only
+// applies to that version of the row and fails with ErrVersionConflict otherwise.
 func (repo *UserRepo) Update(id uuid.UUID, u *model.User) error {
 	query := `
 		UPDATE users
-		SET updated_at = $2, email = $3, name = $4
-		WHERE id = $1
+		SET updated_at = $2, email = $3, first_name = $4, last_name = $5, is_active = $6, is_admin = $7,
+			version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($8 = 0 OR version = $8)
 	`
-	_, err := repo.db.Exec(query, id, time.Now().UTC(), u.Email, u.UserName)
-	return err
+	res, err := repo.db.Exec(query, id, time.Now().UTC(), u.Email, u.FirstName, u.LastName, u.IsActive, u.IsAdmin, u.Version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, u.Version)
+}
+
+// Patch writes the columns that differ between before and after. As with
+// Update, after.Version makes the write conditional when set.
+func (repo *UserRepo) Patch(id uuid.UUID, before, after *model.User) error {
+	return patchRow(repo.db, "users", id, after.Version, diff(before, after, "email", "first_name", "last_name", "is_active", "is_admin"))
 }
 
-func (repo *UserRepo) Delete(id uuid.UUID) error {
-	query := `DELETE FROM users WHERE id = $1`
-	_, err := repo.db.Exec(query, id)
+// Delete soft-deletes the user along with their books and projects, and
+// the tasks of those projects, so that nothing they own stays live until
+// Purge removes it for good.
+func (repo *UserRepo) Delete(id uuid.UUID, version int64) error {
+	query := `
+		WITH u AS (
+			UPDATE users SET deleted_at = $2, updated_at = $2, version = version + 1
+			WHERE id = $1 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
+			RETURNING id
+		), b AS (
+			UPDATE book SET deleted_at = $2, updated_at = $2, version = version + 1
+			WHERE user_id IN (SELECT id FROM u) AND deleted_at IS NULL
+		), p AS (
+			UPDATE projects SET deleted_at = $2, updated_at = $2, version = version + 1
+			WHERE owner_user_id IN (SELECT id FROM u) AND deleted_at IS NULL
+			RETURNING id
+		), t AS (
+			UPDATE tasks SET deleted_at = $2, updated_at = $2, version = version + 1
+			WHERE project_id IN (SELECT id FROM p) AND deleted_at IS NULL
+		)
+		SELECT count(*) FROM u
+	`
+	var n int64
+	err := repo.db.InTx(func(tx *sqlx.Tx) error {
+		return tx.Get(&n, query, id, time.Now().UTC(), version)
+	})
+	if err != nil {
+		return err
+	}
+	if version != 0 && n == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Restore undoes a soft delete, including the books, projects and tasks
+// deleted along with the user. A book whose ISBN a live book has taken in
+// the meantime stays deleted.
+func (repo *UserRepo) Restore(id uuid.UUID) error {
+	query := `
+		WITH old AS (
+			SELECT id, deleted_at FROM users WHERE id = $1 AND deleted_at IS NOT NULL
+		), u AS (
+			UPDATE users SET deleted_at = NULL, updated_at = $2, version = users.version + 1
+			FROM old WHERE users.id = old.id
+		), b AS (
+			UPDATE book SET deleted_at = NULL, updated_at = $2, version = book.version + 1
+			FROM old WHERE book.user_id = old.id AND book.deleted_at = old.deleted_at
+				AND (book.isbn IS NULL OR NOT EXISTS (
+					SELECT 1 FROM book live WHERE live.isbn = book.isbn AND live.deleted_at IS NULL
+				))
+		), p AS (
+			UPDATE projects SET deleted_at = NULL, updated_at = $2, version = projects.version + 1
+			FROM old WHERE projects.owner_user_id = old.id AND projects.deleted_at = old.deleted_at
+			RETURNING projects.id
+		)
+		UPDATE tasks SET deleted_at = NULL, updated_at = $2, version = tasks.version + 1
+		FROM old WHERE tasks.project_id IN (SELECT id FROM p) AND tasks.deleted_at = old.deleted_at
+	`
+	_, err := repo.db.Exec(query, id, time.Now().UTC())
 	return err
 }
+
+// purgeableUsers selects the users soft-deleted before $1 that no longer
+// own live books or projects, say restored on their own since, and have
+// no copy out on loan: the ON DELETE CASCADE foreign keys would remove
+// those without warning, and a copy whose open loan is gone could be lent
+// again while still out.
+const purgeableUsers = `
+	SELECT id FROM users u WHERE u.deleted_at < $1
+		AND NOT EXISTS (SELECT 1 FROM book b WHERE b.user_id = u.id AND b.deleted_at IS NULL)
+		AND NOT EXISTS (SELECT 1 FROM projects p WHERE p.owner_user_id = u.id AND p.deleted_at IS NULL)
+		AND NOT EXISTS (SELECT 1 FROM loans l WHERE l.user_id = u.id AND l.returned_at IS NULL)
+`
+
+// Purge permanently removes users soft-deleted before the given time.
+// Rows owned by those users are removed by the ON DELETE CASCADE foreign
+// keys; users who own live rows or have open loans are kept.
+func (repo *UserRepo) Purge(before time.Time) (int64, error) {
+	var n int64
+	err := repo.db.InTx(func(tx *sqlx.Tx) error {
+		// the reviews go with the users; take them out of the book ratings first
+		query := `
+			UPDATE book SET rating_count = book.rating_count - r.n, rating_sum = book.rating_sum - r.total
+			FROM (
+				SELECT book_id, count(*) AS n, sum(rating) AS total FROM book_reviews
+				WHERE user_id IN (` + purgeableUsers + `)
+				GROUP BY book_id
+			) r
+			WHERE book.id = r.book_id
+		`
+		if _, err := tx.Exec(query, before); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`DELETE FROM users WHERE id IN (`+purgeableUsers+`)`, before)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setUpDB connects to the migrated test database described by .env.test,
+// skipping the test when none is configured.
+func setUpDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	_ = godotenv.Load("../../.env.test")
+	if os.Getenv("DATABASE_URL") == "" && os.Getenv("DB_HOST") == "" {
+		t.Skip("no test database configured (see .env.test)")
+	}
+
+	config.LoadAllConfigs("../../.env.test")
+	require.NoError(t, database.ConnectDB())
+	return database.GetDB()
+}
+
+func newTestUser(t *testing.T, username string) *model.User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("demo#123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	return &model.User{
+		ID:           uuid.New(),
+		IsActive:     true,
+		UserName:     username,
+		Email:        username + "@example.com",
+		PasswordHash: string(hash),
+		FirstName:    "Round",
+		LastName:     "Trip",
+	}
+}
+
+func TestUserRepoRoundTrip(t *testing.T) {
+	db := setUpDB(t)
+	userRepo := NewUserRepo(db)
+
+	u := newTestUser(t, "roundtrip_"+uuid.NewString()[:8])
+	require.NoError(t, userRepo.Create(u))
+	defer db.Exec(`DELETE FROM users WHERE id = $1`, u.ID)
+
+	got, err := userRepo.Get(u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, u.UserName, got.UserName)
+	assert.Equal(t, u.Email, got.Email)
+	assert.Equal(t, u.PasswordHash, got.PasswordHash)
+	assert.Equal(t, "Round Trip", got.FullName())
+	assert.True(t, got.IsActive)
+	assert.False(t, got.IsAdmin)
+
+	byName, err := userRepo.GetByUsername(u.UserName)
+	require.NoError(t, err)
+	assert.Equal(t, u.ID, byName.ID)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(byName.PasswordHash), []byte("demo#123")))
+
+	got.FirstName, got.LastName, got.IsAdmin = "Updated", "Name", true
+	require.NoError(t, userRepo.Update(u.ID, got))
+
+	updated, err := userRepo.Get(u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Updated Name", updated.FullName())
+	assert.True(t, updated.IsAdmin)
+	assert.Equal(t, u.UserName, updated.UserName, "update must not touch the username")
+	assert.Equal(t, u.PasswordHash, updated.PasswordHash, "update must not touch the password")
+
+	all, err := userRepo.All(0, 0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, all)
+
+	require.NoError(t, userRepo.Delete(u.ID, 0))
+	_, err = userRepo.Get(u.ID)
+	assert.Error(t, err, "soft-deleted users are hidden by default")
+	_, err = userRepo.GetByUsername(u.UserName)
+	assert.Error(t, err, "soft-deleted users can't log in")
+
+	deleted, err := userRepo.WithDeleted().Get(u.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+
+	require.NoError(t, userRepo.Restore(u.ID))
+	restored, err := userRepo.Get(u.ID)
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+}
+
+func TestUserRepoUpsertIsIdempotent(t *testing.T) {
+	db := setUpDB(t)
+	userRepo := NewUserRepo(db)
+
+	u := newTestUser(t, "upsert_"+uuid.NewString()[:8])
+	require.NoError(t, userRepo.Upsert(u))
+	defer db.Exec(`DELETE FROM users WHERE id = $1`, u.ID)
+	firstID := u.ID
+
+	again := newTestUser(t, u.UserName)
+	again.FirstName = "Changed"
+	require.NoError(t, userRepo.Upsert(again))
+	assert.Equal(t, firstID, again.ID, "upsert keeps the id of the existing row")
+
+	got, err := userRepo.GetByUsername(u.UserName)
+	require.NoError(t, err)
+	assert.Equal(t, "Changed", got.FirstName)
+}
+
+func TestUserRepoPurgeKeepsOpenLoans(t *testing.T) {
+	db := setUpDB(t)
+	userRepo := NewUserRepo(db)
+	lending := NewLendingRepo(db, time.Hour)
+
+	owner := newTestUser(t, "owner_"+uuid.NewString()[:8])
+	require.NoError(t, userRepo.Create(owner))
+	defer db.Exec(`DELETE FROM users WHERE id = $1`, owner.ID)
+	borrower := newTestUser(t, "borrower_"+uuid.NewString()[:8])
+	require.NoError(t, userRepo.Create(borrower))
+	defer db.Exec(`DELETE FROM users WHERE id = $1`, borrower.ID)
+
+	book := &model.Book{ID: uuid.New(), UserID: owner.ID, Title: "Lent", Author: "Someone", Status: model.BookActive}
+	require.NoError(t, NewBookRepo(db).Create(book))
+	bookCopy := &model.BookCopy{ID: uuid.New(), BookID: book.ID}
+	require.NoError(t, lending.AddCopy(bookCopy))
+
+	now := time.Now().UTC()
+	loan := &model.Loan{ID: uuid.New(), BookID: book.ID, UserID: borrower.ID, CheckedOutAt: now, DueAt: now.Add(24 * time.Hour)}
+	require.NoError(t, lending.Checkout(loan))
+
+	require.NoError(t, userRepo.Delete(borrower.ID, 0))
+	_, err := userRepo.Purge(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	_, err = lending.GetLoan(loan.ID)
+	assert.NoError(t, err, "users with a copy out are kept, and so is the loan")
+
+	require.NoError(t, lending.Return(loan.ID, time.Now().UTC()))
+	_, err = userRepo.Purge(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	_, err = userRepo.WithDeleted().Get(borrower.ID)
+	assert.Error(t, err, "purged once the copy is back")
+}
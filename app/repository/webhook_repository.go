@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// DeliveryFilter narrows down webhook deliveries; zero fields don't filter.
+type DeliveryFilter struct {
+	SubscriptionID uuid.UUID
+	EventID        int64
+	Status         model.WebhookDeliveryStatus
+}
+
+// WebhookRepo keeps webhook subscriptions and their deliveries. The events
+// come from the outbox_events table, filled by database triggers in the
+// transaction of each change.
+type WebhookRepo struct {
+	db *database.DB
+}
+
+func NewWebhookRepo(db *database.DB) WebhookRepository {
+	return &WebhookRepo{db: db}
+}
+
+func (repo *WebhookRepo) CreateSubscription(s *model.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (id, url, secret, events, description, active, created_by_user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	`
+	_, err := repo.db.Exec(query, s.ID, s.URL, s.Secret, s.Events, s.Description, s.Active, s.CreatedByUserID, time.Now().UTC())
+	return err
+}
+
+func (repo *WebhookRepo) GetSubscription(id uuid.UUID) (*model.WebhookSubscription, error) {
+	s := model.WebhookSubscription{}
+	if err := repo.db.Get(&s, `SELECT * FROM webhook_subscriptions WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Subscriptions lists the subscriptions, oldest first.
+func (repo *WebhookRepo) Subscriptions(limit int, offset uint) ([]*model.WebhookSubscription, error) {
+	var out []*model.WebhookSubscription
+	query := `SELECT * FROM webhook_subscriptions ORDER BY created_at, id LIMIT $1 OFFSET $2`
+	if err := repo.db.Select(&out, query, limit, offset); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateSubscription overwrites the subscription. When s.Version is set,
+// the write only applies to that version of the row and fails with
+// ErrVersionConflict otherwise.
+func (repo *WebhookRepo) UpdateSubscription(s *model.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions SET url = $3, secret = $4, events = $5, description = $6, active = $7,
+			updated_at = $8, version = version + 1
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
+	`
+	res, err := repo.db.Exec(query, s.ID, s.Version, s.URL, s.Secret, s.Events, s.Description, s.Active, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, s.Version)
+}
+
+// DeleteSubscription removes the subscription with its deliveries.
+func (repo *WebhookRepo) DeleteSubscription(id uuid.UUID, version int64) error {
+	res, err := repo.db.Exec(`DELETE FROM webhook_subscriptions WHERE id = $1 AND ($2 = 0 OR version = $2)`, id, version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(res, version)
+}
+
+func (repo *WebhookRepo) GetEvent(id int64) (*model.WebhookEvent, error) {
+	e := model.WebhookEvent{}
+	if err := repo.db.Get(&e, `SELECT * FROM outbox_events WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Fanout creates the deliveries of up to limit undispatched events, one per
+// active subscription that wants the event, and marks the events
+// dispatched. Servers running it at the same time take different events.
+func (repo *WebhookRepo) Fanout(limit int) (int, error) {
+	n := 0
+	err := repo.db.InTx(func(tx *sqlx.Tx) error {
+		var events []*model.WebhookEvent
+		query := `SELECT * FROM outbox_events WHERE dispatched_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+		if err := tx.Select(&events, query, limit); err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		var subs []*model.WebhookSubscription
+		if err := tx.Select(&subs, `SELECT * FROM webhook_subscriptions WHERE active`); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		ids := make([]int64, len(events))
+		for i, e := range events {
+			ids[i] = e.ID
+			for _, s := range subs {
+				if !s.Events.Match(e.Type) {
+					continue
+				}
+				_, err := tx.Exec(`
+					INSERT INTO webhook_deliveries (subscription_id, event_id, next_attempt_at, created_at)
+					VALUES ($1, $2, $3, $3) ON CONFLICT (subscription_id, event_id) DO NOTHING
+				`, s.ID, e.ID, now)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		query, args, err := sqlx.In(`UPDATE outbox_events SET dispatched_at = ? WHERE id IN (?)`, now, ids)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+			return err
+		}
+		n = len(events)
+		return nil
+	})
+	return n, err
+}
+
+// deliverySelect reads deliveries with the type of their event.
+const deliverySelect = `
+	SELECT d.*, e.type AS event_type
+	FROM webhook_deliveries d JOIN outbox_events e ON e.id = d.event_id
+`
+
+// Due claims up to limit pending deliveries to active subscriptions whose
+// next attempt is due at now. Claimed deliveries are not due again until
+// lease has passed, so that a server dying halfway doesn't lose them.
+func (repo *WebhookRepo) Due(now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+				AND subscription_id IN (SELECT id FROM webhook_subscriptions WHERE active)
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = $2
+		FROM due, outbox_events e
+		WHERE d.id = due.id AND e.id = d.event_id
+		RETURNING d.*, e.type AS event_type
+	`
+	var out []*model.WebhookDelivery
+	err := repo.db.InTx(func(tx *sqlx.Tx) error {
+		return tx.Select(&out, query, now, now.Add(lease), limit)
+	})
+	return out, err
+}
+
+// RecordAttempt saves the outcome of a delivery attempt.
+func (repo *WebhookRepo) RecordAttempt(d *model.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4, last_attempt_at = $5,
+			response_status = $6, last_error = $7, delivered_at = $8
+		WHERE id = $1
+	`
+	_, err := repo.db.Exec(query, d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.LastAttemptAt,
+		d.ResponseStatus, d.LastError, d.DeliveredAt)
+	return err
+}
+
+func (repo *WebhookRepo) GetDelivery(id uuid.UUID) (*model.WebhookDelivery, error) {
+	d := model.WebhookDelivery{}
+	if err := repo.db.Get(&d, deliverySelect+` WHERE d.id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Deliveries lists the deliveries matching f, newest first.
+func (repo *WebhookRepo) Deliveries(f DeliveryFilter, limit int, offset uint) ([]*model.WebhookDelivery, error) {
+	where, args := f.where()
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`%s WHERE %s ORDER BY d.created_at DESC, d.id LIMIT $%d OFFSET $%d`,
+		deliverySelect, where, len(args)-1, len(args))
+
+	var out []*model.WebhookDelivery
+	if err := repo.db.Select(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Replay sends the delivery again, right away and with a fresh set of
+// attempts, whatever became of it.
+func (repo *WebhookRepo) Replay(id uuid.UUID) error {
+	res, err := repo.db.Exec(`
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $2, delivered_at = NULL
+		WHERE id = $1
+	`, id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReplayAll replays the deliveries matching f created since since, and
+// returns how many there were. f.Status defaults to dead deliveries.
+func (repo *WebhookRepo) ReplayAll(f DeliveryFilter, since time.Time) (int64, error) {
+	if f.Status == "" {
+		f.Status = model.DeliveryDead
+	}
+	where, args := f.where()
+	args = append(args, time.Now().UTC(), since)
+	query := fmt.Sprintf(`
+		UPDATE webhook_deliveries d SET status = 'pending', attempts = 0, next_attempt_at = $%d, delivered_at = NULL
+		WHERE %s AND d.created_at >= $%d
+	`, len(args)-1, where, len(args))
+	res, err := repo.db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (f DeliveryFilter) where() (string, []interface{}) {
+	where := []string{"TRUE"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.SubscriptionID != uuid.Nil {
+		where = append(where, "d.subscription_id = "+arg(f.SubscriptionID))
+	}
+	if f.EventID != 0 {
+		where = append(where, "d.event_id = "+arg(f.EventID))
+	}
+	if f.Status != "" {
+		where = append(where, "d.status = "+arg(f.Status))
+	}
+	return strings.Join(where, " AND "), args
+}
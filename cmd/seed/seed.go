@@ -0,0 +1,57 @@
+package seed
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/logger"
+	"github.com/byeblogs/go-boilerplate/platform/seeds"
+)
+
+// Seed fills the database with one of the seed profiles.
+//
+//	go run main.go seed -profile=load-test -users=500 -seed=42 -anchor=2026-01-01
+func Seed(args []string) {
+	logger.SetUpLogger()
+	logr := logger.GetLogger()
+
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	profileName := fs.String("profile", "demo", "seed profile: "+strings.Join(seeds.ProfileNames(), ", "))
+	rngSeed := fs.Int64("seed", 1, "seed of the random generator, same seed gives the same data")
+	users := fs.Int("users", -1, "override the number of generated users")
+	projects := fs.Int("projects", -1, "override the number of generated projects per user")
+	tasks := fs.Int("tasks", -1, "override the number of generated tasks per project")
+	anchor := fs.String("anchor", seeds.DefaultAnchor.Format("2006-01-02"), "date (YYYY-MM-DD) the seeded due dates are relative to")
+	_ = fs.Parse(args)
+
+	profile, err := seeds.GetProfile(*profileName)
+	if err != nil {
+		logr.Fatalln(err)
+	}
+	if *users >= 0 {
+		profile.Users = *users
+	}
+	if *projects >= 0 {
+		profile.ProjectsPerUser = *projects
+	}
+	if *tasks >= 0 {
+		profile.TasksPerProject = *tasks
+	}
+
+	anchorAt, err := time.Parse("2006-01-02", *anchor)
+	if err != nil {
+		logr.Fatalf("invalid -anchor: %v", err)
+	}
+
+	if err := database.ConnectDB(); err != nil {
+		logr.Fatalf("failed database setup. error: %v", err)
+	}
+
+	logr.Infof("seeding profile %q (seed=%d, anchor=%s)", profile.Name, *rngSeed, *anchor)
+	if err := seeds.New(database.GetDB(), *rngSeed, anchorAt).Run(profile); err != nil {
+		logr.Fatalf("seeding failed: %v", err)
+	}
+	logr.Infoln("seeding done")
+}
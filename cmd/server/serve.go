@@ -1,16 +1,24 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/byeblogs/go-boilerplate/app/job"
+	"github.com/byeblogs/go-boilerplate/app/model"
 	"github.com/byeblogs/go-boilerplate/pkg/config"
 	"github.com/byeblogs/go-boilerplate/pkg/middleware"
 	"github.com/byeblogs/go-boilerplate/pkg/route"
 	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/events"
 	"github.com/byeblogs/go-boilerplate/platform/logger"
+	"github.com/byeblogs/go-boilerplate/platform/notify"
+	"github.com/byeblogs/go-boilerplate/platform/storage"
+	"github.com/byeblogs/go-boilerplate/platform/webhook"
+	"github.com/byeblogs/go-boilerplate/platform/worker"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -26,6 +34,11 @@ func Serve() {
 		logr.Panicf("failed database setup. error: %v", err)
 	}
 
+	// open the attachment blob store
+	if err := storage.Setup(); err != nil {
+		logr.Panicf("failed storage setup. error: %v", err)
+	}
+
 	// Define Fiber config & app.
 	fiberCfg := config.FiberConfig()
 	app := fiber.New(fiberCfg)
@@ -40,6 +53,48 @@ func Serve() {
 	route.PrivateRoutes(app)
 	route.NotFoundRoute(app)
 
+	// Background jobs, stopped through ctx on shutdown.
+	ctx, stopJobs := context.WithCancel(context.Background())
+	jobs := worker.NewRunner(logr)
+
+	workerCfg := config.WorkerCfg()
+	if workerCfg.SoftDeleteRetention > 0 && workerCfg.PurgeInterval > 0 {
+		jobs.Start(ctx, job.PurgeDeleted(workerCfg.SoftDeleteRetention, workerCfg.PurgeInterval))
+	}
+	if workerCfg.RecurrenceInterval > 0 {
+		jobs.Start(ctx, job.MaterializeRecurrences(workerCfg.RecurrenceWindow, workerCfg.RecurrenceInterval))
+	}
+
+	notifyCfg := config.NotifyCfg()
+	if notifyCfg.ReminderInterval > 0 {
+		notifiers, err := notify.NewRouter(notifyCfg)
+		if err != nil {
+			logr.Panicf("failed notifier setup. error: %v", err)
+		}
+		jobs.Start(ctx, job.SendReminders(notifiers, notifyCfg.ReminderLead, notifyCfg.DefaultChannel, notifyCfg.ReminderInterval))
+	}
+
+	reportsCfg := config.ReportsCfg()
+	if reportsCfg.Materialized && reportsCfg.RefreshInterval > 0 {
+		jobs.Start(ctx, job.RefreshReports(reportsCfg.RefreshInterval))
+	}
+
+	lendingCfg := config.LendingCfg()
+	if lendingCfg.HoldInterval > 0 {
+		jobs.Start(ctx, job.ExpireHolds(lendingCfg.ReservationHold, lendingCfg.HoldInterval))
+	}
+
+	webhookCfg := config.WebhookCfg()
+	if webhookCfg.Interval > 0 {
+		retry := model.WebhookRetry{Base: webhookCfg.RetryBase, Max: webhookCfg.RetryMax, MaxAttempts: webhookCfg.MaxAttempts}
+		client := webhook.NewClient(webhookCfg.Timeout)
+		jobs.Start(ctx, job.DispatchWebhooks(client, retry, webhookCfg.Timeout, webhookCfg.Interval))
+	}
+
+	// wake up the event streams of this server on the events of every server
+	eventsCfg := config.EventsCfg()
+	go events.GetBroker().Listen(ctx, config.BuildPostgresDSN(config.DBCfg()), eventsCfg.ListenRetry, logr)
+
 	// signal channel to capture system calls
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
@@ -49,6 +104,7 @@ func Serve() {
 		// capture sigterm and other system call here
 		<-sigCh
 		logr.Infoln("Shutting down server...")
+		stopJobs()
 		_ = app.Shutdown()
 	}()
 
@@ -58,4 +114,8 @@ func Serve() {
 		logr.Errorf("Oops... server is not running! error: %v", err)
 	}
 
+	// let running jobs finish their current iteration
+	stopJobs()
+	jobs.Wait()
+
 }
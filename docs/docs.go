@@ -474,7 +474,13 @@ const docTemplate = `{
                     "$ref": "#/definitions/model.Meta"
                 },
                 "status": {
-                    "type": "integer"
+                    "type": "string",
+                    "enum": [
+                        "draft",
+                        "active",
+                        "archived",
+                        "withdrawn"
+                    ]
                 },
                 "title": {
                     "type": "string",
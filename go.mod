@@ -6,6 +6,7 @@ toolchain go1.24.1
 
 require (
 	github.com/arsmn/fiber-swagger/v2 v2.6.0
+	github.com/evanphx/json-patch/v5 v5.9.11
 	github.com/form3tech-oss/jwt-go v3.2.2+incompatible
 	github.com/go-playground/validator/v10 v10.5.0
 	github.com/gofiber/fiber/v2 v2.52.1
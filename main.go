@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+
+	"github.com/byeblogs/go-boilerplate/cmd/seed"
 	"github.com/byeblogs/go-boilerplate/cmd/server"
 	_ "github.com/byeblogs/go-boilerplate/docs" // load API Docs files (Swagger)
 	"github.com/byeblogs/go-boilerplate/pkg/config"
@@ -24,5 +27,11 @@ func main() {
 	// setup various configuration for app
 	config.LoadAllConfigs(".env")
 
+	// `go run main.go seed [flags]` fills the database instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		seed.Seed(os.Args[2:])
+		return
+	}
+
 	server.Serve()
 }
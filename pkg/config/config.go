@@ -37,11 +37,26 @@ func LoadAllConfigs(envFile string) {
 
 	LoadApp()
 	LoadDBCfg()
+	LoadWorkerCfg()
+	LoadWorkflowCfg()
+	LoadStorageCfg()
+	LoadNotifyCfg()
+	LoadReportsCfg()
+	LoadLendingCfg()
+	LoadWebhookCfg()
+	LoadEventsCfg()
 }
 
 // FiberConfig func for configuration Fiber app.
 func FiberConfig() fiber.Config {
+	// leave room for the multipart envelope around the largest upload
+	bodyLimit := fiber.DefaultBodyLimit
+	if n := int(StorageCfg().MaxUploadBytes) + 1<<20; n > bodyLimit {
+		bodyLimit = n
+	}
+
 	return fiber.Config{
 		ReadTimeout: time.Second * time.Duration(AppCfg().ReadTimeout),
+		BodyLimit:   bodyLimit,
 	}
 }
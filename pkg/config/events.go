@@ -0,0 +1,29 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// Events holds the configuration of the /api/v1/events streams
+type Events struct {
+	// How often an idle stream sends a keep-alive, so proxies don't close it.
+	Heartbeat time.Duration
+	// How long to wait before listening again after losing the database.
+	ListenRetry time.Duration
+}
+
+var events = &Events{}
+
+// EventsCfg returns the event stream configuration
+func EventsCfg() *Events { return events }
+
+// LoadEventsCfg loads the event stream configuration
+func LoadEventsCfg() {
+	events.Heartbeat = time.Duration(firstInt(15, os.Getenv("EVENTS_HEARTBEAT_SECONDS"))) * time.Second
+	if events.Heartbeat <= 0 {
+		// streams can't do without: proxies close idle connections
+		events.Heartbeat = 15 * time.Second
+	}
+	events.ListenRetry = time.Duration(firstInt(5, os.Getenv("EVENTS_LISTEN_RETRY_SECONDS"))) * time.Second
+}
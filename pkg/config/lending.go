@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// Lending holds the configuration of the book lending endpoints
+type Lending struct {
+	// Loan period used when a checkout doesn't ask for one, in days.
+	LoanDays int
+	// Longest loan period a checkout may ask for, in days.
+	MaxLoanDays int
+	// How long a returned copy is held for the next reservation.
+	ReservationHold time.Duration
+	// How often expired holds are passed down the waitlists; 0 disables it.
+	HoldInterval time.Duration
+}
+
+var lending = &Lending{}
+
+// LendingCfg returns the lending configuration
+func LendingCfg() *Lending { return lending }
+
+// LoadLendingCfg loads the lending configuration
+func LoadLendingCfg() {
+	lending.LoanDays = firstInt(14, os.Getenv("LOAN_DAYS"))
+	lending.MaxLoanDays = firstInt(60, os.Getenv("LOAN_MAX_DAYS"))
+	lending.ReservationHold = time.Duration(firstInt(48, os.Getenv("RESERVATION_HOLD_HOURS"))) * time.Hour
+	lending.HoldInterval = time.Duration(firstInt(15, os.Getenv("RESERVATION_INTERVAL_MINUTES"))) * time.Minute
+}
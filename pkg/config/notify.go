@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Notify holds the configuration of task reminders and their notifiers
+type Notify struct {
+	// Enabled notifiers: log, webhook, email.
+	Channels []string
+	// Channel of users without preferences.
+	DefaultChannel string
+
+	// Reminders go out this long before due_at, unless a user says otherwise.
+	ReminderLead time.Duration
+	// How often the reminder worker runs; 0 disables it.
+	ReminderInterval time.Duration
+
+	WebhookURL    string
+	WebhookSecret string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+}
+
+var notify = &Notify{}
+
+// NotifyCfg returns the reminder configuration
+func NotifyCfg() *Notify { return notify }
+
+// LoadNotifyCfg loads the reminder configuration
+func LoadNotifyCfg() {
+	notify.Channels = nil
+	for _, c := range strings.Split(firstNonEmpty(os.Getenv("NOTIFY_CHANNELS"), "log"), ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			notify.Channels = append(notify.Channels, c)
+		}
+	}
+	notify.DefaultChannel = strings.ToLower(firstNonEmpty(os.Getenv("NOTIFY_DEFAULT_CHANNEL"), "log"))
+
+	notify.ReminderLead = time.Duration(firstInt(24*60, os.Getenv("REMINDER_LEAD_MINUTES"))) * time.Minute
+	notify.ReminderInterval = time.Duration(firstInt(5, os.Getenv("REMINDER_INTERVAL_MINUTES"))) * time.Minute
+
+	notify.WebhookURL = os.Getenv("NOTIFY_WEBHOOK_URL")
+	notify.WebhookSecret = os.Getenv("NOTIFY_WEBHOOK_SECRET")
+
+	notify.SMTPHost = os.Getenv("SMTP_HOST")
+	notify.SMTPPort = firstInt(587, os.Getenv("SMTP_PORT"))
+	notify.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	notify.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	notify.SMTPFrom = os.Getenv("SMTP_FROM")
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// Reports holds the configuration of the reporting endpoints
+type Reports struct {
+	// Serve throughput reports from the task_throughput_daily materialized
+	// view instead of aggregating tasks on every request.
+	Materialized bool
+	// How often the view is refreshed; only used when Materialized is set.
+	RefreshInterval time.Duration
+}
+
+var reports = &Reports{}
+
+// ReportsCfg returns the reporting configuration
+func ReportsCfg() *Reports { return reports }
+
+// LoadReportsCfg loads the reporting configuration
+func LoadReportsCfg() {
+	reports.Materialized = firstBool(false, os.Getenv("REPORTS_MATERIALIZED"))
+	reports.RefreshInterval = time.Duration(firstInt(15, os.Getenv("REPORTS_REFRESH_MINUTES"))) * time.Minute
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Storage holds the configuration of the attachment blob store
+type Storage struct {
+	// "local" (the default) or "s3"
+	Driver   string
+	LocalDir string
+
+	// S3-compatible service, e.g. AWS S3 or MinIO
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+
+	MaxUploadBytes int64
+	// Comma separated content types accepted for task files; empty allows any.
+	AllowedTypes []string
+
+	// Signed download URLs are valid for SignedURLTTL.
+	SigningKey   string
+	SignedURLTTL time.Duration
+}
+
+var storage = &Storage{}
+
+// StorageCfg returns the blob store configuration
+func StorageCfg() *Storage { return storage }
+
+// LoadStorageCfg loads the blob store configuration
+func LoadStorageCfg() {
+	storage.Driver = strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_DRIVER")))
+	storage.LocalDir = firstNonEmpty(os.Getenv("STORAGE_LOCAL_DIR"), "./data/blobs")
+
+	storage.S3Endpoint = os.Getenv("S3_ENDPOINT")
+	storage.S3Region = firstNonEmpty(os.Getenv("S3_REGION"), "us-east-1")
+	storage.S3Bucket = os.Getenv("S3_BUCKET")
+	storage.S3AccessKey = os.Getenv("S3_ACCESS_KEY_ID")
+	storage.S3SecretKey = os.Getenv("S3_SECRET_ACCESS_KEY")
+
+	storage.MaxUploadBytes = int64(firstInt(10, os.Getenv("UPLOAD_MAX_MB"))) << 20
+	storage.AllowedTypes = nil
+	for _, t := range strings.Split(os.Getenv("UPLOAD_ALLOWED_TYPES"), ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			storage.AllowedTypes = append(storage.AllowedTypes, t)
+		}
+	}
+
+	storage.SigningKey = firstNonEmpty(os.Getenv("SIGNED_URL_KEY"), os.Getenv("JWT_SECRET_KEY"))
+	storage.SignedURLTTL = time.Duration(firstInt(15, os.Getenv("SIGNED_URL_TTL_MINUTES"))) * time.Minute
+}
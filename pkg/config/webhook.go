@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// Webhook holds the configuration of the outgoing webhook dispatcher
+type Webhook struct {
+	// How often the outbox is dispatched and due deliveries sent; 0 disables it.
+	Interval time.Duration
+	// How long an endpoint has to answer.
+	Timeout time.Duration
+	// A failed delivery is retried after RetryBase, doubling each time up
+	// to RetryMax, and is dead after MaxAttempts attempts.
+	RetryBase   time.Duration
+	RetryMax    time.Duration
+	MaxAttempts int
+}
+
+var webhook = &Webhook{}
+
+// WebhookCfg returns the webhook configuration
+func WebhookCfg() *Webhook { return webhook }
+
+// LoadWebhookCfg loads the webhook configuration
+func LoadWebhookCfg() {
+	webhook.Interval = time.Duration(firstInt(5, os.Getenv("WEBHOOK_INTERVAL_SECONDS"))) * time.Second
+	webhook.Timeout = time.Duration(firstInt(10, os.Getenv("WEBHOOK_TIMEOUT_SECONDS"))) * time.Second
+	webhook.RetryBase = time.Duration(firstInt(30, os.Getenv("WEBHOOK_RETRY_BASE_SECONDS"))) * time.Second
+	webhook.RetryMax = time.Duration(firstInt(360, os.Getenv("WEBHOOK_RETRY_MAX_MINUTES"))) * time.Minute
+	webhook.MaxAttempts = firstInt(10, os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// Worker holds the configuration of the background jobs started by the server
+type Worker struct {
+	// Soft-deleted rows older than this are purged every PurgeInterval;
+	// 0 for either disables purging.
+	SoftDeleteRetention time.Duration
+	PurgeInterval       time.Duration
+
+	// Recurring tasks are created this far ahead of their start; 0 creates
+	// each one when it starts, or when the previous one is closed.
+	RecurrenceWindow time.Duration
+	// How often the scheduler looks for occurrences to create; 0 disables it.
+	RecurrenceInterval time.Duration
+}
+
+var worker = &Worker{}
+
+// WorkerCfg returns the background job configuration
+func WorkerCfg() *Worker { return worker }
+
+// LoadWorkerCfg loads the background job configuration
+func LoadWorkerCfg() {
+	worker.SoftDeleteRetention = time.Duration(firstInt(30, os.Getenv("SOFT_DELETE_RETENTION_DAYS"))) * 24 * time.Hour
+	worker.PurgeInterval = time.Duration(firstInt(60, os.Getenv("PURGE_INTERVAL_MINUTES"))) * time.Minute
+	worker.RecurrenceWindow = time.Duration(firstInt(24, os.Getenv("RECURRENCE_WINDOW_HOURS"))) * time.Hour
+	worker.RecurrenceInterval = time.Duration(firstInt(15, os.Getenv("RECURRENCE_INTERVAL_MINUTES"))) * time.Minute
+}
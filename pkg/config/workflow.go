@@ -0,0 +1,20 @@
+package config
+
+import "os"
+
+// Workflow holds the configuration of the task state machine
+type Workflow struct {
+	// Allowed task status moves as "from=to,to;from=to", see
+	// model.ParseTaskWorkflow. Empty means model.DefaultTaskTransitions.
+	TaskTransitions string
+}
+
+var workflow = &Workflow{}
+
+// WorkflowCfg returns the task workflow configuration
+func WorkflowCfg() *Workflow { return workflow }
+
+// LoadWorkflowCfg loads the task workflow configuration
+func LoadWorkflowCfg() {
+	workflow.TaskTransitions = os.Getenv("TASK_TRANSITIONS")
+}
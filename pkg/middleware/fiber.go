@@ -5,12 +5,15 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
 func FiberMiddleware(a *fiber.App) {
 	a.Use(
 		// Add CORS to each route.
 		cors.New(),
+		// Tag each request with an id (X-Request-ID), kept in the audit log.
+		requestid.New(),
 		// Add simple logger.
 		logger.New(),
 	)
@@ -24,6 +24,28 @@ func JWTProtected() func(*fiber.Ctx) error {
 	return jwtware.New(jwtwareConfig)
 }
 
+// JWTOptional identifies the caller on public routes, so that they can
+// honor admin-only query flags (e.g. ?include_deleted). A missing, invalid
+// or expired token makes the request anonymous rather than failing it:
+// public routes, logging in included, must keep working for a client that
+// holds a stale token.
+func JWTOptional() func(*fiber.Ctx) error {
+	return jwtware.New(jwtware.Config{
+		SigningKey: []byte(config.AppCfg().JWTSecretKey),
+		ContextKey: "user",
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return c.Next()
+		},
+		SuccessHandler: func(c *fiber.Ctx) error {
+			claims := c.Locals("user").(*jwt.Token).Claims.(jwt.MapClaims)
+			if expires, ok := claims["exp"].(float64); !ok || time.Now().Unix() > int64(expires) {
+				c.Locals("user", nil)
+			}
+			return c.Next()
+		},
+	})
+}
+
 func verifyTokenExpiration(c *fiber.Ctx) error {
 	user := c.Locals("user").(*jwt.Token)
 	claims := user.Claims.(jwt.MapClaims)
@@ -48,3 +70,16 @@ func jwtError(c *fiber.Ctx, err error) error {
 		"msg": err.Error(),
 	})
 }
+
+// TokenFromQuery takes the token of ?access_token when the request has no
+// Authorization header, for clients that can't set headers, such as
+// EventSource and WebSocket in browsers. Use it only before JWTProtected
+// on routes that need it: URLs end up in logs.
+func TokenFromQuery(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderAuthorization) == "" {
+		if token := c.Query("access_token"); token != "" {
+			c.Request().Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		}
+	}
+	return c.Next()
+}
@@ -15,12 +15,112 @@ func PrivateRoutes(a *fiber.App) {
 	adminRoute.Get("/", controller.GetUsers)
 	adminRoute.Get("/:id", controller.GetUser)
 	adminRoute.Put("/:id", controller.UpdateUser)
+	adminRoute.Patch("/:id", controller.PatchUser)
 	adminRoute.Delete("/:id", controller.DeleteUser)
+	adminRoute.Post("/:id/restore", controller.RestoreUser)
 
 	// Book
 	route := a.Group("/api/v1/books", middleware.JWTProtected())
 	route.Post("/", controller.CreateBook)
+	route.Post("/import", controller.ImportBooks)
 	route.Put("/:id", controller.UpdateBook)
+	route.Patch("/:id", controller.PatchBook)
 	route.Delete("/:id", controller.DeleteBook)
+	route.Post("/:id/restore", middleware.IsAdmin, controller.RestoreBook)
+	route.Post("/:id/publish", controller.PublishBook)
+	route.Post("/:id/archive", controller.ArchiveBook)
+	route.Post("/:id/withdraw", controller.WithdrawBook)
+	route.Put("/:id/cover", controller.UploadBookCover)
+	route.Post("/:id/copies", middleware.IsAdmin, controller.CreateBookCopy)
+	route.Delete("/:id/copies/:copy_id", middleware.IsAdmin, controller.WithdrawBookCopy)
+	route.Post("/:id/checkout", controller.CheckoutBook)
+	route.Get("/:id/reservations", controller.GetBookReservations)
+	route.Post("/:id/reservations", controller.ReserveBook)
+	route.Post("/:id/reviews", controller.CreateBookReview)
+
+	// Review
+	reviewRoute := a.Group("/api/v1/reviews", middleware.JWTProtected())
+	reviewRoute.Put("/:id", controller.UpdateReview)
+	reviewRoute.Delete("/:id", controller.DeleteReview)
+
+	// Lending
+	loanRoute := a.Group("/api/v1/loans", middleware.JWTProtected())
+	loanRoute.Get("/", controller.GetLoans)
+	loanRoute.Get("/:id", controller.GetLoan)
+	loanRoute.Post("/:id/return", controller.ReturnLoan)
+	reservationRoute := a.Group("/api/v1/reservations", middleware.JWTProtected())
+	reservationRoute.Get("/", controller.GetReservations)
+	reservationRoute.Delete("/:id", controller.CancelReservation)
+
+	// Project
+	projectRoute := a.Group("/api/v1/projects", middleware.JWTProtected())
+	projectRoute.Patch("/:id", controller.PatchProject)
+	projectRoute.Post("/:id/labels", controller.CreateLabel)
+	projectRoute.Delete("/:id/labels/:label_id", controller.DeleteLabel)
+	projectRoute.Post("/:id/archive", controller.ArchiveProject)
+	projectRoute.Post("/:id/unarchive", controller.UnarchiveProject)
+	projectRoute.Post("/:id/clone", controller.CloneProject)
+	projectRoute.Post("/:id/restore", middleware.IsAdmin, controller.RestoreProject)
+	projectRoute.Post("/:id/comments", controller.CreateProjectComment)
+
+	// Task
+	taskRoute := a.Group("/api/v1/tasks", middleware.JWTProtected())
+	taskRoute.Patch("/:id", controller.PatchTask)
+	taskRoute.Post("/:id/transition", controller.TransitionTask)
+	taskRoute.Post("/:id/dependencies", controller.AddTaskDependency)
+	taskRoute.Delete("/:id/dependencies/:blocker_id", controller.RemoveTaskDependency)
+	taskRoute.Put("/:id/recurrence", controller.SetTaskRecurrence)
+	taskRoute.Delete("/:id/recurrence", controller.DeleteTaskRecurrence)
+	taskRoute.Post("/:id/move", controller.MoveTask)
+	taskRoute.Post("/:id/restore", middleware.IsAdmin, controller.RestoreTask)
+	taskRoute.Post("/:id/comments", controller.CreateTaskComment)
+	taskRoute.Post("/:id/attachments", controller.UploadTaskAttachments)
+	taskRoute.Post("/:id/time-entries", controller.CreateTaskTimeEntry)
+	taskRoute.Post("/:id/timer/start", controller.StartTaskTimer)
+
+	// Comment
+	commentRoute := a.Group("/api/v1/comments", middleware.JWTProtected())
+	commentRoute.Put("/:id", controller.UpdateComment)
+	commentRoute.Delete("/:id", controller.DeleteComment)
+
+	// Attachment
+	attachmentRoute := a.Group("/api/v1/attachments", middleware.JWTProtected())
+	attachmentRoute.Delete("/:id", controller.DeleteAttachment)
+
+	// Time tracking
+	timeEntryRoute := a.Group("/api/v1/time-entries", middleware.JWTProtected())
+	timeEntryRoute.Get("/", controller.GetTimeEntries)
+	timeEntryRoute.Get("/export", controller.ExportTimeEntries)
+	timeEntryRoute.Get("/summary", controller.GetTimeSummary)
+	timeEntryRoute.Get("/:id", controller.GetTimeEntry)
+	timeEntryRoute.Put("/:id", controller.UpdateTimeEntry)
+	timeEntryRoute.Delete("/:id", controller.DeleteTimeEntry)
+	timerRoute := a.Group("/api/v1/timer", middleware.JWTProtected())
+	timerRoute.Get("/", controller.GetTimer)
+	timerRoute.Post("/stop", controller.StopTimer)
+
+	// Notification
+	notificationRoute := a.Group("/api/v1/notifications", middleware.JWTProtected())
+	notificationRoute.Get("/preferences", controller.GetNotificationPreferences)
+	notificationRoute.Put("/preferences", controller.UpdateNotificationPreferences)
+
+	// Audit
+	auditRoute := a.Group("/api/v1/audit", middleware.JWTProtected(), middleware.IsAdmin)
+	auditRoute.Get("/", controller.GetAuditEvents)
+	auditRoute.Get("/:id", controller.GetAuditEvent)
+
+	// Live events, over SSE or WebSocket
+	a.Get("/api/v1/events", middleware.TokenFromQuery, middleware.JWTProtected(), controller.GetEvents)
+
+	// Webhooks
+	webhookRoute := a.Group("/api/v1/webhooks", middleware.JWTProtected(), middleware.IsAdmin)
+	webhookRoute.Get("/", controller.GetWebhooks)
+	webhookRoute.Post("/", controller.CreateWebhook)
+	webhookRoute.Post("/deliveries/:id/replay", controller.ReplayWebhookDelivery)
+	webhookRoute.Get("/:id", controller.GetWebhook)
+	webhookRoute.Put("/:id", controller.UpdateWebhook)
+	webhookRoute.Delete("/:id", controller.DeleteWebhook)
+	webhookRoute.Get("/:id/deliveries", controller.GetWebhookDeliveries)
+	webhookRoute.Post("/:id/replay", controller.ReplayWebhookDeliveries)
 
 }
@@ -13,7 +13,9 @@ import (
 	"github.com/byeblogs/go-boilerplate/app/model"
 	"github.com/byeblogs/go-boilerplate/app/repository"
 	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/byeblogs/go-boilerplate/pkg/middleware"
 	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/byeblogs/go-boilerplate/platform/events"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -189,7 +191,7 @@ func TestPrivateBookRoutes(t *testing.T) {
 		Title:  RandomWord(3),
 		Author: RandomWord(2),
 		UserID: userID,
-		Status: RandomInt(0, 1),
+		Status: model.BookActive,
 		Meta:   meta,
 	}
 
@@ -247,6 +249,24 @@ func TestPrivateBookRoutes(t *testing.T) {
 			expectedError: false,
 			expectedCode:  200,
 		},
+		{
+			description:   "patch book title",
+			route:         "/api/v1/books/" + bookIDS[RandomInt(0, 9)],
+			method:        "PATCH",
+			tokenString:   "Bearer " + token,
+			body:          strings.NewReader(`{"title": "Patched title"}`),
+			expectedError: false,
+			expectedCode:  200,
+		},
+		{
+			description:   "patch book into an invalid state",
+			route:         "/api/v1/books/" + bookIDS[RandomInt(0, 9)],
+			method:        "PATCH",
+			tokenString:   "Bearer " + token,
+			body:          strings.NewReader(`{"meta": {"rating": 15}}`),
+			expectedError: false,
+			expectedCode:  400,
+		},
 		{
 			description:   "delete a book",
 			route:         "/api/v1/books/" + bookIDS[RandomInt(0, 9)],
@@ -287,13 +307,221 @@ func TestPrivateBookRoutes(t *testing.T) {
 
 }
 
+func TestDeleteUserCascade(t *testing.T) {
+	setUpBook()
+	defer tearDownBook()
+
+	db := database.GetDB()
+	project := &model.Project{ID: uuid.New(), OwnerUserID: userID, Name: "Owned project"}
+	assert.NoError(t, repository.NewProjectRepo(db).Create(project))
+	bookID := uuid.MustParse(bookIDS[0])
+
+	userRepo := repository.NewUserRepo(db)
+	assert.NoError(t, userRepo.Delete(userID, 0))
+	_, err := repository.NewBookRepo(db).Get(bookID)
+	assert.Error(t, err, "books go with their owner")
+	_, err = repository.NewProjectRepo(db).Get(project.ID)
+	assert.Error(t, err, "projects go with their owner")
+
+	assert.NoError(t, userRepo.Restore(userID))
+	_, err = repository.NewBookRepo(db).Get(bookID)
+	assert.NoError(t, err, "books come back with their owner")
+	_, err = repository.NewProjectRepo(db).Get(project.ID)
+	assert.NoError(t, err, "projects come back with their owner")
+}
+
+func TestAuditRoutes(t *testing.T) {
+	setUpBook()
+	defer tearDownBook()
+
+	app := fiber.New()
+	middleware.FiberMiddleware(app)
+	PrivateRoutes(app)
+
+	bookID := bookIDS[0]
+	req := httptest.NewRequest("PATCH", "/api/v1/books/"+bookID, strings.NewReader(`{"title": "Audited title"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(fiber.HeaderXRequestID, "audit-test")
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "patch book")
+
+	route := "/api/v1/audit?resource_type=book&action=update&resource_id=" + bookID
+	req = httptest.NewRequest("GET", route, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode, "audit log is for admins")
+
+	req = httptest.NewRequest("GET", route, nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "get audit log")
+
+	var body struct {
+		Events []*model.AuditEvent `json:"events"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	if assert.Len(t, body.Events, 1) {
+		e := body.Events[0]
+		assert.NotNil(t, e.ActorUserID, "actor comes from the token")
+		assert.NotEqual(t, userID, *e.ActorUserID, "patched by the non-admin user")
+		assert.Equal(t, "audit-test", *e.RequestID)
+		assert.Contains(t, e.Before.String(), `"title"`)
+		assert.Contains(t, e.After.String(), `"Audited title"`)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/audit?action=rename", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode, "unknown action")
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	setUpBook()
+	defer tearDownBook()
+
+	app := fiber.New()
+	PrivateRoutes(app)
+
+	body := `{"url": "https://example.com/hooks", "events": ["book.updated"]}`
+	req := httptest.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode, "webhooks are for admins")
+
+	req = httptest.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(`{"url": "https://example.com/hooks", "events": ["user.created"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode, "unknown event")
+
+	req = httptest.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "create webhook")
+
+	var created struct {
+		Webhook *model.WebhookSubscription `json:"webhook"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	sub := created.Webhook
+	assert.Len(t, sub.Secret, 64, "generated secret is shown once")
+	assert.True(t, sub.Active)
+	defer database.GetDB().Exec(`DELETE FROM webhook_subscriptions WHERE id = $1`, sub.ID)
+
+	req = httptest.NewRequest("GET", "/api/v1/webhooks/"+sub.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "get webhook")
+	respBody, _ := io.ReadAll(resp.Body)
+	assert.NotContains(t, string(respBody), sub.Secret)
+
+	req = httptest.NewRequest("PATCH", "/api/v1/books/"+bookIDS[0], strings.NewReader(`{"title": "Published title"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "patch book")
+
+	_, err = repository.NewWebhookRepo(database.GetDB()).Fanout(1000)
+	assert.NoError(t, err)
+
+	route := "/api/v1/webhooks/" + sub.ID.String() + "/deliveries"
+	req = httptest.NewRequest("GET", route+"?status=pending", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "get deliveries")
+
+	var listed struct {
+		Deliveries []*model.WebhookDelivery `json:"deliveries"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	if assert.Len(t, listed.Deliveries, 1) {
+		assert.Equal(t, "book.updated", listed.Deliveries[0].EventType)
+
+		req = httptest.NewRequest("POST", "/api/v1/webhooks/deliveries/"+listed.Deliveries[0].ID.String()+"/replay", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		resp, err = app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode, "replay delivery")
+	}
+
+	req = httptest.NewRequest("GET", route+"?status=lost", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode, "unknown status")
+
+	req = httptest.NewRequest("DELETE", "/api/v1/webhooks/"+sub.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	req.Header.Set(fiber.HeaderIfMatch, controller.ETag(sub.Version+1))
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 412, resp.StatusCode, "stale version")
+}
+
+func TestEventRoutes(t *testing.T) {
+	setUpUser()
+	defer tearDownUser()
+
+	app := fiber.New()
+	PrivateRoutes(app)
+
+	since, err := repository.NewEventRepo(database.GetDB()).LastID()
+	assert.NoError(t, err)
+	project := &model.Project{ID: uuid.New(), OwnerUserID: userID, Name: "Live project"}
+	assert.NoError(t, repository.NewProjectRepo(database.GetDB()).Create(project))
+
+	// with the broker closed, as on shutdown, streams end after the backlog
+	events.GetBroker().Close()
+
+	req := httptest.NewRequest("GET", "/api/v1/events", nil)
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode, "without a token")
+
+	req = httptest.NewRequest("GET", "/api/v1/events?types=user.*&access_token="+adminToken, nil)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode, "unknown event")
+
+	req = httptest.NewRequest("GET", "/api/v1/events?access_token="+adminToken, nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(since, 10))
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "owner's stream")
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "event: project.created\n")
+	assert.Contains(t, string(body), project.ID.String())
+
+	req = httptest.NewRequest("GET", "/api/v1/events?last_event_id="+strconv.FormatInt(since, 10), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "other user's stream")
+	body, _ = io.ReadAll(resp.Body)
+	assert.NotContains(t, string(body), project.ID.String(), "can't see the project")
+}
+
 func setUpUser() {
 	config.LoadAllConfigs("../../.env.test")
 	if err := database.ConnectDB(); err != nil {
 		panic(err)
 	}
 
-	users := []model.User{
+	users := []model.CreateUser{
 		{
 			IsAdmin:   true,
 			IsActive:  true,
@@ -326,9 +554,10 @@ func setUpUser() {
 		dbUser := &model.User{
 			ID:           uuid.New(),
 			Email:        u.Email,
-			Name:         u.UserName,
-			Username:     &u.UserName,
-			PasswordHash: &hash,
+			UserName:     u.UserName,
+			PasswordHash: hash,
+			FirstName:    u.FirstName,
+			LastName:     u.LastName,
 			IsActive:     true,
 			IsAdmin:      u.IsAdmin,
 		}
@@ -379,7 +608,7 @@ func setUpBook() {
 			Title:  RandomWord(3),
 			Author: RandomWord(2),
 			UserID: userID,
-			Status: RandomInt(0, 1),
+			Status: model.BookActive,
 			Meta:   meta,
 		}
 		if err := bookRepo.Create(book); err != nil {
@@ -392,7 +621,7 @@ func setUpBook() {
 
 func tearDownUser() {
 	db := database.GetDB()
-	_, err := db.Exec(`TRUNCATE TABLE "user" CASCADE;`)
+	_, err := db.Exec(`TRUNCATE TABLE users CASCADE;`)
 	if err != nil {
 		panic(err)
 	}
@@ -405,7 +634,7 @@ func tearDownBook() {
 	if err != nil {
 		panic(err)
 	}
-	_, err = db.Exec(`TRUNCATE TABLE "user" CASCADE;`)
+	_, err = db.Exec(`TRUNCATE TABLE users CASCADE;`)
 	if err != nil {
 		panic(err)
 	}
@@ -2,17 +2,25 @@ package route
 
 import (
 	"github.com/byeblogs/go-boilerplate/app/controller"
+	"github.com/byeblogs/go-boilerplate/pkg/middleware"
 	"github.com/gofiber/fiber/v2"
 )
 
 // PublicRoutes func for describe group of public route.
 func PublicRoutes(a *fiber.App) {
-	// Create route group.
-	route := a.Group("/api/v1")
+	// Create route group. A token is optional here; when present it lets
+	// admins use flags such as ?include_deleted=true.
+	route := a.Group("/api/v1", middleware.JWTOptional())
 
 	route.Post("/token/new", controller.GetNewAccessToken)
 	route.Get("/books", controller.GetBooks)
+	route.Get("/books/isbn/:isbn", controller.GetBookByISBN)
+	route.Get("/books/export", controller.ExportBooks)
 	route.Get("/books/:id", controller.GetBook)
+	route.Get("/books/:id/cover", controller.GetBookCover)
+	route.Get("/books/:id/copies", controller.GetBookCopies)
+	route.Get("/books/:id/reviews", controller.GetBookReviews)
+	route.Get("/reviews/:id", controller.GetReview)
 
 	// Users
 	route.Get("/users", controller.GetUsers)
@@ -27,14 +35,34 @@ func PublicRoutes(a *fiber.App) {
 	route.Post("/projects", controller.CreateProject)
 	route.Put("/projects/:id", controller.UpdateProject)
 	route.Delete("/projects/:id", controller.DeleteProject)
+	route.Get("/projects/:id/board", controller.GetProjectBoard)
+	route.Get("/projects/:id/stats", controller.GetProjectStats)
+	route.Get("/projects/:id/comments", controller.GetProjectComments)
+	route.Get("/projects/:id/labels", controller.GetLabels)
 
 	// Tasks
 	route.Get("/tasks", controller.GetTasks)
 	route.Get("/tasks/:id", controller.GetTask)
 	route.Post("/tasks", controller.CreateTask)
 	route.Put("/tasks/:id", controller.UpdateTask)
+	route.Get("/tasks/:id/comments", controller.GetTaskComments)
+	route.Get("/tasks/:id/attachments", controller.GetTaskAttachments)
+	route.Get("/tasks/:id/recurrence", controller.GetTaskRecurrence)
+	route.Get("/tasks/:id/tree", controller.GetTaskTree)
+	route.Get("/tasks/:id/dependencies", controller.GetTaskDependencies)
 	route.Delete("/tasks/:id", controller.DeleteTask)
 
+	// Comments
+	route.Get("/comments/:id", controller.GetComment)
+	route.Get("/comments/:id/revisions", controller.GetCommentRevisions)
+
+	// Attachments; content is served to signed URLs only
+	route.Get("/attachments/:id", controller.GetAttachment)
+	route.Get("/attachments/:id/content", controller.GetAttachmentContent)
+
+	// Reports
+	route.Get("/reports/throughput", controller.GetThroughput)
+
 	// UI
 
 	RegisterUI(route)
@@ -5,12 +5,14 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/byeblogs/go-boilerplate/app/controller"
 	"github.com/byeblogs/go-boilerplate/app/model"
 	"github.com/byeblogs/go-boilerplate/app/repository"
 	"github.com/byeblogs/go-boilerplate/pkg/config"
 	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/form3tech-oss/jwt-go"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -115,13 +117,81 @@ func TestPublicRoutes(t *testing.T) {
 
 }
 
+func TestPublicRoutesStaleToken(t *testing.T) {
+	setUpTPuR()
+	defer tearDownTPuR()
+
+	app := fiber.New()
+	PublicRoutes(app)
+
+	claims := jwt.MapClaims{
+		"user_id": uuid.New().String(),
+		"admin":   true,
+		"exp":     time.Now().Add(-time.Hour).Unix(),
+	}
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.AppCfg().JWTSecretKey))
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/api/v1/token/new", strings.NewReader(`{"username": "hrshadhin", "password": "demo#123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+expired)
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "log in again with an expired token")
+
+	req = httptest.NewRequest("GET", "/api/v1/books?include_deleted=true", nil)
+	req.Header.Set("Authorization", "Bearer not-a-token")
+	resp, err = app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode, "malformed token is anonymous")
+}
+
+// TestPublicRoutesAnonymousWrites checks that the writes registered behind
+// a token are not reachable without one.
+func TestPublicRoutesAnonymousWrites(t *testing.T) {
+	config.LoadAllConfigs("../../.env.test")
+
+	app := fiber.New()
+	PublicRoutes(app)
+	PrivateRoutes(app)
+
+	id := uuid.New().String()
+	writes := []struct {
+		method string
+		route  string
+	}{
+		{"PATCH", "/api/v1/users/" + id},
+		{"PATCH", "/api/v1/projects/" + id},
+		{"PATCH", "/api/v1/tasks/" + id},
+		{"POST", "/api/v1/tasks/" + id + "/transition"},
+		{"POST", "/api/v1/projects/" + id + "/labels"},
+		{"DELETE", "/api/v1/projects/" + id + "/labels/" + id + ""},
+		{"POST", "/api/v1/tasks/" + id + "/dependencies"},
+		{"DELETE", "/api/v1/tasks/" + id + "/dependencies/" + id + ""},
+		{"PUT", "/api/v1/tasks/" + id + "/recurrence"},
+		{"DELETE", "/api/v1/tasks/" + id + "/recurrence"},
+		{"POST", "/api/v1/projects/" + id + "/archive"},
+		{"POST", "/api/v1/projects/" + id + "/unarchive"},
+		{"POST", "/api/v1/projects/" + id + "/clone"},
+		{"POST", "/api/v1/tasks/" + id + "/move"},
+	}
+
+	for _, w := range writes {
+		req := httptest.NewRequest(w.method, w.route, strings.NewReader(`{"is_admin": true}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equalf(t, 400, resp.StatusCode, "%s %s without a token", w.method, w.route)
+	}
+}
+
 func setUpTPuR() {
 	config.LoadAllConfigs("../../.env.test")
 	if err := database.ConnectDB(); err != nil {
 		panic(err)
 	}
 
-	users := []model.User{
+	users := []model.CreateUser{
 		{
 			IsAdmin:   false,
 			IsActive:  true,
@@ -154,9 +224,10 @@ func setUpTPuR() {
 		dbUser := &model.User{
 			ID:           uuid.New(),
 			Email:        u.Email,
-			Name:         u.UserName,
-			Username:     &u.UserName,
-			PasswordHash: &hash,
+			UserName:     u.UserName,
+			PasswordHash: hash,
+			FirstName:    u.FirstName,
+			LastName:     u.LastName,
 			IsActive:     true,
 			IsAdmin:      u.IsAdmin,
 		}
@@ -170,7 +241,7 @@ func setUpTPuR() {
 
 func tearDownTPuR() {
 	db := database.GetDB()
-	_, err := db.Exec(`TRUNCATE TABLE "user" CASCADE;`)
+	_, err := db.Exec(`TRUNCATE TABLE users CASCADE;`)
 	if err != nil {
 		panic(err)
 	}
@@ -535,6 +535,39 @@ const htmlShellTemplate = `<!doctype html>
       return await res.json()
     }
 
+    // Live updates: with a token (?token= on the page URL, then kept in
+    // localStorage), the page reloads when /api/v1/events reports changes
+    // to the given resources. EventSource resumes by itself after a drop.
+    const eventKinds = ['created','updated','status_changed','deleted','restored','purged']
+    function liveReload(resources, reload){
+      const pill = $('#live')
+      const show = (s) => { if(pill) pill.textContent = s }
+
+      let token = new URLSearchParams(location.search).get('token')
+      try{
+        if(token) localStorage.setItem('__forgeon_token__', token)
+        else token = localStorage.getItem('__forgeon_token__')
+      }catch(e){}
+      if(!token || !window.EventSource){
+        show('off')
+        return
+      }
+
+      const types = resources.map((r) => r + '.*').join(',')
+      const es = new EventSource('/api/v1/events?types=' + encodeURIComponent(types) + '&access_token=' + encodeURIComponent(token))
+      let timer = null
+      const onEvent = () => {
+        // one reload for a burst of changes
+        clearTimeout(timer)
+        timer = setTimeout(reload, 300)
+      }
+      for(const r of resources){
+        for(const kind of eventKinds) es.addEventListener(r + '.' + kind, onEvent)
+      }
+      es.onopen = () => show('on')
+      es.onerror = () => show(es.readyState === EventSource.CLOSED ? 'off' : 'reconnecting…')
+    }
+
     {{SCRIPT}}
   </script>
 </body>
@@ -701,18 +734,19 @@ func UITasksPage(c *fiber.Ctx) error {
       <span class="muted">Status: <strong id="status">…</strong></span>
     </div>
     <div class="hint">API URL</div>
-    <input id="api" value="/api/v1/tasks?page=1&page_size=10" />
+    <input id="api" value="/api/v1/tasks?page=1&page_size=10&sort=position" />
     <div class="pill-row">
       <div class="pill">count: <strong id="count">0</strong></div>
       <div class="pill">page: <strong id="page">1</strong></div>
       <div class="pill">page_size: <strong id="page_size">10</strong></div>
+      <div class="pill">live: <strong id="live">…</strong></div>
     </div>
     <div class="muted" style="margin-top:.35rem">Rendered: <code>%s</code></div>
   </div>`, html.EscapeString(now))
 
 	body := uiTop(
 		"Tasks dashboard",
-		"Calls /api/v1/tasks and renders real data.",
+		"Calls /api/v1/tasks and renders real data, live from /api/v1/events.",
 		uiNav("/api/v1/ui/tasks"),
 		right,
 	) + `
@@ -779,6 +813,7 @@ async function load(){
 }
 $('#reload').addEventListener('click', load)
 load()
+liveReload(['task'], load)
 `
 	return c.Status(200).Type("html", "utf-8").SendString(htmlShell("Forgeon · Tasks UI", body, script))
 }
@@ -833,7 +868,7 @@ function render(payload){
     const flags = []
     if (u.is_admin) flags.push('admin')
     if (u.is_active) flags.push('active')
-    if (u.is_deleted) flags.push('deleted')
+    if (u.deleted_at) flags.push('deleted')
     return ''
       + '<tr>'
       + ' <td>' + htmlEscape(u.username || '') + '<div class="muted"><code>' + htmlEscape(u.id || '') + '</code></div></td>'
@@ -874,11 +909,14 @@ func UIProjectsPage(c *fiber.Ctx) error {
     </div>
     <div class="hint">API URL</div>
     <input id="api" value="/api/v1/projects?page=1&page_size=10" />
+    <div class="pill-row">
+      <div class="pill">live: <strong id="live">…</strong></div>
+    </div>
   </div>`
 
 	body := uiTop(
 		"Projects list",
-		"Calls /api/v1/projects and renders real data.",
+		"Calls /api/v1/projects and renders real data, live from /api/v1/events.",
 		uiNav("/api/v1/ui/projects"),
 		right,
 	) + `
@@ -939,6 +977,7 @@ async function load(){
 }
 $('#reload').addEventListener('click', load)
 load()
+liveReload(['project'], load)
 `
 	return c.Type("html", "utf-8").SendString(htmlShell("Forgeon · Projects UI", body, script))
 }
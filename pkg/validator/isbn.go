@@ -0,0 +1,78 @@
+package validator
+
+import "strings"
+
+// NormalizeISBN checks an ISBN-10 or ISBN-13, with or without hyphens and
+// spaces, and returns it as the 13 digits of its ISBN-13 form, so both
+// forms of a book's number compare equal. ok is false for a malformed
+// number or a wrong check digit.
+func NormalizeISBN(s string) (isbn string, ok bool) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		if r == 'x' {
+			return 'X'
+		}
+		return r
+	}, s)
+
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", false
+		}
+		return isbn13("978" + digits[:9]), true
+	case 13:
+		if !validISBN13(digits) {
+			return "", false
+		}
+		return digits, true
+	default:
+		return "", false
+	}
+}
+
+// validISBN10 checks the mod 11 checksum; the check digit may be X (10).
+func validISBN10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var d int
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			d = int(s[i] - '0')
+		case s[i] == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * d
+	}
+	return sum%11 == 0
+}
+
+// validISBN13 checks the EAN-13 checksum of a 978 or 979 number.
+func validISBN13(s string) bool {
+	if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+		return false
+	}
+	for i := 0; i < 13; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return isbn13(s[:12]) == s
+}
+
+// isbn13 appends the check digit to the first 12 digits of an ISBN-13.
+func isbn13(s string) string {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(s[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return s + string(rune('0'+(10-sum%10)%10))
+}
@@ -0,0 +1,40 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	valid := map[string]string{
+		"978-0-306-40615-7": "9780306406157",
+		"9780306406157":     "9780306406157",
+		"0-306-40615-2":     "9780306406157",
+		"0306406152":        "9780306406157",
+		"0-8044-2957-X":     "9780804429573",
+		"080442957x":        "9780804429573",
+		"979 10 90636 07 1": "9791090636071",
+	}
+	for in, want := range valid {
+		got, ok := NormalizeISBN(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "978-0-306-40615-8", "0-306-40615-3", "X306406152", "1234567890123", "97803064061", "978030640615a"} {
+		_, ok := NormalizeISBN(in)
+		assert.False(t, ok, in)
+	}
+}
+
+func TestISBNTag(t *testing.T) {
+	type book struct {
+		ISBN *string `validate:"omitempty,isbn"`
+	}
+	good, bad := "0-306-40615-2", "0-306-40615-3"
+	v := NewValidator()
+	assert.NoError(t, v.Struct(book{}))
+	assert.NoError(t, v.Struct(book{ISBN: &good}))
+	assert.Error(t, v.Struct(book{ISBN: &bad}))
+}
@@ -20,6 +20,12 @@ func NewValidator() *vldtr.Validate {
 		return false
 	})
 
+	// ISBN-10 or ISBN-13 with a valid check digit, see NormalizeISBN.
+	_ = validate.RegisterValidation("isbn", func(fl vldtr.FieldLevel) bool {
+		_, ok := NormalizeISBN(fl.Field().String())
+		return ok
+	})
+
 	return validate
 }
 
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 
@@ -9,7 +10,19 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-type DB struct{ *sqlx.DB }
+type DB struct {
+	*sqlx.DB
+	audit *Audit
+}
+
+// Audit says who is behind the writes made through a DB. The audit
+// triggers of the database read it from settings local to the transaction
+// of the write (see the audit_events migration).
+type Audit struct {
+	ActorID   string // user id; empty for the system
+	RequestID string
+	IP        string
+}
 
 var defaultDB = &DB{}
 
@@ -39,3 +52,42 @@ func (db *DB) connect(cfg *config.DB) (err error) {
 
 func GetDB() *DB       { return defaultDB }
 func ConnectDB() error { return defaultDB.connect(config.DBCfg()) }
+
+// WithAudit returns a copy of db whose writes are recorded as made by a.
+func (db *DB) WithAudit(a Audit) *DB {
+	return &DB{DB: db.DB, audit: &a}
+}
+
+// InTx runs fn in a transaction that is committed when fn returns nil and
+// rolled back otherwise.
+func (db *DB) InTx(fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	if db.audit != nil {
+		const query = `SELECT set_config('audit.actor_id', $1, true), set_config('audit.request_id', $2, true), set_config('audit.ip', $3, true)`
+		if _, err := tx.Exec(query, db.audit.ActorID, db.audit.RequestID, db.audit.IP); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Exec runs a single statement. With WithAudit it runs in a transaction of
+// its own, so that the audit settings apply to it.
+func (db *DB) Exec(query string, args ...interface{}) (res sql.Result, err error) {
+	if db.audit == nil {
+		return db.DB.Exec(query, args...)
+	}
+	err = db.InTx(func(tx *sqlx.Tx) error {
+		res, err = tx.Exec(query, args...)
+		return err
+	})
+	return res, err
+}
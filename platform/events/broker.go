@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/platform/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// Channel is what the outbox notifies, with the event id, when an event
+// is committed (see the live_events migration).
+const Channel = "outbox_events"
+
+// Broker wakes up the event streams of this server when events may have
+// been committed. It only says that something happened: streams read the
+// events themselves, which keeps what each one sees to what it may see.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[chan struct{}]struct{}
+	closed bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: map[chan struct{}]struct{}{}}
+}
+
+var defaultBroker = NewBroker()
+
+// GetBroker returns the broker of the server.
+func GetBroker() *Broker { return defaultBroker }
+
+// Subscribe returns a channel that receives a value when events may have
+// been committed, and is closed when the broker stops. Wake-ups coalesce,
+// so a slow reader gets one for several events. The returned function
+// unsubscribes.
+func (b *Broker) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subs[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish wakes up every subscriber.
+func (b *Broker) Publish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- struct{}{}:
+		default: // already woken up
+		}
+	}
+}
+
+// Close closes the channel of every subscriber, and of those to come.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		close(ch)
+	}
+	b.subs = map[chan struct{}]struct{}{}
+	b.closed = true
+}
+
+// Listen LISTENs on Channel through a connection of its own to dsn and
+// publishes every notification, until ctx is cancelled; it then closes
+// the broker. A lost connection is opened again after retry, and
+// subscribers are woken up, since notifications may have been missed in
+// between.
+func (b *Broker) Listen(ctx context.Context, dsn string, retry time.Duration, logr *logger.Logger) {
+	defer b.Close()
+
+	for ctx.Err() == nil {
+		err := b.listen(ctx, dsn)
+		if ctx.Err() != nil {
+			return
+		}
+		logr.Errorf("events: listening on %s: %v (retrying in %s)", Channel, err, retry)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context, dsn string) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	b.Publish()
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		b.Publish()
+	}
+}
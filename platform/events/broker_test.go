@@ -0,0 +1,39 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrokerPublish(t *testing.T) {
+	b := NewBroker()
+	first, unsubscribe := b.Subscribe()
+	second, _ := b.Subscribe()
+
+	b.Publish()
+	b.Publish()
+	assert.Len(t, first, 1, "wake-ups coalesce")
+	assert.Len(t, second, 1)
+	<-first
+
+	unsubscribe()
+	unsubscribe()
+	b.Publish()
+	_, ok := <-first
+	assert.False(t, ok, "closed on unsubscribe")
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker()
+	ch, unsubscribe := b.Subscribe()
+
+	b.Close()
+	_, ok := <-ch
+	assert.False(t, ok, "closed with the broker")
+	unsubscribe()
+
+	late, _ := b.Subscribe()
+	_, ok = <-late
+	assert.False(t, ok, "subscribing to a closed broker")
+}
@@ -12,11 +12,12 @@ type Logger struct {
 	*logrus.Logger
 }
 
-var logger = &Logger{}
+// logger is set up in place, so that loggers taken with GetLogger before
+// SetUpLogger runs, e.g. in package variables, log with its settings.
+var logger = &Logger{logrus.New()}
 
 // SetUpLogger settings
 func SetUpLogger() {
-	logger = &Logger{logrus.New()}
 	logger.Formatter = &logrus.JSONFormatter{}
 	logger.SetOutput(os.Stdout)
 
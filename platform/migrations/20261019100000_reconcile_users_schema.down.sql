@@ -0,0 +1,19 @@
+ALTER TABLE public.users
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN updated_at DROP NOT NULL,
+    ALTER COLUMN updated_at DROP DEFAULT,
+    ALTER COLUMN is_active DROP NOT NULL,
+    ALTER COLUMN is_admin DROP NOT NULL,
+    ALTER COLUMN is_deleted DROP NOT NULL;
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS password VARCHAR(100);
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS name text;
+
+UPDATE public.users
+SET password = password_hash,
+    name     = btrim(first_name || ' ' || last_name);
+
+ALTER TABLE public.users ALTER COLUMN password SET NOT NULL;
+ALTER TABLE public.users ALTER COLUMN name SET NOT NULL;
+
+ALTER TABLE public.users DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,40 @@
+-- Settle on one users schema:
+--   username / password_hash / first_name / last_name
+-- The legacy "password" and "name" columns are folded into the canonical ones.
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS password_hash VARCHAR(255);
+
+-- Backfill hashes that were stored in the legacy column.
+UPDATE public.users
+SET password_hash = password
+WHERE password_hash IS NULL;
+
+-- Backfill first/last name from the legacy display name.
+UPDATE public.users
+SET first_name = split_part(btrim(name), ' ', 1),
+    last_name  = btrim(substr(btrim(name), length(split_part(btrim(name), ' ', 1)) + 1))
+WHERE coalesce(first_name, '') = ''
+  AND coalesce(last_name, '') = ''
+  AND coalesce(name, '') <> '';
+
+ALTER TABLE public.users DROP COLUMN IF EXISTS password;
+ALTER TABLE public.users DROP COLUMN IF EXISTS name;
+
+ALTER TABLE public.users ALTER COLUMN password_hash SET NOT NULL;
+
+-- Timestamps and flags are always present on a user.
+UPDATE public.users SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE public.users SET updated_at = created_at WHERE updated_at IS NULL;
+UPDATE public.users
+SET is_active  = coalesce(is_active, TRUE),
+    is_admin   = coalesce(is_admin, FALSE),
+    is_deleted = coalesce(is_deleted, FALSE)
+WHERE is_active IS NULL OR is_admin IS NULL OR is_deleted IS NULL;
+
+ALTER TABLE public.users
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET DEFAULT NOW(),
+    ALTER COLUMN updated_at SET NOT NULL,
+    ALTER COLUMN is_active SET NOT NULL,
+    ALTER COLUMN is_admin SET NOT NULL,
+    ALTER COLUMN is_deleted SET NOT NULL;
@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS public.idx_users_deleted_at;
+DROP INDEX IF EXISTS public.idx_book_deleted_at;
+DROP INDEX IF EXISTS public.idx_projects_deleted_at;
+DROP INDEX IF EXISTS public.idx_tasks_deleted_at;
+
+ALTER TABLE public.tasks DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE public.projects DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE public.book ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN DEFAULT FALSE;
+UPDATE public.book SET is_deleted = (deleted_at IS NOT NULL);
+ALTER TABLE public.book DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE public.users SET is_deleted = (deleted_at IS NOT NULL);
+ALTER TABLE public.users DROP COLUMN IF EXISTS deleted_at;
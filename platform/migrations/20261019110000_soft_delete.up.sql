@@ -0,0 +1,20 @@
+-- Uniform soft delete: every resource carries a nullable deleted_at.
+-- Reads hide rows with deleted_at set; a background job purges them after
+-- the retention window (SOFT_DELETE_RETENTION_DAYS).
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE NULL;
+UPDATE public.users SET deleted_at = coalesce(updated_at, NOW()) WHERE is_deleted;
+ALTER TABLE public.users DROP COLUMN IF EXISTS is_deleted;
+
+ALTER TABLE public.book ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE NULL;
+UPDATE public.book SET deleted_at = coalesce(updated_at, NOW()) WHERE is_deleted;
+ALTER TABLE public.book DROP COLUMN IF EXISTS is_deleted;
+
+ALTER TABLE public.projects ADD COLUMN IF NOT EXISTS deleted_at timestamptz NULL;
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS deleted_at timestamptz NULL;
+
+-- Used by the purge job.
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON public.users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_book_deleted_at ON public.book (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_projects_deleted_at ON public.projects (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON public.tasks (deleted_at) WHERE deleted_at IS NOT NULL;
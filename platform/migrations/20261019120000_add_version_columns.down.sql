@@ -0,0 +1,4 @@
+ALTER TABLE public.tasks DROP COLUMN IF EXISTS version;
+ALTER TABLE public.projects DROP COLUMN IF EXISTS version;
+ALTER TABLE public.book DROP COLUMN IF EXISTS version;
+ALTER TABLE public.users DROP COLUMN IF EXISTS version;
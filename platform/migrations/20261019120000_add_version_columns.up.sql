@@ -0,0 +1,6 @@
+-- Row versions for optimistic concurrency control. Every write bumps the
+-- version; the API exposes it as a strong ETag and honors If-Match.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE public.book ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE public.projects ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,15 @@
+-- Task statuses are a closed set (model.TaskStatuses); which moves between
+-- them are allowed is configured in the application (TASK_TRANSITIONS).
+UPDATE public.tasks SET status = lower(trim(status));
+UPDATE public.tasks SET status = 'todo' WHERE status NOT IN ('todo', 'doing', 'done', 'cancelled');
+
+ALTER TABLE public.tasks
+  ADD CONSTRAINT tasks_status_check CHECK (status IN ('todo', 'doing', 'done', 'cancelled'));
+
+-- Lifecycle timestamps stamped by the workflow.
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS started_at timestamptz NULL;
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS completed_at timestamptz NULL;
+
+-- Best effort backfill: the last update is the closest thing we have.
+UPDATE public.tasks SET started_at = updated_at WHERE status = 'doing';
+UPDATE public.tasks SET completed_at = updated_at WHERE status IN ('done', 'cancelled');
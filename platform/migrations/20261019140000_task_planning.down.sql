@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS public.task_labels;
+DROP TABLE IF EXISTS public.labels;
+DROP TABLE IF EXISTS public.task_assignees;
+
+DROP INDEX IF EXISTS public.idx_tasks_priority;
+ALTER TABLE public.tasks DROP CONSTRAINT IF EXISTS tasks_priority_check;
+ALTER TABLE public.tasks DROP COLUMN IF EXISTS priority;
+ALTER TABLE public.tasks DROP COLUMN IF EXISTS description;
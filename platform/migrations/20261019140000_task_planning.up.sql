@@ -0,0 +1,32 @@
+-- Planning fields on tasks.
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS description text NULL;
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS priority text NOT NULL DEFAULT 'medium';
+ALTER TABLE public.tasks
+  ADD CONSTRAINT tasks_priority_check CHECK (priority IN ('low', 'medium', 'high', 'urgent'));
+CREATE INDEX IF NOT EXISTS idx_tasks_priority ON public.tasks (priority);
+
+-- Assignees.
+CREATE TABLE IF NOT EXISTS public.task_assignees (
+  task_id uuid NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (task_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_task_assignees_user_id ON public.task_assignees (user_id);
+
+-- Labels are defined per project; names are stored lower case.
+CREATE TABLE IF NOT EXISTS public.labels (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  project_id uuid NOT NULL REFERENCES public.projects(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  color text NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (project_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS public.task_labels (
+  task_id uuid NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  label_id uuid NOT NULL REFERENCES public.labels(id) ON DELETE CASCADE,
+  PRIMARY KEY (task_id, label_id)
+);
+CREATE INDEX IF NOT EXISTS idx_task_labels_label_id ON public.task_labels (label_id);
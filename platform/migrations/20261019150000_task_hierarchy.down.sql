@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS public.task_dependencies;
+
+DROP INDEX IF EXISTS public.idx_tasks_parent_task_id;
+ALTER TABLE public.tasks DROP CONSTRAINT IF EXISTS tasks_parent_not_self;
+ALTER TABLE public.tasks DROP COLUMN IF EXISTS parent_task_id;
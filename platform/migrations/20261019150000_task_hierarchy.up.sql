@@ -0,0 +1,17 @@
+-- Subtasks: a task may have a parent task of the same project.
+ALTER TABLE public.tasks
+  ADD COLUMN IF NOT EXISTS parent_task_id uuid NULL REFERENCES public.tasks(id) ON DELETE CASCADE;
+ALTER TABLE public.tasks
+  ADD CONSTRAINT tasks_parent_not_self CHECK (parent_task_id <> id);
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_task_id ON public.tasks (parent_task_id);
+
+-- Dependencies: task_id can't be finished before blocked_by_task_id.
+-- Cycles are refused by the application.
+CREATE TABLE IF NOT EXISTS public.task_dependencies (
+  task_id uuid NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  blocked_by_task_id uuid NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (task_id, blocked_by_task_id),
+  CHECK (task_id <> blocked_by_task_id)
+);
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_blocked_by ON public.task_dependencies (blocked_by_task_id);
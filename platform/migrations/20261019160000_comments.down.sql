@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.comment_mentions;
+DROP TABLE IF EXISTS public.comment_revisions;
+DROP TABLE IF EXISTS public.comments;
@@ -0,0 +1,36 @@
+-- Comments belong to exactly one task or project. Bodies are Markdown.
+CREATE TABLE IF NOT EXISTS public.comments (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  task_id uuid NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  project_id uuid NULL REFERENCES public.projects(id) ON DELETE CASCADE,
+  author_user_id uuid NULL REFERENCES public.users(id) ON DELETE SET NULL,
+  body text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  edited_at timestamptz NULL,
+  deleted_at timestamptz NULL,
+  version BIGINT NOT NULL DEFAULT 1,
+  CHECK ((task_id IS NULL) <> (project_id IS NULL))
+);
+-- Threads are read in (created_at, id) order, which is also the cursor.
+CREATE INDEX IF NOT EXISTS idx_comments_task_thread ON public.comments (task_id, created_at, id) WHERE task_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_project_thread ON public.comments (project_id, created_at, id) WHERE project_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON public.comments (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Previous bodies of edited comments.
+CREATE TABLE IF NOT EXISTS public.comment_revisions (
+  id bigserial PRIMARY KEY,
+  comment_id uuid NOT NULL REFERENCES public.comments(id) ON DELETE CASCADE,
+  body text NOT NULL,
+  edited_by_user_id uuid NULL REFERENCES public.users(id) ON DELETE SET NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_id ON public.comment_revisions (comment_id, id);
+
+-- Users @mentioned in the current body.
+CREATE TABLE IF NOT EXISTS public.comment_mentions (
+  comment_id uuid NOT NULL REFERENCES public.comments(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  PRIMARY KEY (comment_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_comment_mentions_user_id ON public.comment_mentions (user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.attachments;
+DROP TABLE IF EXISTS public.blobs;
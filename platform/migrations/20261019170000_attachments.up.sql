@@ -0,0 +1,27 @@
+-- Uploaded content, stored once per distinct SHA-256 in the blob store.
+CREATE TABLE IF NOT EXISTS public.blobs (
+  sha256 text PRIMARY KEY CHECK (sha256 ~ '^[0-9a-f]{64}$'),
+  size bigint NOT NULL CHECK (size >= 0),
+  content_type text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Files attached to a task, and book covers.
+CREATE TABLE IF NOT EXISTS public.attachments (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  sha256 text NOT NULL REFERENCES public.blobs(sha256),
+  task_id uuid NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  book_id uuid NULL REFERENCES public.book(id) ON DELETE CASCADE,
+  kind text NOT NULL DEFAULT 'file' CHECK (kind IN ('file', 'cover')),
+  filename text NOT NULL,
+  content_type text NOT NULL,
+  size bigint NOT NULL,
+  uploaded_by_user_id uuid NULL REFERENCES public.users(id) ON DELETE SET NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  deleted_at timestamptz NULL,
+  CHECK ((task_id IS NULL) <> (book_id IS NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_task_id ON public.attachments (task_id, created_at) WHERE task_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_book_id ON public.attachments (book_id, kind, created_at) WHERE book_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256 ON public.attachments (sha256);
+CREATE INDEX IF NOT EXISTS idx_attachments_deleted_at ON public.attachments (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_tasks_recurrence_occurrence;
+ALTER TABLE public.tasks
+  DROP COLUMN IF EXISTS occurrence_at,
+  DROP COLUMN IF EXISTS recurrence_id;
+DROP TABLE IF EXISTS public.task_recurrences;
@@ -0,0 +1,21 @@
+-- A recurring task is a series of tasks; the series creates the next one.
+CREATE TABLE IF NOT EXISTS public.task_recurrences (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  task_id uuid NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  rule text NOT NULL,
+  timezone text NOT NULL DEFAULT 'UTC',
+  starts_at timestamptz NOT NULL,
+  due_offset_seconds bigint NULL,
+  last_occurrence_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (task_id)
+);
+
+ALTER TABLE public.tasks
+  ADD COLUMN IF NOT EXISTS recurrence_id uuid NULL REFERENCES public.task_recurrences(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS occurrence_at timestamptz NULL;
+
+-- at most one task per occurrence, however many schedulers race for it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_recurrence_occurrence ON public.tasks (recurrence_id, occurrence_at)
+  WHERE recurrence_id IS NOT NULL;
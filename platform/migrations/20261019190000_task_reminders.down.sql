@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tasks_open_due_at;
+DROP TABLE IF EXISTS public.task_reminders;
+DROP TABLE IF EXISTS public.notification_preferences;
@@ -0,0 +1,24 @@
+-- How users want to be reminded; NULL columns use the server defaults.
+CREATE TABLE IF NOT EXISTS public.notification_preferences (
+  user_id uuid PRIMARY KEY REFERENCES public.users(id) ON DELETE CASCADE,
+  channel text NULL CHECK (channel IN ('log', 'webhook', 'email', 'none')),
+  webhook_url text NULL,
+  remind_before_minutes integer NULL CHECK (remind_before_minutes >= 0),
+  due_soon boolean NOT NULL DEFAULT true,
+  overdue boolean NOT NULL DEFAULT true,
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Reminders already sent. Moving due_at arms the reminders again.
+CREATE TABLE IF NOT EXISTS public.task_reminders (
+  task_id uuid NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  kind text NOT NULL CHECK (kind IN ('due_soon', 'overdue')),
+  due_at timestamptz NOT NULL,
+  channel text NOT NULL,
+  sent_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (task_id, user_id, kind, due_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_open_due_at ON public.tasks (due_at)
+  WHERE due_at IS NOT NULL AND deleted_at IS NULL AND status NOT IN ('done', 'cancelled');
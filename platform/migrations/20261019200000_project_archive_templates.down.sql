@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_projects_listing;
+ALTER TABLE public.projects
+  DROP COLUMN IF EXISTS archived_at,
+  DROP COLUMN IF EXISTS is_template;
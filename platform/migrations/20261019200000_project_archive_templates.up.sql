@@ -0,0 +1,8 @@
+-- Archived projects are kept but hidden from listings; templates are
+-- projects meant to be cloned.
+ALTER TABLE public.projects
+  ADD COLUMN IF NOT EXISTS is_template boolean NOT NULL DEFAULT false,
+  ADD COLUMN IF NOT EXISTS archived_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS idx_projects_listing ON public.projects (created_at DESC)
+  WHERE deleted_at IS NULL AND archived_at IS NULL AND NOT is_template;
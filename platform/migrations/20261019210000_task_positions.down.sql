@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS public.idx_tasks_board;
+
+ALTER TABLE public.tasks
+  DROP COLUMN IF EXISTS position;
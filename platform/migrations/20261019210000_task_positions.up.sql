@@ -0,0 +1,17 @@
+-- Board order within a status column. Positions are lexorank keys compared
+-- byte-wise, hence the C collation.
+ALTER TABLE public.tasks
+  ADD COLUMN IF NOT EXISTS position text COLLATE "C" NOT NULL DEFAULT '';
+
+-- Keep the current order (newest first) for existing tasks. The keys are
+-- fixed width and end in a non-zero digit, as the application expects.
+UPDATE public.tasks t
+SET position = 'i' || lpad(r.n::text, 10, '0') || 'i'
+FROM (
+  SELECT id, row_number() OVER (PARTITION BY project_id, status ORDER BY created_at DESC, id) AS n
+  FROM public.tasks
+) r
+WHERE r.id = t.id AND t.position = '';
+
+CREATE INDEX IF NOT EXISTS idx_tasks_board ON public.tasks (project_id, status, position)
+  WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP MATERIALIZED VIEW IF EXISTS public.task_throughput_daily;
+DROP INDEX IF EXISTS public.idx_tasks_completed;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.time_entries;
+ALTER TABLE public.tasks
+  DROP COLUMN IF EXISTS estimate_minutes;
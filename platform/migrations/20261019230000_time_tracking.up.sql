@@ -0,0 +1,21 @@
+-- Planned effort of a task.
+ALTER TABLE public.tasks
+  ADD COLUMN IF NOT EXISTS estimate_minutes integer NULL CHECK (estimate_minutes >= 0);
+
+-- Time spent on a task. A running timer is an entry without ended_at; a user
+-- runs at most one at a time.
+CREATE TABLE IF NOT EXISTS public.time_entries (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  task_id uuid NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  started_at timestamptz NOT NULL,
+  ended_at timestamptz NULL,
+  note text NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  version BIGINT NOT NULL DEFAULT 1,
+  CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON public.time_entries (user_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_time_entries_task ON public.time_entries (task_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_time_entries_user ON public.time_entries (user_id, started_at);
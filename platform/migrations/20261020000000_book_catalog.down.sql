@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS public.book_tags;
+DROP TABLE IF EXISTS public.book_genres;
+DROP TABLE IF EXISTS public.book_authors;
+DROP TABLE IF EXISTS public.authors;
+DROP INDEX IF EXISTS public.idx_book_isbn;
+ALTER TABLE public.book
+  DROP COLUMN IF EXISTS page_count,
+  DROP COLUMN IF EXISTS language,
+  DROP COLUMN IF EXISTS published_year,
+  DROP COLUMN IF EXISTS publisher,
+  DROP COLUMN IF EXISTS edition,
+  DROP COLUMN IF EXISTS isbn;
@@ -0,0 +1,49 @@
+-- Catalog data of books. ISBNs are stored as the 13 digits of the ISBN-13
+-- and identify a live book.
+ALTER TABLE public.book
+  ADD COLUMN IF NOT EXISTS isbn text NULL CHECK (isbn ~ '^97[89][0-9]{10}$'),
+  ADD COLUMN IF NOT EXISTS edition text NULL,
+  ADD COLUMN IF NOT EXISTS publisher text NULL,
+  ADD COLUMN IF NOT EXISTS published_year integer NULL CHECK (published_year BETWEEN 1 AND 9999),
+  ADD COLUMN IF NOT EXISTS language text NULL,
+  ADD COLUMN IF NOT EXISTS page_count integer NULL CHECK (page_count > 0);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_book_isbn ON public.book (isbn) WHERE isbn IS NOT NULL AND deleted_at IS NULL;
+
+-- Authors are shared between books; names match case-insensitively.
+CREATE TABLE IF NOT EXISTS public.authors (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_authors_name ON public.authors (lower(name));
+
+CREATE TABLE IF NOT EXISTS public.book_authors (
+  book_id uuid NOT NULL REFERENCES public.book(id) ON DELETE CASCADE,
+  author_id uuid NOT NULL REFERENCES public.authors(id) ON DELETE CASCADE,
+  position integer NOT NULL, -- order of the byline
+  PRIMARY KEY (book_id, author_id)
+);
+CREATE INDEX IF NOT EXISTS idx_book_authors_author_id ON public.book_authors (author_id);
+
+-- The single author of existing books becomes their byline.
+INSERT INTO public.authors (name)
+SELECT DISTINCT ON (lower(btrim(author))) btrim(author) FROM public.book WHERE btrim(author) <> ''
+ON CONFLICT DO NOTHING;
+INSERT INTO public.book_authors (book_id, author_id, position)
+SELECT b.id, a.id, 0 FROM public.book b JOIN public.authors a ON lower(a.name) = lower(btrim(b.author))
+ON CONFLICT DO NOTHING;
+
+-- Genres and free-form tags, lower-cased like task labels.
+CREATE TABLE IF NOT EXISTS public.book_genres (
+  book_id uuid NOT NULL REFERENCES public.book(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  PRIMARY KEY (book_id, name)
+);
+CREATE INDEX IF NOT EXISTS idx_book_genres_name ON public.book_genres (name);
+
+CREATE TABLE IF NOT EXISTS public.book_tags (
+  book_id uuid NOT NULL REFERENCES public.book(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  PRIMARY KEY (book_id, name)
+);
+CREATE INDEX IF NOT EXISTS idx_book_tags_name ON public.book_tags (name);
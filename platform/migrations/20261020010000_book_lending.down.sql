@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.reservations;
+DROP TABLE IF EXISTS public.loans;
+DROP TABLE IF EXISTS public.book_copies;
@@ -0,0 +1,49 @@
+-- Physical copies of a book. Withdrawn copies stay for the loan history.
+CREATE TABLE IF NOT EXISTS public.book_copies (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  book_id uuid NOT NULL REFERENCES public.book(id) ON DELETE CASCADE,
+  barcode text NULL,
+  note text NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  withdrawn_at timestamptz NULL
+);
+CREATE INDEX IF NOT EXISTS idx_book_copies_book_id ON public.book_copies (book_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_book_copies_barcode ON public.book_copies (barcode) WHERE barcode IS NOT NULL;
+
+-- A copy lent to a user. A copy has at most one open loan.
+CREATE TABLE IF NOT EXISTS public.loans (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  copy_id uuid NOT NULL REFERENCES public.book_copies(id) ON DELETE CASCADE,
+  book_id uuid NOT NULL REFERENCES public.book(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  checked_out_at timestamptz NOT NULL,
+  due_at timestamptz NOT NULL,
+  returned_at timestamptz NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  CHECK (due_at > checked_out_at),
+  CHECK (returned_at IS NULL OR returned_at >= checked_out_at)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loans_open_copy ON public.loans (copy_id) WHERE returned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_loans_user ON public.loans (user_id, checked_out_at);
+CREATE INDEX IF NOT EXISTS idx_loans_book ON public.loans (book_id, checked_out_at);
+CREATE INDEX IF NOT EXISTS idx_loans_due ON public.loans (due_at) WHERE returned_at IS NULL;
+
+-- The waitlist of a book, served first come first served. A ready
+-- reservation holds a returned copy for its user until expires_at.
+CREATE TABLE IF NOT EXISTS public.reservations (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  book_id uuid NOT NULL REFERENCES public.book(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  status text NOT NULL DEFAULT 'waiting'
+    CHECK (status IN ('waiting', 'ready', 'fulfilled', 'cancelled', 'expired')),
+  ready_at timestamptz NULL,
+  expires_at timestamptz NULL,
+  closed_at timestamptz NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservations_open ON public.reservations (book_id, user_id) WHERE status IN ('waiting', 'ready');
+CREATE INDEX IF NOT EXISTS idx_reservations_queue ON public.reservations (book_id, created_at, id) WHERE status IN ('waiting', 'ready');
+CREATE INDEX IF NOT EXISTS idx_reservations_user ON public.reservations (user_id, created_at);
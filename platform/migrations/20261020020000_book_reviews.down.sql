@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_book_rating;
+ALTER TABLE public.book
+  DROP COLUMN IF EXISTS rating_avg,
+  DROP COLUMN IF EXISTS rating_sum,
+  DROP COLUMN IF EXISTS rating_count;
+DROP TABLE IF EXISTS public.book_reviews;
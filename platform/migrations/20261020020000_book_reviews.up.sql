@@ -0,0 +1,22 @@
+-- Reviews of a book, one per user and book.
+CREATE TABLE IF NOT EXISTS public.book_reviews (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  book_id uuid NOT NULL REFERENCES public.book(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  rating smallint NOT NULL CHECK (rating BETWEEN 1 AND 10),
+  body text NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  version BIGINT NOT NULL DEFAULT 1,
+  UNIQUE (book_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_book_reviews_book ON public.book_reviews (book_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_book_reviews_user ON public.book_reviews (user_id);
+
+-- Review aggregates, kept up to date by every review write.
+ALTER TABLE public.book
+  ADD COLUMN IF NOT EXISTS rating_count integer NOT NULL DEFAULT 0 CHECK (rating_count >= 0),
+  ADD COLUMN IF NOT EXISTS rating_sum bigint NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS rating_avg double precision
+    GENERATED ALWAYS AS (CASE WHEN rating_count > 0 THEN rating_sum::double precision / rating_count END) STORED;
+CREATE INDEX IF NOT EXISTS idx_book_rating ON public.book (rating_avg DESC NULLS LAST, rating_count DESC) WHERE deleted_at IS NULL;
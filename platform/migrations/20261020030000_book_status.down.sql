@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_book_status;
+DROP INDEX IF EXISTS active_books;
+ALTER TABLE public.book
+  DROP CONSTRAINT IF EXISTS book_status_check,
+  ALTER COLUMN status DROP DEFAULT,
+  ALTER COLUMN status TYPE integer USING (
+    CASE status WHEN 'draft' THEN 0 WHEN 'archived' THEN 2 WHEN 'withdrawn' THEN 3 ELSE 1 END
+  );
+CREATE INDEX IF NOT EXISTS active_books ON public.book (title) WHERE status = 1;
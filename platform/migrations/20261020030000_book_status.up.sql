@@ -0,0 +1,11 @@
+-- Book statuses become names; the numeric codes map to 0 draft, 1 active,
+-- 2 archived and 3 withdrawn.
+DROP INDEX IF EXISTS active_books;
+ALTER TABLE public.book
+  ALTER COLUMN status TYPE text USING (
+    CASE status WHEN 0 THEN 'draft' WHEN 2 THEN 'archived' WHEN 3 THEN 'withdrawn' ELSE 'active' END
+  ),
+  ALTER COLUMN status SET DEFAULT 'draft',
+  ADD CONSTRAINT book_status_check CHECK (status IN ('draft', 'active', 'archived', 'withdrawn'));
+CREATE INDEX IF NOT EXISTS active_books ON public.book (title) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_book_status ON public.book (status, created_at) WHERE deleted_at IS NULL;
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS audit_tasks ON public.tasks;
+DROP TRIGGER IF EXISTS audit_projects ON public.projects;
+DROP TRIGGER IF EXISTS audit_users ON public.users;
+DROP TRIGGER IF EXISTS audit_book ON public.book;
+DROP FUNCTION IF EXISTS public.audit_row();
+DROP TABLE IF EXISTS public.audit_events;
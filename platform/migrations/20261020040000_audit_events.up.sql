@@ -0,0 +1,86 @@
+-- One row per change to an audited row, written by a trigger in the
+-- transaction of the change. The actor, request id and IP come from the
+-- transaction-local settings audit.actor_id, audit.request_id and audit.ip;
+-- they are null for changes made by the system (jobs, migrations).
+CREATE TABLE IF NOT EXISTS public.audit_events (
+  id bigserial PRIMARY KEY,
+  occurred_at timestamptz NOT NULL DEFAULT now(),
+  actor_user_id uuid NULL,
+  action text NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore', 'purge')),
+  resource_type text NOT NULL,
+  resource_id uuid NOT NULL,
+  -- The columns that changed, with their old and new values. Creates only
+  -- have after, purges only before.
+  before jsonb NULL,
+  after jsonb NULL,
+  request_id text NULL,
+  ip text NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_resource ON public.audit_events (resource_type, resource_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON public.audit_events (actor_user_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON public.audit_events (occurred_at);
+
+-- audit_row(resource_type, secret_column...) records the change of a row.
+-- Soft deletes and restores, i.e. updates of deleted_at, are recorded as
+-- such. Secret columns show as "[redacted]".
+CREATE OR REPLACE FUNCTION public.audit_row() RETURNS trigger AS $$
+DECLARE
+  old_row jsonb;
+  new_row jsonb;
+  event_action text;
+  row_id uuid;
+  secret text;
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    event_action := 'create';
+    row_id := NEW.id;
+    new_row := to_jsonb(NEW);
+  ELSIF TG_OP = 'DELETE' THEN
+    event_action := 'purge';
+    row_id := OLD.id;
+    old_row := to_jsonb(OLD);
+  ELSE
+    row_id := NEW.id;
+    SELECT jsonb_object_agg(o.key, o.value), jsonb_object_agg(o.key, n.value)
+    INTO old_row, new_row
+    FROM jsonb_each(to_jsonb(OLD)) o JOIN jsonb_each(to_jsonb(NEW)) n USING (key)
+    WHERE o.value IS DISTINCT FROM n.value;
+    IF old_row IS NULL THEN
+      RETURN NULL;
+    END IF;
+    event_action := CASE
+      WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+      WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+      ELSE 'update'
+    END;
+  END IF;
+
+  FOR i IN 1 .. TG_NARGS - 1 LOOP
+    secret := TG_ARGV[i];
+    IF old_row ? secret THEN
+      old_row := jsonb_set(old_row, ARRAY[secret], '"[redacted]"');
+    END IF;
+    IF new_row ? secret THEN
+      new_row := jsonb_set(new_row, ARRAY[secret], '"[redacted]"');
+    END IF;
+  END LOOP;
+
+  INSERT INTO public.audit_events (actor_user_id, action, resource_type, resource_id, before, after, request_id, ip)
+  VALUES (
+    nullif(current_setting('audit.actor_id', true), '')::uuid,
+    event_action, TG_ARGV[0], row_id, old_row, new_row,
+    nullif(current_setting('audit.request_id', true), ''),
+    nullif(current_setting('audit.ip', true), '')
+  );
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_book AFTER INSERT OR UPDATE OR DELETE ON public.book
+  FOR EACH ROW EXECUTE FUNCTION public.audit_row('book');
+CREATE TRIGGER audit_users AFTER INSERT OR UPDATE OR DELETE ON public.users
+  FOR EACH ROW EXECUTE FUNCTION public.audit_row('user', 'password_hash');
+CREATE TRIGGER audit_projects AFTER INSERT OR UPDATE OR DELETE ON public.projects
+  FOR EACH ROW EXECUTE FUNCTION public.audit_row('project');
+CREATE TRIGGER audit_tasks AFTER INSERT OR UPDATE OR DELETE ON public.tasks
+  FOR EACH ROW EXECUTE FUNCTION public.audit_row('task');
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS public.webhook_deliveries;
+DROP TABLE IF EXISTS public.webhook_subscriptions;
+DROP TRIGGER IF EXISTS outbox_tasks ON public.tasks;
+DROP TRIGGER IF EXISTS outbox_book ON public.book;
+DROP FUNCTION IF EXISTS public.outbox_row();
+DROP TABLE IF EXISTS public.outbox_events;
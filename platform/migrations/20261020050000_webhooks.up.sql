@@ -0,0 +1,100 @@
+-- Domain events of books and tasks, written by a trigger in the transaction
+-- of the change (a transactional outbox). The webhook dispatcher fans each
+-- event out to the subscriptions that want it, then sets dispatched_at.
+CREATE TABLE IF NOT EXISTS public.outbox_events (
+  id bigserial PRIMARY KEY,
+  type text NOT NULL, -- <resource>.<created|updated|status_changed|deleted|restored|purged>
+  resource_type text NOT NULL,
+  resource_id uuid NOT NULL,
+  actor_user_id uuid NULL,
+  data jsonb NOT NULL, -- the row after the change, or before a purge
+  previous jsonb NULL, -- old values of the columns an update changed
+  created_at timestamptz NOT NULL DEFAULT now(),
+  dispatched_at timestamptz NULL
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON public.outbox_events (id) WHERE dispatched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_resource ON public.outbox_events (resource_type, resource_id, id);
+
+-- outbox_row(resource_type) records the change of a row as a domain event.
+-- The actor comes from the audit.actor_id setting, as for audit_events.
+CREATE OR REPLACE FUNCTION public.outbox_row() RETURNS trigger AS $$
+DECLARE
+  kind text;
+  row_id uuid;
+  row_data jsonb;
+  old_values jsonb;
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    kind := 'created';
+    row_id := NEW.id;
+    row_data := to_jsonb(NEW);
+  ELSIF TG_OP = 'DELETE' THEN
+    kind := 'purged';
+    row_id := OLD.id;
+    row_data := to_jsonb(OLD);
+  ELSE
+    row_id := NEW.id;
+    row_data := to_jsonb(NEW);
+    SELECT jsonb_object_agg(o.key, o.value)
+    INTO old_values
+    FROM jsonb_each(to_jsonb(OLD)) o JOIN jsonb_each(row_data) n USING (key)
+    WHERE o.value IS DISTINCT FROM n.value;
+    IF old_values IS NULL THEN
+      RETURN NULL;
+    END IF;
+    kind := CASE
+      WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'deleted'
+      WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restored'
+      WHEN old_values ? 'status' THEN 'status_changed'
+      ELSE 'updated'
+    END;
+  END IF;
+
+  INSERT INTO public.outbox_events (type, resource_type, resource_id, actor_user_id, data, previous)
+  VALUES (
+    TG_ARGV[0] || '.' || kind, TG_ARGV[0], row_id,
+    nullif(current_setting('audit.actor_id', true), '')::uuid,
+    row_data, old_values
+  );
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_book AFTER INSERT OR UPDATE OR DELETE ON public.book
+  FOR EACH ROW EXECUTE FUNCTION public.outbox_row('book');
+CREATE TRIGGER outbox_tasks AFTER INSERT OR UPDATE OR DELETE ON public.tasks
+  FOR EACH ROW EXECUTE FUNCTION public.outbox_row('task');
+
+-- Endpoints that receive the events matching their patterns: "*", a
+-- resource ("task.*") or a type ("book.deleted").
+CREATE TABLE IF NOT EXISTS public.webhook_subscriptions (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  url text NOT NULL,
+  secret text NOT NULL, -- HMAC key of the signatures
+  events jsonb NOT NULL DEFAULT '["*"]',
+  description text NULL,
+  active boolean NOT NULL DEFAULT true,
+  created_by_user_id uuid NULL REFERENCES public.users(id) ON DELETE SET NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  version BIGINT NOT NULL DEFAULT 1
+);
+
+-- One event to one subscription. Failed attempts are retried at
+-- next_attempt_at with exponential backoff until the delivery is dead.
+CREATE TABLE IF NOT EXISTS public.webhook_deliveries (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  subscription_id uuid NOT NULL REFERENCES public.webhook_subscriptions(id) ON DELETE CASCADE,
+  event_id bigint NOT NULL REFERENCES public.outbox_events(id) ON DELETE CASCADE,
+  status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+  attempts int NOT NULL DEFAULT 0,
+  next_attempt_at timestamptz NOT NULL DEFAULT now(),
+  last_attempt_at timestamptz NULL,
+  response_status int NULL,
+  last_error text NULL,
+  delivered_at timestamptz NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (subscription_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON public.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON public.webhook_deliveries (subscription_id, created_at);
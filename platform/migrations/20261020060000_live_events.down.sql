@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS outbox_events_notify ON public.outbox_events;
+DROP FUNCTION IF EXISTS public.outbox_event_notify();
+DROP TRIGGER IF EXISTS outbox_events_project ON public.outbox_events;
+DROP FUNCTION IF EXISTS public.outbox_event_project();
+DROP INDEX IF EXISTS public.idx_outbox_events_project;
+ALTER TABLE public.outbox_events DROP COLUMN IF EXISTS project_id;
+DROP TRIGGER IF EXISTS outbox_projects ON public.projects;
@@ -0,0 +1,34 @@
+-- Project changes become outbox events too, and each event records the
+-- project it belongs to, so the /api/v1/events streams can show a user the
+-- events of the projects they can see.
+CREATE TRIGGER outbox_projects AFTER INSERT OR UPDATE OR DELETE ON public.projects
+  FOR EACH ROW EXECUTE FUNCTION public.outbox_row('project');
+
+ALTER TABLE public.outbox_events ADD COLUMN IF NOT EXISTS project_id uuid NULL;
+UPDATE public.outbox_events SET project_id = (data->>'project_id')::uuid WHERE resource_type = 'task';
+CREATE INDEX IF NOT EXISTS idx_outbox_events_project ON public.outbox_events (project_id, id);
+
+CREATE OR REPLACE FUNCTION public.outbox_event_project() RETURNS trigger AS $$
+BEGIN
+  NEW.project_id := CASE NEW.resource_type
+    WHEN 'project' THEN NEW.resource_id
+    WHEN 'task' THEN (NEW.data->>'project_id')::uuid
+  END;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_events_project BEFORE INSERT ON public.outbox_events
+  FOR EACH ROW EXECUTE FUNCTION public.outbox_event_project();
+
+-- Every server LISTENs on outbox_events and wakes its streams up. The
+-- notification is sent when the transaction of the event commits.
+CREATE OR REPLACE FUNCTION public.outbox_event_notify() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('outbox_events', NEW.id::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_events_notify AFTER INSERT ON public.outbox_events
+  FOR EACH ROW EXECUTE FUNCTION public.outbox_event_notify();
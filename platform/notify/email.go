@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailNotifier sends plain text mail through an SMTP server.
+type EmailNotifier struct {
+	addr string
+	auth smtp.Auth
+	from string
+	// send is smtp.SendMail, replaced in tests
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailNotifier sends mail as from through host:port, authenticating
+// when a username is given.
+func NewEmailNotifier(host string, port int, username, password, from string) *EmailNotifier {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &EmailNotifier{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+		send: smtp.SendMail,
+	}
+}
+
+func (e *EmailNotifier) Notify(ctx context.Context, m Message) error {
+	if m.To.Email == "" {
+		return fmt.Errorf("notify: %s has no e-mail address", m.To.UserID)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return e.send(e.addr, e.auth, e.from, []string{m.To.Email}, e.compose(m))
+}
+
+func (e *EmailNotifier) compose(m Message) []byte {
+	to := m.To.Email
+	if m.To.Name != "" {
+		to = mime.QEncoding.Encode("utf-8", m.To.Name) + " <" + m.To.Email + ">"
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + e.from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", m.Subject) + "\r\n")
+	b.WriteString("Date: " + m.SentAt.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Text, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
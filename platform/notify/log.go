@@ -0,0 +1,17 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/byeblogs/go-boilerplate/platform/logger"
+)
+
+// LogNotifier writes messages to the application log.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier { return &LogNotifier{} }
+
+func (LogNotifier) Notify(_ context.Context, m Message) error {
+	logger.GetLogger().Infof("notify %s <%s>: %s", m.To.Name, m.To.Email, m.Subject)
+	return nil
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/google/uuid"
+)
+
+// Channel names, as users pick them in their preferences.
+const (
+	ChannelLog     = "log"
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+	// ChannelNone mutes a user.
+	ChannelNone = "none"
+)
+
+// Recipient is who a message is for.
+type Recipient struct {
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	Email  string    `json:"email"`
+	// WebhookURL overrides the webhook notifier's default URL.
+	WebhookURL string `json:"-"`
+}
+
+// Message is a notification about a task.
+type Message struct {
+	Kind    string     `json:"kind"`
+	To      Recipient  `json:"to"`
+	Subject string     `json:"subject"`
+	Text    string     `json:"text"`
+	TaskID  uuid.UUID  `json:"task_id"`
+	DueAt   *time.Time `json:"due_at,omitempty"`
+	SentAt  time.Time  `json:"sent_at"`
+}
+
+// Notifier delivers messages over one channel.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// Router sends each message over the channel it is addressed to.
+type Router map[string]Notifier
+
+// Notify sends m over channel. Channels that aren't enabled are an error, so
+// the caller can retry once they are.
+func (r Router) Notify(ctx context.Context, channel string, m Message) error {
+	if channel == ChannelNone {
+		return nil
+	}
+	n, ok := r[channel]
+	if !ok {
+		return fmt.Errorf("notify: channel %q is not enabled", channel)
+	}
+	return n.Notify(ctx, m)
+}
+
+// Enabled reports whether channel can be used.
+func (r Router) Enabled(channel string) bool {
+	_, ok := r[channel]
+	return ok || channel == ChannelNone
+}
+
+// NewRouter builds the notifiers enabled in cfg.
+func NewRouter(cfg *config.Notify) (Router, error) {
+	r := Router{}
+	for _, channel := range cfg.Channels {
+		switch channel {
+		case ChannelLog:
+			r[channel] = NewLogNotifier()
+		case ChannelWebhook:
+			r[channel] = NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret)
+		case ChannelEmail:
+			if cfg.SMTPHost == "" || cfg.SMTPFrom == "" {
+				return nil, fmt.Errorf("notify: the email channel needs SMTP_HOST and SMTP_FROM")
+			}
+			r[channel] = NewEmailNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+		default:
+			return nil, fmt.Errorf("notify: unknown channel %q (want %s)", channel,
+				strings.Join([]string{ChannelLog, ChannelWebhook, ChannelEmail}, ", "))
+		}
+	}
+	if !r.Enabled(cfg.DefaultChannel) {
+		return nil, fmt.Errorf("notify: default channel %q is not enabled", cfg.DefaultChannel)
+	}
+	return r, nil
+}
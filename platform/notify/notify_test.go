@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func message() Message {
+	return Message{
+		Kind:    "due_soon",
+		To:      Recipient{UserID: uuid.New(), Name: "Jörg Doe", Email: "jorg@example.com"},
+		Subject: `"Ship it" is due in 3 hours`,
+		Text:    "line one\nline two",
+		TaskID:  uuid.New(),
+		SentAt:  time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestNewRouter(t *testing.T) {
+	r, err := NewRouter(&config.Notify{Channels: []string{"log", "webhook"}, DefaultChannel: "log"})
+	require.NoError(t, err)
+	assert.True(t, r.Enabled(ChannelWebhook))
+	assert.True(t, r.Enabled(ChannelNone))
+	assert.False(t, r.Enabled(ChannelEmail))
+
+	assert.NoError(t, r.Notify(context.Background(), ChannelNone, message()))
+	assert.Error(t, r.Notify(context.Background(), ChannelEmail, message()))
+
+	_, err = NewRouter(&config.Notify{Channels: []string{"pigeon"}, DefaultChannel: "pigeon"})
+	assert.Error(t, err)
+	_, err = NewRouter(&config.Notify{Channels: []string{"email"}, DefaultChannel: "email"})
+	assert.Error(t, err, "email needs an SMTP server")
+	_, err = NewRouter(&config.Notify{Channels: []string{"log"}, DefaultChannel: "webhook"})
+	assert.Error(t, err, "the default channel must be enabled")
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got Message
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+		if r.Header.Get(SignatureHeader) != signature {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &got)
+		if strings.HasSuffix(r.URL.Path, "/gone") {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer srv.Close()
+
+	w := NewWebhookNotifier(srv.URL+"/hook", "s3cret")
+	m := message()
+	require.NoError(t, w.Notify(context.Background(), m))
+	assert.Equal(t, m.Subject, got.Subject)
+	assert.Equal(t, m.TaskID, got.TaskID)
+
+	m.To.WebhookURL = srv.URL + "/gone"
+	assert.Error(t, w.Notify(context.Background(), m), "the recipient's own URL wins, and failures are reported")
+
+	assert.Error(t, NewWebhookNotifier("", "").Notify(context.Background(), message()))
+}
+
+func TestEmailNotifier(t *testing.T) {
+	e := NewEmailNotifier("mail.example.com", 587, "bot", "pw", "Tasks <tasks@example.com>")
+
+	var to []string
+	var sent string
+	e.send = func(addr string, _ smtp.Auth, from string, rcpt []string, msg []byte) error {
+		assert.Equal(t, "mail.example.com:587", addr)
+		assert.Equal(t, "Tasks <tasks@example.com>", from)
+		to, sent = rcpt, string(msg)
+		return nil
+	}
+
+	require.NoError(t, e.Notify(context.Background(), message()))
+	assert.Equal(t, []string{"jorg@example.com"}, to)
+	assert.Contains(t, sent, "To: =?utf-8?q?J=C3=B6rg_Doe?= <jorg@example.com>\r\n")
+	assert.Contains(t, sent, "Subject: \"Ship it\" is due in 3 hours\r\n")
+	assert.True(t, strings.HasSuffix(sent, "\r\n\r\nline one\r\nline two\r\n"))
+
+	m := message()
+	m.Subject = "x\r\nBcc: someone@example.com"
+	require.NoError(t, e.Notify(context.Background(), m))
+	assert.NotContains(t, sent, "\r\nBcc:", "header injection is encoded away")
+}
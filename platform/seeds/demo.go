@@ -0,0 +1,81 @@
+package seeds
+
+import (
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+)
+
+const day = 24 * time.Hour
+
+// demo writes the fixed demo data set.
+func (s *Seeder) demo() error {
+	users := map[string]*model.User{}
+	for _, f := range []struct {
+		username, firstName, lastName string
+		isAdmin                       bool
+	}{
+		{"yaahtze", "Mr.", "Yaahtze", true},
+		{"nami", "Nami", "Navigator", false},
+		{"luna", "Luna", "Moon", false},
+		{"zoro", "Roronoa", "Zoro", false},
+		{"sanji", "Vinsmoke", "Sanji", false},
+	} {
+		u, err := s.user(f.username, f.firstName, f.lastName, f.isAdmin)
+		if err != nil {
+			return err
+		}
+		users[f.username] = u
+	}
+
+	for _, f := range []struct {
+		owner, title, author string
+		rating               int
+	}{
+		{"yaahtze", "Forgeon: Deploy Like a God", "Yaahtze", 9},
+		{"nami", "The Navigator’s Map", "Nami", 7},
+		{"luna", "Moonlit Databases", "Luna", 8},
+		{"zoro", "Three-Sword Refactoring", "Zoro", 6},
+		{"sanji", "Cookbook for APIs", "Sanji", 8},
+	} {
+		if _, err := s.book(users[f.owner], f.title, f.author, f.rating); err != nil {
+			return err
+		}
+	}
+
+	projects := map[string]*model.Project{}
+	for _, f := range []struct {
+		owner, name, description string
+	}{
+		{"yaahtze", "Forgeon Core", "Main platform services + gateway"},
+		{"nami", "DX Playground", "UI/UX and developer experience experiments"},
+		{"luna", "Billing System", "Plans, usage records, invoices"},
+		{"zoro", "Refactor Sprint", "Tech debt cleanup and consistency"},
+		{"sanji", "API Kitchen", "Fiber endpoints + docs + swagger"},
+	} {
+		p, err := s.project(users[f.owner], f.name, f.description)
+		if err != nil {
+			return err
+		}
+		projects[f.name] = p
+	}
+
+	for _, f := range []struct {
+		project, title string
+		status         model.TaskStatus
+		dueIn          time.Duration
+	}{
+		{"Forgeon Core", "Wire pooler DATABASE_URL in prod", model.TaskDone, -1 * day},
+		{"Forgeon Core", "Add healthcheck endpoint", model.TaskDoing, 1 * day},
+		{"DX Playground", "Implement users/projects/tasks pages", model.TaskTodo, 3 * day},
+		{"Billing System", "Create usage rollup job", model.TaskTodo, 7 * day},
+		{"Refactor Sprint", "Normalize repo patterns", model.TaskDoing, 2 * day},
+		{"API Kitchen", "Add swagger comments + examples", model.TaskTodo, 4 * day},
+	} {
+		if _, err := s.task(projects[f.project], f.title, f.status, f.dueIn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package seeds
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Profile describes how much data a seeding run writes.
+type Profile struct {
+	Name string
+	// Demo adds the hand written demo fixtures (users, books, projects, tasks).
+	Demo bool
+	// Generated data on top of the fixtures.
+	Users           int
+	BooksPerUser    int
+	ProjectsPerUser int
+	TasksPerProject int
+}
+
+var profiles = map[string]Profile{
+	// minimal only creates the admin account needed to log in.
+	"minimal": {Name: "minimal"},
+	// demo is the data set used for local development and the UI pages.
+	"demo": {Name: "demo", Demo: true},
+	// load-test generates a configurable amount of random (but reproducible) data.
+	"load-test": {Name: "load-test", Users: 100, BooksPerUser: 2, ProjectsPerUser: 5, TasksPerProject: 50},
+}
+
+// GetProfile returns the profile registered under name.
+func GetProfile(name string) (Profile, error) {
+	p, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown seed profile %q (available: %v)", name, ProfileNames())
+	}
+	return p, nil
+}
+
+// ProfileNames lists the registered profile names.
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,176 @@
+package seeds
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/app/model"
+	repo "github.com/byeblogs/go-boilerplate/app/repository"
+	"github.com/byeblogs/go-boilerplate/platform/database"
+	"github.com/google/uuid"
+)
+
+// DemoPassword is the plain text password of every seeded user.
+const DemoPassword = "demo#123"
+
+// demoPasswordHash is the bcrypt hash of DemoPassword. It is precomputed so
+// seeding stays deterministic and large profiles don't pay the bcrypt cost per user.
+const demoPasswordHash = "$2a$10$AWuR/dHlOdwYY0Vwtez28.thr67ir8LoB964QQr8QS2tX/eYKh8yS"
+
+// namespace is used to derive stable ids from natural keys, so a re-run
+// updates the rows written by the previous run instead of duplicating them.
+var namespace = uuid.MustParse("4f0c3a4e-5b1e-4d2a-9d43-1c1f7f0f6a10")
+
+// DefaultAnchor is the date seeded due dates and timestamps are relative
+// to, unless another one is given: a fixed date, so that runs agree.
+var DefaultAnchor = time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Seeder writes seed data through the repository layer.
+type Seeder struct {
+	users    repo.UserRepository
+	books    repo.BookRepository
+	projects repo.ProjectRepository
+	tasks    repo.TaskRepository
+
+	rng *rand.Rand
+	now time.Time
+}
+
+// New returns a Seeder whose generated data is fully determined by seed
+// and anchor, the date due dates are relative to.
+func New(db *database.DB, seed int64, anchor time.Time) *Seeder {
+	return &Seeder{
+		users:    repo.NewUserRepo(db),
+		books:    repo.NewBookRepo(db),
+		projects: repo.NewProjectRepo(db),
+		tasks:    repo.NewTaskRepo(db),
+		rng:      rand.New(rand.NewSource(seed)),
+		now:      anchor.UTC(),
+	}
+}
+
+// Run seeds everything the profile asks for. It is safe to run repeatedly.
+func (s *Seeder) Run(p Profile) error {
+	if _, err := s.user("admin", "Admin", "", true); err != nil {
+		return err
+	}
+
+	if p.Demo {
+		if err := s.demo(); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < p.Users; i++ {
+		u, err := s.user(fmt.Sprintf("loaduser%04d", i), s.words(1), s.words(1), false)
+		if err != nil {
+			return err
+		}
+
+		for j := 0; j < p.BooksPerUser; j++ {
+			if _, err := s.book(u, s.words(3), u.FullName(), s.rng.Intn(10)+1); err != nil {
+				return err
+			}
+		}
+
+		for j := 0; j < p.ProjectsPerUser; j++ {
+			pr, err := s.project(u, fmt.Sprintf("Project %02d", j), s.words(8))
+			if err != nil {
+				return err
+			}
+
+			for k := 0; k < p.TasksPerProject; k++ {
+				status := []model.TaskStatus{model.TaskTodo, model.TaskDoing, model.TaskDone}[s.rng.Intn(3)]
+				due := time.Duration(s.rng.Intn(60)-15) * 24 * time.Hour
+				if _, err := s.task(pr, fmt.Sprintf("Task %03d: %s", k, s.words(4)), status, due); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Seeder) user(username, firstName, lastName string, isAdmin bool) (*model.User, error) {
+	u := &model.User{
+		ID:           uuid.NewSHA1(namespace, []byte("user:"+username)),
+		Email:        username + "@example.com",
+		UserName:     username,
+		PasswordHash: demoPasswordHash,
+		FirstName:    firstName,
+		LastName:     lastName,
+		IsActive:     true,
+		IsAdmin:      isAdmin,
+	}
+	if err := s.users.Upsert(u); err != nil {
+		return nil, fmt.Errorf("seed user %s: %w", username, err)
+	}
+	return u, nil
+}
+
+func (s *Seeder) book(owner *model.User, title, author string, rating int) (*model.Book, error) {
+	b := &model.Book{
+		ID:     uuid.NewSHA1(namespace, []byte("book:"+title+"\x00"+author)),
+		UserID: owner.ID,
+		Title:  title,
+		Author: author,
+		Status: model.BookActive,
+		Meta: model.Meta{
+			Description: "Seeded book " + title,
+			Rating:      rating,
+		},
+	}
+	if err := s.books.Upsert(b); err != nil {
+		return nil, fmt.Errorf("seed book %q: %w", title, err)
+	}
+	return b, nil
+}
+
+func (s *Seeder) project(owner *model.User, name, description string) (*model.Project, error) {
+	p := &model.Project{
+		ID:          uuid.NewSHA1(namespace, []byte("project:"+owner.ID.String()+"\x00"+name)),
+		OwnerUserID: owner.ID,
+		Name:        name,
+		Description: &description,
+	}
+	if err := s.projects.Upsert(p); err != nil {
+		return nil, fmt.Errorf("seed project %q: %w", name, err)
+	}
+	return p, nil
+}
+
+func (s *Seeder) task(p *model.Project, title string, status model.TaskStatus, dueIn time.Duration) (*model.Task, error) {
+	due := s.now.Add(dueIn)
+	t := &model.Task{
+		ID:        uuid.NewSHA1(namespace, []byte("task:"+p.ID.String()+"\x00"+title)),
+		ProjectID: p.ID,
+		Title:     title,
+		Status:    status,
+		Priority:  model.TaskPriorityMedium,
+		DueAt:     &due,
+	}
+	model.DefaultTaskWorkflow().Stamp(t, s.now)
+	if err := s.tasks.Upsert(t); err != nil {
+		return nil, fmt.Errorf("seed task %q: %w", title, err)
+	}
+	return t, nil
+}
+
+// words returns n pseudo random lower case words drawn from the seeded RNG.
+func (s *Seeder) words(n int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz"
+
+	words := make([]string, n)
+	for i := range words {
+		var sb strings.Builder
+		size := 3 + s.rng.Intn(6)
+		for j := 0; j < size; j++ {
+			sb.WriteByte(alphabet[s.rng.Intn(len(alphabet))])
+		}
+		words[i] = sb.String()
+	}
+	return strings.Join(words, " ")
+}
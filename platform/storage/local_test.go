@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	testStore(t, store)
+
+	assert.Error(t, store.Put(context.Background(), "../escape", strings.NewReader("x"), 1, ""))
+}
+
+// testStore runs the BlobStore contract against a driver.
+func testStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	key := ContentKey("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+
+	ok, err := store.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("hello"), 5, "text/plain"))
+	ok, err = store.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	r, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	body, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "hello", string(body))
+
+	require.NoError(t, store.Delete(ctx, key))
+	require.NoError(t, store.Delete(ctx, key), "deleting twice is fine")
+	ok, err = store.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLSigner(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	signer := NewURLSigner("secret", 15*time.Minute)
+
+	path := "/api/v1/attachments/1/content"
+	signed, err := url.Parse(signer.Sign(path, now))
+	require.NoError(t, err)
+	assert.Equal(t, path, signed.Path)
+
+	q := signed.Query()
+	exp, sig := q.Get("expires"), q.Get("signature")
+	assert.NoError(t, signer.Verify(path, exp, sig, now.Add(15*time.Minute)))
+	assert.ErrorIs(t, signer.Verify(path, exp, sig, now.Add(16*time.Minute)), ErrURLExpired)
+	assert.ErrorIs(t, signer.Verify("/api/v1/attachments/2/content", exp, sig, now), ErrBadSignature)
+	assert.ErrorIs(t, signer.Verify(path, exp+"0", sig, now), ErrBadSignature)
+	assert.ErrorIs(t, signer.Verify(path, exp, strings.ToUpper(sig), now), ErrBadSignature)
+	assert.ErrorIs(t, NewURLSigner("other", time.Minute).Verify(path, exp, sig, now), ErrBadSignature)
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/byeblogs/go-boilerplate/pkg/config"
+)
+
+// ErrNotFound is returned for keys the store does not hold.
+var ErrNotFound = errors.New("storage: blob not found")
+
+// BlobStore keeps opaque blobs under slash separated keys. Writing a key
+// again replaces its content, and deleting a missing key is not an error.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	defaultStore  BlobStore
+	defaultSigner *URLSigner
+)
+
+// GetStore returns the store set up by Setup.
+func GetStore() BlobStore { return defaultStore }
+
+// GetSigner returns the download URL signer set up by Setup.
+func GetSigner() *URLSigner { return defaultSigner }
+
+// Setup opens the configured blob store.
+func Setup() error {
+	cfg := config.StorageCfg()
+	store, err := Open(cfg)
+	if err != nil {
+		return err
+	}
+	defaultStore = store
+	defaultSigner = NewURLSigner(cfg.SigningKey, cfg.SignedURLTTL)
+	return nil
+}
+
+// Open returns the store named by cfg.Driver.
+func Open(cfg *config.Storage) (BlobStore, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", cfg.Driver)
+	}
+}
+
+// ContentKey is the key of a blob addressed by the hex SHA-256 of its
+// content, fanned out so no directory grows too large.
+func ContentKey(sum string) string {
+	return "sha256/" + sum[:2] + "/" + sum[2:4] + "/" + sum
+}
+
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("storage: invalid key %q", key)
+		}
+	}
+	return nil
+}
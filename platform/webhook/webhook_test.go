@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPost(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := NewClient(time.Second)
+	c.now = func() time.Time { return time.Unix(1760864400, 0) }
+	r := Request{URL: srv.URL, Secret: "s3cret-s3cret-s3cret", Event: "task.created", DeliveryID: "d1", Body: []byte(`{"id":1}`)}
+
+	code, err := c.Post(context.Background(), r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, `{"id":1}`, string(body))
+	assert.Equal(t, "task.created", got.Header.Get(EventHeader))
+	assert.Equal(t, "d1", got.Header.Get(DeliveryHeader))
+	assert.Equal(t, "1760864400", got.Header.Get(TimestampHeader))
+	assert.Equal(t, "sha256="+Sign(r.Secret, "1760864400", r.Body), got.Header.Get(SignatureHeader))
+	assert.NotEqual(t, Sign(r.Secret, "1760864401", r.Body), Sign(r.Secret, "1760864400", r.Body), "the timestamp is signed")
+
+	status = http.StatusBadGateway
+	code, err = c.Post(context.Background(), r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadGateway, code)
+
+	srv.Close()
+	code, err = c.Post(context.Background(), r)
+	assert.Error(t, err)
+	assert.Zero(t, code, "no answer")
+}
@@ -0,0 +1,201 @@
+// Package websocket is a minimal server side of RFC 6455 for pushing
+// messages: the server sends text messages and pings, and only reads
+// what the client sends to answer pings and notice it closing.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// keyGUID is appended to the key of the client to accept the handshake.
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxFrame bounds what a client may send; pushed-to clients have little
+// to say.
+const maxFrame = 64 * 1024
+
+// writeTimeout bounds how long a write may block on a stalled client.
+const writeTimeout = 10 * time.Second
+
+// IsUpgrade reports whether the request asks for a WebSocket.
+func IsUpgrade(c *fiber.Ctx) bool {
+	return strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") &&
+		strings.Contains(strings.ToLower(c.Get(fiber.HeaderConnection)), "upgrade")
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept of a Sec-WebSocket-Key.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + keyGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Upgrade answers the handshake and runs fn with the connection once the
+// response is sent. The connection is closed when fn returns.
+func Upgrade(c *fiber.Ctx, fn func(*Conn)) error {
+	key := c.Get("Sec-WebSocket-Key")
+	if c.Method() != fiber.MethodGet || key == "" || c.Get("Sec-WebSocket-Version") != "13" {
+		c.Set("Sec-WebSocket-Version", "13")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"msg": "invalid websocket handshake"})
+	}
+
+	c.Set(fiber.HeaderUpgrade, "websocket")
+	c.Set(fiber.HeaderConnection, "Upgrade")
+	c.Set("Sec-WebSocket-Accept", AcceptKey(key))
+	c.Status(fiber.StatusSwitchingProtocols)
+
+	c.Context().Hijack(func(nc net.Conn) {
+		conn := newConn(nc)
+		go conn.read()
+		fn(conn)
+		conn.Close()
+	})
+	return nil
+}
+
+// Conn is an open WebSocket.
+type Conn struct {
+	nc   net.Conn
+	mu   sync.Mutex // serializes writes
+	w    *bufio.Writer
+	done chan struct{}
+	once sync.Once
+}
+
+func newConn(nc net.Conn) *Conn {
+	return &Conn{nc: nc, w: bufio.NewWriter(nc), done: make(chan struct{})}
+}
+
+// Done is closed once the client closed the connection or it failed.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+// WriteText sends p as a text message.
+func (c *Conn) WriteText(p []byte) error { return c.write(opText, p) }
+
+// Ping sends a ping, which also keeps proxies from closing an idle
+// connection.
+func (c *Conn) Ping() error { return c.write(opPing, nil) }
+
+// Close sends a normal closure and closes the connection.
+func (c *Conn) Close() {
+	_ = c.write(opClose, []byte{0x03, 0xE8}) // 1000
+	c.finish()
+}
+
+func (c *Conn) finish() {
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.nc.Close()
+	})
+}
+
+// write sends a single, unmasked frame, as servers do.
+func (c *Conn) write(op byte, p []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | op, 0}
+	switch n := len(p); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	_ = c.nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.w.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.w.Write(p); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// read consumes what the client sends until it closes the connection:
+// pings are answered, anything else is dropped.
+func (c *Conn) read() {
+	defer c.finish()
+
+	r := bufio.NewReader(c.nc)
+	for {
+		op, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		switch op {
+		case opPing:
+			if c.write(opPong, payload) != nil {
+				return
+			}
+		case opClose:
+			_ = c.write(opClose, payload)
+			return
+		}
+	}
+}
+
+// readFrame reads one frame of the client, which must be masked.
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("websocket: unmasked client frame")
+	}
+
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxFrame {
+		return 0, nil, errors.New("websocket: frame too large")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
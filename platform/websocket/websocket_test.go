@@ -0,0 +1,85 @@
+package websocket
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// the example of RFC 6455, section 1.3
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestUpgrade(t *testing.T) {
+	closed := make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws", func(c *fiber.Ctx) error {
+		if !IsUpgrade(c) {
+			return c.SendStatus(fiber.StatusUpgradeRequired)
+		}
+		return Upgrade(c, func(conn *Conn) {
+			_ = conn.WriteText([]byte(`{"hello":"world"}`))
+			<-conn.Done()
+			close(closed)
+		})
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln) }()
+	defer app.Shutdown()
+
+	nc, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	_, err = nc.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	require.NoError(t, err)
+
+	r := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 101, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	head := make([]byte, 2)
+	_, err = io.ReadFull(r, head)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x81), head[0], "final text frame")
+	payload := make([]byte, head[1])
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+	assert.Equal(t, `{"hello":"world"}`, string(payload))
+
+	// a masked close frame with an empty mask
+	_, err = nc.Write([]byte{0x88, 0x80, 0, 0, 0, 0})
+	require.NoError(t, err)
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the server did not notice the close")
+	}
+}
+
+func TestUpgradeInvalid(t *testing.T) {
+	app := fiber.New()
+	app.Get("/ws", func(c *fiber.Ctx) error {
+		return Upgrade(c, func(*Conn) {})
+	})
+
+	req, _ := http.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode, "without a key")
+}
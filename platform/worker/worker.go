@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/byeblogs/go-boilerplate/platform/logger"
+)
+
+// Job is a piece of background work run on a fixed interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Runner runs jobs in the background until their context is cancelled.
+type Runner struct {
+	wg   sync.WaitGroup
+	logr *logger.Logger
+}
+
+func NewRunner(logr *logger.Logger) *Runner {
+	return &Runner{logr: logr}
+}
+
+// Start runs the job once right away and then on every interval tick,
+// until ctx is cancelled. A failing run is logged and retried on the next tick.
+func (r *Runner) Start(ctx context.Context, job Job) {
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(job.Interval)
+		defer ticker.Stop()
+
+		r.logr.Infof("job %s started (every %s)", job.Name, job.Interval)
+		for {
+			if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+				r.logr.Errorf("job %s failed: %v", job.Name, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				r.logr.Infof("job %s stopped", job.Name)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until every started job has returned.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}